@@ -1,8 +1,9 @@
 module go.semut.io/sdk/go-sdk
 
-go 1.14
+go 1.16
 
 require (
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/google/uuid v1.1.3
 	github.com/hashicorp/go-retryablehttp v0.6.8
 )
@@ -0,0 +1,171 @@
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent by the platform with each cron callback
+const (
+	HeaderCronID    = "X-Semut-Cron-Id"
+	HeaderCronName  = "X-Semut-Cron-Name"
+	HeaderRunID     = "X-Semut-Run-Id"
+	HeaderAttempt   = "X-Semut-Delivery-Attempt"
+	HeaderTimestamp = "X-Semut-Timestamp"
+	HeaderNonce     = "X-Semut-Nonce"
+	HeaderKeyID     = "X-Semut-Key-Id"
+	HeaderSignature = "X-Semut-Signature"
+)
+
+// signaturePrefix identifies the signature scheme
+const signaturePrefix = "v1="
+
+// DefaultMaxSkew is how far a callback timestamp may be from the receiver clock
+const DefaultMaxSkew = 5 * time.Minute
+
+// Err* is used to throw errors while signing or verifying callbacks
+var (
+	ErrMissingKey       = errors.New("no signing key for key identifier")
+	ErrMissingHeader    = errors.New("missing callback header")
+	ErrInvalidSignature = errors.New("invalid callback signature")
+	ErrStaleTimestamp   = errors.New("callback timestamp outside the allowed window")
+	ErrReplayed         = errors.New("callback nonce has already been used")
+)
+
+// Message is a verified cron callback
+type Message struct {
+	// ID of the cron job
+	CronID string
+	// Name of the cron job, empty if the job has no name
+	Name string
+	// ID of the run, identical across retries of the same run
+	RunID string
+	// Attempt is the delivery attempt number starting at 1
+	Attempt int
+	// Timestamp at which this delivery was signed
+	Timestamp time.Time
+	// Content is the CallbackMessageContent of the job, or of the trigger request
+	Content []byte
+}
+
+// IdempotencyKey identifies the run, so that retried deliveries can be recognised
+func (message *Message) IdempotencyKey() string {
+	return message.CronID + "/" + message.RunID
+}
+
+// Decode decodes the JSON content of the message into a typed payload
+func (message *Message) Decode(payload interface{}) error {
+	return json.Unmarshal(message.Content, payload)
+}
+
+// Signer signs callbacks, it is used by the platform and to exercise handlers locally
+type Signer struct {
+	// KeyID identifies Key, allowing handlers to accept several keys during rotation
+	KeyID string
+	// Key is the HMAC-SHA256 secret key
+	Key []byte
+	// Now returns the current time, defaults to time.Now
+	Now func() time.Time
+}
+
+// Sign sets the callback headers and signature of a request carrying the message content
+func (signer *Signer) Sign(request *http.Request, message Message) error {
+
+	if len(signer.Key) == 0 {
+		return ErrMissingKey
+	}
+
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now
+	if signer.Now != nil {
+		now = signer.Now
+	}
+	attempt := message.Attempt
+	if attempt <= 0 {
+		attempt = 1
+	}
+
+	header := request.Header
+	header.Set(HeaderCronID, message.CronID)
+	if message.Name != "" {
+		header.Set(HeaderCronName, message.Name)
+	}
+	header.Set(HeaderRunID, message.RunID)
+	header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	header.Set(HeaderTimestamp, strconv.FormatInt(now().Unix(), 10))
+	header.Set(HeaderNonce, hex.EncodeToString(nonce))
+	header.Set(HeaderKeyID, signer.KeyID)
+	header.Set(HeaderSignature, signaturePrefix+signature(signer.Key, header, message.Content))
+
+	return nil
+}
+
+// verify checks the signature and timestamp of a callback and returns its message
+func verify(header http.Header, body []byte, keys map[string][]byte, now time.Time, maxSkew time.Duration) (
+	Message, error) {
+
+	for _, name := range []string{HeaderCronID, HeaderRunID, HeaderTimestamp, HeaderNonce, HeaderSignature} {
+		if header.Get(name) == "" {
+			return Message{}, ErrMissingHeader
+		}
+	}
+
+	key, ok := keys[header.Get(HeaderKeyID)]
+	if !ok || len(key) == 0 {
+		return Message{}, ErrMissingKey
+	}
+
+	given := strings.TrimPrefix(header.Get(HeaderSignature), signaturePrefix)
+	expected := signature(key, header, body)
+	if !hmac.Equal([]byte(given), []byte(expected)) {
+		return Message{}, ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return Message{}, ErrInvalidSignature
+	}
+	timestamp := time.Unix(seconds, 0)
+	if timestamp.Before(now.Add(-maxSkew)) || timestamp.After(now.Add(maxSkew)) {
+		return Message{}, ErrStaleTimestamp
+	}
+
+	attempt, _ := strconv.Atoi(header.Get(HeaderAttempt))
+
+	return Message{
+		CronID:    header.Get(HeaderCronID),
+		Name:      header.Get(HeaderCronName),
+		RunID:     header.Get(HeaderRunID),
+		Attempt:   attempt,
+		Timestamp: timestamp,
+		Content:   body,
+	}, nil
+}
+
+// signature computes the hex HMAC of the signed headers and body, each header value is
+// followed by a newline so that values cannot be shifted between headers
+func signature(key []byte, header http.Header, body []byte) string {
+
+	mac := hmac.New(sha256.New, key)
+	for _, name := range []string{HeaderTimestamp, HeaderNonce, HeaderKeyID, HeaderCronID, HeaderCronName,
+		HeaderRunID, HeaderAttempt} {
+		mac.Write([]byte(header.Get(name)))
+		mac.Write([]byte{'\n'})
+	}
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,220 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBodySize is the largest callback body accepted
+const DefaultMaxBodySize = 1 << 20
+
+// ErrNoRoute is returned when no function is registered for a callback
+var ErrNoRoute = errors.New("no function registered for cron job")
+
+// Func handles a verified callback. Returning an error makes the platform deliver the run again
+type Func func(ctx context.Context, message *Message) error
+
+// Handler is an http.Handler receiving cron callbacks. It verifies the signature, rejects stale
+// and replayed deliveries, and routes each run to the function registered for its CronID or,
+// failing that, its name. Deliveries are at least once: a run whose function fails is retried
+// by the platform, and a run that already succeeded is acknowledged without calling it again.
+// A delivery arriving while the run is in progress is answered with status code 409.
+type Handler struct {
+	// Keys by key identifier
+	Keys map[string][]byte
+	// MaxSkew is how far a callback timestamp may be from Now, defaults to DefaultMaxSkew
+	MaxSkew time.Duration
+	// MaxBodySize is the largest body accepted, defaults to DefaultMaxBodySize
+	MaxBodySize int64
+	// Nonces records used nonces, defaults to an in-memory store
+	Nonces NonceStore
+	// Idempotency records completed runs, defaults to an in-memory store. Use a shared store
+	// when several replicas receive callbacks
+	Idempotency IdempotencyStore
+	// Now returns the current time, defaults to time.Now
+	Now func() time.Time
+
+	mutex  sync.RWMutex
+	byID   map[string]Func
+	byName map[string]Func
+	once   sync.Once
+}
+
+// NewHandler is used to create a Handler accepting a single key
+func NewHandler(keyID string, key []byte) *Handler {
+	return &Handler{Keys: map[string][]byte{keyID: key}}
+}
+
+// Handle registers the function called for runs of the cron job with the given ID
+func (handler *Handler) Handle(cronID string, fn Func) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	if handler.byID == nil {
+		handler.byID = map[string]Func{}
+	}
+	handler.byID[cronID] = fn
+}
+
+// HandleName registers the function called for runs of cron jobs with the given name
+func (handler *Handler) HandleName(name string, fn Func) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	if handler.byName == nil {
+		handler.byName = map[string]Func{}
+	}
+	handler.byName[name] = fn
+}
+
+// ServeHTTP verifies and dispatches a callback
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	handler.once.Do(handler.defaults)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, handler.maxBodySize()+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > handler.maxBodySize() {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	now := handler.now()
+	message, err := verify(r.Header, body, handler.Keys, now, handler.maxSkew())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	fresh, err := handler.Nonces.Use(r.Header.Get(HeaderNonce), message.Timestamp.Add(handler.maxSkew()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if !fresh {
+		http.Error(w, ErrReplayed.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	fn := handler.route(&message)
+	if fn == nil {
+		http.Error(w, ErrNoRoute.Error(), http.StatusNotFound)
+		return
+	}
+
+	key := message.IdempotencyKey()
+	state, err := handler.Idempotency.Acquire(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	switch state {
+	case RunCompleted:
+		w.WriteHeader(http.StatusOK)
+		return
+	case RunInProgress:
+		http.Error(w, "run is being handled by another delivery", http.StatusConflict)
+		return
+	}
+
+	err = handler.call(r.Context(), fn, key, &message)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = handler.Idempotency.Complete(key)
+	if err != nil {
+		http.Error(w, handler.release(key, err).Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// call calls the function of an acquired run, releasing the run if the function fails or
+// panics so that the next delivery of the run is handled
+func (handler *Handler) call(ctx context.Context, fn Func, key string, message *Message) (err error) {
+
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			err = handler.release(key, err)
+		}
+	}()
+
+	err = fn(ctx, message)
+	succeeded = err == nil
+	return err
+}
+
+// release releases a run that failed with cause and returns cause, noting a failure to release.
+// A run that cannot be released stays in progress until its lease expires
+func (handler *Handler) release(key string, cause error) error {
+	err := handler.Idempotency.Release(key)
+	if err != nil {
+		return fmt.Errorf("%w, and releasing the run failed: %v", cause, err)
+	}
+	return cause
+}
+
+// route returns the function registered for the message
+func (handler *Handler) route(message *Message) Func {
+	handler.mutex.RLock()
+	defer handler.mutex.RUnlock()
+
+	if fn, ok := handler.byID[message.CronID]; ok {
+		return fn
+	}
+	if message.Name != "" {
+		return handler.byName[message.Name]
+	}
+	return nil
+}
+
+// defaults sets the stores left unset
+func (handler *Handler) defaults() {
+	if handler.Nonces == nil {
+		handler.Nonces = NewMemoryStore()
+	}
+	if handler.Idempotency == nil {
+		handler.Idempotency = NewMemoryStore()
+	}
+}
+
+// now returns the current time
+func (handler *Handler) now() time.Time {
+	if handler.Now != nil {
+		return handler.Now()
+	}
+	return time.Now()
+}
+
+// maxSkew returns the allowed timestamp skew
+func (handler *Handler) maxSkew() time.Duration {
+	if handler.MaxSkew > 0 {
+		return handler.MaxSkew
+	}
+	return DefaultMaxSkew
+}
+
+// maxBodySize returns the largest body accepted
+func (handler *Handler) maxBodySize() int64 {
+	if handler.MaxBodySize > 0 {
+		return handler.MaxBodySize
+	}
+	return DefaultMaxBodySize
+}
@@ -0,0 +1,309 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var handlerNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func testSigner() *Signer {
+	return &Signer{KeyID: "key-1", Key: []byte("callback secret"), Now: func() time.Time { return handlerNow }}
+}
+
+func newTestHandler() *Handler {
+	handler := NewHandler("key-1", []byte("callback secret"))
+	handler.Now = func() time.Time { return handlerNow }
+	return handler
+}
+
+// signedRequest returns a callback request for the message signed by signer
+func signedRequest(t *testing.T, signer *Signer, message Message) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/cron", bytes.NewReader(message.Content))
+	if err := signer.Sign(request, message); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return request
+}
+
+// serve passes the request to handler and returns the response
+func serve(handler http.Handler, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestHandlerVerifiesCallbacks(t *testing.T) {
+
+	message := Message{CronID: "cron-1", Name: "nightly", RunID: "run-1", Content: []byte(`{"report":"daily"}`)}
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		status  int
+	}{
+		{"signed", func() *http.Request {
+			return signedRequest(t, testSigner(), message)
+		}, http.StatusOK},
+		{"routed by name", func() *http.Request {
+			named := message
+			named.CronID = "cron-2"
+			return signedRequest(t, testSigner(), named)
+		}, http.StatusOK},
+		{"no route", func() *http.Request {
+			unknown := Message{CronID: "cron-3", RunID: "run-1"}
+			return signedRequest(t, testSigner(), unknown)
+		}, http.StatusNotFound},
+		{"method", func() *http.Request {
+			request := signedRequest(t, testSigner(), message)
+			request.Method = http.MethodGet
+			return request
+		}, http.StatusMethodNotAllowed},
+		{"body too large", func() *http.Request {
+			large := message
+			large.Content = bytes.Repeat([]byte("x"), DefaultMaxBodySize+1)
+			return signedRequest(t, testSigner(), large)
+		}, http.StatusRequestEntityTooLarge},
+		{"other key", func() *http.Request {
+			signer := testSigner()
+			signer.Key = []byte("other secret")
+			return signedRequest(t, signer, message)
+		}, http.StatusUnauthorized},
+		{"unknown key ID", func() *http.Request {
+			signer := testSigner()
+			signer.KeyID = "key-2"
+			return signedRequest(t, signer, message)
+		}, http.StatusUnauthorized},
+		{"tampered body", func() *http.Request {
+			request := signedRequest(t, testSigner(), message)
+			request.Body = httptest.NewRequest(http.MethodPost, "/cron", strings.NewReader(`{"report":"all"}`)).Body
+			return request
+		}, http.StatusUnauthorized},
+		{"tampered run ID", func() *http.Request {
+			request := signedRequest(t, testSigner(), message)
+			request.Header.Set(HeaderRunID, "run-2")
+			return request
+		}, http.StatusUnauthorized},
+		{"tampered attempt", func() *http.Request {
+			request := signedRequest(t, testSigner(), message)
+			request.Header.Set(HeaderAttempt, "2")
+			return request
+		}, http.StatusUnauthorized},
+		{"missing nonce", func() *http.Request {
+			request := signedRequest(t, testSigner(), message)
+			request.Header.Del(HeaderNonce)
+			return request
+		}, http.StatusUnauthorized},
+		{"missing signature", func() *http.Request {
+			request := signedRequest(t, testSigner(), message)
+			request.Header.Del(HeaderSignature)
+			return request
+		}, http.StatusUnauthorized},
+		{"timestamp before the window", func() *http.Request {
+			signer := testSigner()
+			signer.Now = func() time.Time { return handlerNow.Add(-DefaultMaxSkew - time.Second) }
+			return signedRequest(t, signer, message)
+		}, http.StatusUnauthorized},
+		{"timestamp after the window", func() *http.Request {
+			signer := testSigner()
+			signer.Now = func() time.Time { return handlerNow.Add(DefaultMaxSkew + time.Second) }
+			return signedRequest(t, signer, message)
+		}, http.StatusUnauthorized},
+		{"timestamp within the window", func() *http.Request {
+			signer := testSigner()
+			signer.Now = func() time.Time { return handlerNow.Add(-DefaultMaxSkew) }
+			return signedRequest(t, signer, message)
+		}, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		var received *Message
+		handler := newTestHandler()
+		handler.Handle("cron-1", func(ctx context.Context, message *Message) error {
+			received = message
+			return nil
+		})
+		handler.HandleName("nightly", func(ctx context.Context, message *Message) error {
+			received = message
+			return nil
+		})
+
+		response := serve(handler, test.request())
+		if response.Code != test.status {
+			t.Errorf("%s: status %d, want %d: %s", test.name, response.Code, test.status, response.Body)
+		}
+		if called := received != nil; called != (test.status == http.StatusOK) {
+			t.Errorf("%s: function called: %v, want %v", test.name, called, test.status == http.StatusOK)
+		}
+		if received != nil && (received.RunID != "run-1" || received.Attempt != 1 ||
+			string(received.Content) != string(message.Content)) {
+			t.Errorf("%s: received %+v, want the signed message", test.name, received)
+		}
+	}
+}
+
+func TestHandlerRejectsReplayedDelivery(t *testing.T) {
+
+	calls := 0
+	handler := newTestHandler()
+	handler.Handle("cron-1", func(ctx context.Context, message *Message) error {
+		calls++
+		return nil
+	})
+
+	message := Message{CronID: "cron-1", RunID: "run-1", Content: []byte("content")}
+	request := signedRequest(t, testSigner(), message)
+	replayed := request.Clone(context.Background())
+	replayed.Body = httptest.NewRequest(http.MethodPost, "/cron", bytes.NewReader(message.Content)).Body
+
+	if response := serve(handler, request); response.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", response.Code, response.Body)
+	}
+	response := serve(handler, replayed)
+	if response.Code != http.StatusUnauthorized || !strings.Contains(response.Body.String(), ErrReplayed.Error()) {
+		t.Fatalf("replay status %d: %s, want 401 with ErrReplayed", response.Code, response.Body)
+	}
+	if calls != 1 {
+		t.Fatalf("function called %d times, want once", calls)
+	}
+}
+
+func TestHandlerIdempotency(t *testing.T) {
+
+	handler := newTestHandler()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	calls := 0
+	handler.Handle("cron-1", func(ctx context.Context, message *Message) error {
+		calls++
+		if message.RunID == "slow" {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+	deliver := func(runID string, attempt int) *httptest.ResponseRecorder {
+		message := Message{CronID: "cron-1", RunID: runID, Attempt: attempt}
+		return serve(handler, signedRequest(t, testSigner(), message))
+	}
+
+	// a redelivery of a completed run is acknowledged without calling the function
+	if response := deliver("run-1", 1); response.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", response.Code, response.Body)
+	}
+	if response := deliver("run-1", 2); response.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("redelivery status %d with %d calls, want 200 without calling again", response.Code, calls)
+	}
+
+	// a redelivery while the run is in progress conflicts
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- deliver("slow", 1) }()
+	<-started
+	if response := deliver("slow", 2); response.Code != http.StatusConflict {
+		t.Fatalf("status %d during the run, want 409: %s", response.Code, response.Body)
+	}
+	close(release)
+	if response := <-done; response.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", response.Code, response.Body)
+	}
+	if response := deliver("slow", 3); response.Code != http.StatusOK || calls != 2 {
+		t.Fatalf("status %d with %d calls after the run, want 200 without calling again", response.Code, calls)
+	}
+}
+
+func TestHandlerReleasesFailedRuns(t *testing.T) {
+
+	handler := newTestHandler()
+	var fail error
+	shouldPanic := false
+	calls := 0
+	handler.Handle("cron-1", func(ctx context.Context, message *Message) error {
+		calls++
+		if shouldPanic {
+			panic("function panicked")
+		}
+		return fail
+	})
+	deliver := func(attempt int) *httptest.ResponseRecorder {
+		message := Message{CronID: "cron-1", RunID: "run-1", Attempt: attempt}
+		return serve(handler, signedRequest(t, testSigner(), message))
+	}
+
+	fail = errors.New("report failed")
+	if response := deliver(1); response.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500: %s", response.Code, response.Body)
+	}
+
+	fail, shouldPanic = nil, true
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic not propagated")
+			}
+		}()
+		deliver(2)
+	}()
+
+	shouldPanic = false
+	if response := deliver(3); response.Code != http.StatusOK || calls != 3 {
+		t.Fatalf("status %d with %d calls, want the run handled after failing and panicking", response.Code, calls)
+	}
+}
+
+// failingStore is an IdempotencyStore whose Complete and Release fail
+type failingStore struct {
+	mutex    sync.Mutex
+	released []string
+}
+
+var errStore = errors.New("store unavailable")
+
+func (store *failingStore) Acquire(key string) (RunState, error) {
+	return RunAcquired, nil
+}
+
+func (store *failingStore) Complete(key string) error {
+	return errStore
+}
+
+func (store *failingStore) Release(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.released = append(store.released, key)
+	return errStore
+}
+
+func TestHandlerReportsStoreErrors(t *testing.T) {
+
+	store := &failingStore{}
+	handler := newTestHandler()
+	handler.Idempotency = store
+	fail := errors.New("report failed")
+	handler.Handle("cron-1", func(ctx context.Context, message *Message) error {
+		if message.RunID == "failing" {
+			return fail
+		}
+		return nil
+	})
+
+	response := serve(handler, signedRequest(t, testSigner(), Message{CronID: "cron-1", RunID: "failing"}))
+	if response.Code != http.StatusInternalServerError ||
+		!strings.Contains(response.Body.String(), "report failed, and releasing the run failed: store unavailable") {
+		t.Fatalf("status %d: %s, want 500 naming both errors", response.Code, response.Body)
+	}
+
+	response = serve(handler, signedRequest(t, testSigner(), Message{CronID: "cron-1", RunID: "run-1"}))
+	if response.Code != http.StatusServiceUnavailable || !strings.Contains(response.Body.String(), errStore.Error()) {
+		t.Fatalf("status %d: %s, want 503 when completing fails", response.Code, response.Body)
+	}
+	if len(store.released) != 2 || store.released[1] != "cron-1/run-1" {
+		t.Fatalf("released %v, want the run released when completing fails", store.released)
+	}
+}
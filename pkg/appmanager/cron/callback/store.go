@@ -0,0 +1,187 @@
+package callback
+
+import (
+	"sync"
+	"time"
+)
+
+// Default MemoryStore durations
+const (
+	// DefaultRunRetention is how long MemoryStore remembers completed runs
+	DefaultRunRetention = 24 * time.Hour
+	// DefaultRunLease is how long MemoryStore keeps a run in progress before another delivery
+	// may take it over
+	DefaultRunLease = 10 * time.Minute
+)
+
+// RunState is the idempotency state of a run
+type RunState int
+
+const (
+	// RunAcquired means the caller should handle the run
+	RunAcquired RunState = iota
+	// RunInProgress means another delivery of the run is being handled
+	RunInProgress
+	// RunCompleted means the run was already handled successfully
+	RunCompleted
+)
+
+// NonceStore records nonces so that a delivery cannot be replayed
+type NonceStore interface {
+	// Use records the nonce until expires and reports whether it was unused
+	Use(nonce string, expires time.Time) (fresh bool, err error)
+}
+
+// IdempotencyStore records which runs have been handled. Runs in progress should expire after
+// a lease, so that a run is delivered again if the process handling it dies before releasing it
+type IdempotencyStore interface {
+	// Acquire marks the run as in progress unless it is already in progress or completed
+	Acquire(key string) (RunState, error)
+	// Complete marks an acquired run as handled
+	Complete(key string) error
+	// Release forgets an acquired run so that its next delivery is handled
+	Release(key string) error
+}
+
+// MemoryStore is a NonceStore and IdempotencyStore kept in memory. It only deduplicates
+// deliveries received by the same process. The zero value is ready to use
+type MemoryStore struct {
+	// RunRetention is how long completed runs are remembered, defaults to DefaultRunRetention
+	RunRetention time.Duration
+	// RunLease is how long a run stays in progress, defaults to DefaultRunLease. Once it
+	// expires the next delivery of the run acquires it, so it should exceed the longest run
+	RunLease time.Duration
+	// Now returns the current time, defaults to time.Now
+	Now func() time.Time
+
+	mutex     sync.Mutex
+	nonces    map[string]time.Time
+	running   map[string]time.Time
+	completed map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore is used to create an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nonces:    map[string]time.Time{},
+		running:   map[string]time.Time{},
+		completed: map[string]time.Time{},
+	}
+}
+
+// Use records the nonce until expires and reports whether it was unused
+func (store *MemoryStore) Use(nonce string, expires time.Time) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.init()
+	store.sweep()
+
+	if _, ok := store.nonces[nonce]; ok {
+		return false, nil
+	}
+	store.nonces[nonce] = expires
+	return true, nil
+}
+
+// Acquire marks the run as in progress unless it is already in progress or completed. A run
+// whose lease expired is acquired again
+func (store *MemoryStore) Acquire(key string) (RunState, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.init()
+	store.sweep()
+
+	now := store.now()
+	if _, ok := store.completed[key]; ok {
+		return RunCompleted, nil
+	}
+	if expires, ok := store.running[key]; ok && !now.After(expires) {
+		return RunInProgress, nil
+	}
+	store.running[key] = now.Add(store.lease())
+	return RunAcquired, nil
+}
+
+// Complete marks an acquired run as handled
+func (store *MemoryStore) Complete(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.init()
+
+	delete(store.running, key)
+	store.completed[key] = store.now().Add(store.retention())
+	return nil
+}
+
+// Release forgets an acquired run
+func (store *MemoryStore) Release(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.running, key)
+	return nil
+}
+
+// init creates the maps of a zero value MemoryStore, the mutex must be held
+func (store *MemoryStore) init() {
+	if store.nonces == nil {
+		store.nonces = map[string]time.Time{}
+	}
+	if store.running == nil {
+		store.running = map[string]time.Time{}
+	}
+	if store.completed == nil {
+		store.completed = map[string]time.Time{}
+	}
+}
+
+// sweep drops expired nonces, runs and leases at most once a minute
+func (store *MemoryStore) sweep() {
+
+	now := store.now()
+	if now.Sub(store.lastSweep) < time.Minute {
+		return
+	}
+	store.lastSweep = now
+
+	for nonce, expires := range store.nonces {
+		if now.After(expires) {
+			delete(store.nonces, nonce)
+		}
+	}
+	for key, expires := range store.completed {
+		if now.After(expires) {
+			delete(store.completed, key)
+		}
+	}
+	for key, expires := range store.running {
+		if now.After(expires) {
+			delete(store.running, key)
+		}
+	}
+}
+
+// now returns the current time
+func (store *MemoryStore) now() time.Time {
+	if store.Now != nil {
+		return store.Now()
+	}
+	return time.Now()
+}
+
+// retention returns how long completed runs are remembered
+func (store *MemoryStore) retention() time.Duration {
+	if store.RunRetention > 0 {
+		return store.RunRetention
+	}
+	return DefaultRunRetention
+}
+
+// lease returns how long a run stays in progress
+func (store *MemoryStore) lease() time.Duration {
+	if store.RunLease > 0 {
+		return store.RunLease
+	}
+	return DefaultRunLease
+}
@@ -0,0 +1,73 @@
+package callback
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreZeroValue(t *testing.T) {
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &MemoryStore{Now: func() time.Time { return now }}
+
+	fresh, err := store.Use("nonce", now.Add(time.Minute))
+	if err != nil || !fresh {
+		t.Fatalf("first Use = %v, %v, want fresh", fresh, err)
+	}
+	fresh, _ = store.Use("nonce", now.Add(time.Minute))
+	if fresh {
+		t.Fatal("second Use of the nonce reported fresh")
+	}
+
+	for _, want := range []RunState{RunAcquired, RunInProgress} {
+		state, err := store.Acquire("run")
+		if err != nil || state != want {
+			t.Fatalf("Acquire = %v, %v, want %v", state, err, want)
+		}
+	}
+	err = store.Complete("run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _ := store.Acquire("run")
+	if state != RunCompleted {
+		t.Fatalf("Acquire after Complete = %v, want RunCompleted", state)
+	}
+
+	completeFirst := &MemoryStore{}
+	err = completeFirst.Complete("run")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryStoreRunLease(t *testing.T) {
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &MemoryStore{RunLease: time.Hour, Now: func() time.Time { return now }}
+
+	state, _ := store.Acquire("run")
+	if state != RunAcquired {
+		t.Fatalf("Acquire = %v, want RunAcquired", state)
+	}
+	now = now.Add(time.Hour)
+	if state, _ := store.Acquire("run"); state != RunInProgress {
+		t.Fatalf("Acquire within the lease = %v, want RunInProgress", state)
+	}
+
+	// the process handling the run died without releasing it
+	now = now.Add(time.Second)
+	if state, _ := store.Acquire("run"); state != RunAcquired {
+		t.Fatalf("Acquire after the lease = %v, want RunAcquired", state)
+	}
+	if state, _ := store.Acquire("run"); state != RunInProgress {
+		t.Fatalf("Acquire = %v, want the new lease held", state)
+	}
+
+	if err := store.Release("run"); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := store.Acquire("run"); state != RunAcquired {
+		t.Fatalf("Acquire after Release = %v, want RunAcquired", state)
+	}
+}
@@ -1,17 +1,40 @@
 package cron
 
 import (
+	"time"
+
 	"go.semut.io/sdk/go-sdk/pkg/common"
 )
 
 // Spec is the complete specification of a cron job
 type Spec struct {
-	// Schedule of the cron job as per linux crontab format
+	// Name of the cron job, sent to the callback with each run. Optional
+	Name string `json:"name,omitempty"`
+	// Schedule of the cron job as per linux crontab format, see Parse for the accepted syntax
 	Schedule string `json:"schedule"`
+	// TimeZone is the IANA name of the time zone the schedule is evaluated in eg. `Europe/Berlin`.
+	// Omit to evaluate the schedule in UTC
+	TimeZone string `json:"time_zone,omitempty"`
 	// URI where to pass callback
 	CallbackURL string `json:"callback_url"`
 	// Content to pass to the callback URI
 	CallbackMessageContent string `json:"callback_message_content"`
+	// Labels are key value pairs attached to the cron job. Optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Retry controls redelivery of failed runs. Omit to deliver each run once
+	Retry *RetryPolicy `json:"retry,omitempty"`
+	// Timeout is how long a callback may take before the run is marked TimedOut and, if the retry
+	// policy allows, retried. Omit to use the platform default
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// ConcurrencyPolicy applies when a run is due while the previous run is in progress,
+	// defaults to AllowConcurrent
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrency_policy,omitempty"`
+	// Jitter delays each run by a random duration up to Jitter, spreading the load of jobs
+	// sharing a schedule. Omit to run exactly on schedule
+	Jitter time.Duration `json:"jitter,omitempty"`
+	// StartingDeadline is how late a run may start, eg. after a platform outage, before it is
+	// skipped. Omit to always deliver missed runs
+	StartingDeadline time.Duration `json:"starting_deadline,omitempty"`
 }
 
 // Cron is a cron job with an ID
@@ -19,6 +42,8 @@ type Cron struct {
 	// Unique ID of the cron job
 	CronID string `json:"cron_id"`
 	Spec
+	// Paused is true while the job is paused
+	Paused bool `json:"paused,omitempty"`
 }
 
 // CreateRequest create new cron
@@ -69,6 +94,10 @@ type DeleteResponse struct {
 // Create is used to create a new cron job
 func (createRequest *CreateRequest) Create() (cronID string, apiErr *common.Error) {
 
+	if err := createRequest.Validate(); err != nil {
+		return "", &common.Error{ErrorCode: "400", ErrorDescription: err.Error()}
+	}
+
 	createResponse := CreateResponse{}
 	err := common.Execute("CronCreate", createRequest, &createResponse)
 
@@ -116,6 +145,10 @@ func (listRequest *ListRequest) List() (cronList []Cron, apiErr *common.Error) {
 // Update is used to update all fields of an existing cron job
 func (updateRequest *UpdateRequest) Update() (sucess bool, apiErr *common.Error) {
 
+	if err := updateRequest.Validate(); err != nil {
+		return false, &common.Error{ErrorCode: "400", ErrorDescription: err.Error()}
+	}
+
 	updateResponse := UpdateResponse{}
 	err := common.Execute("CronUpdate", updateRequest, &updateResponse)
 
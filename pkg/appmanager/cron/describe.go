@@ -0,0 +1,170 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Describe returns a human-readable description of the schedule, eg. `At 09:30, on Monday
+// through Friday` for `30 9 * * 1-5`
+func (schedule *Schedule) Describe() string {
+
+	minute, hour := schedule.fields[0], schedule.fields[1]
+	description := ""
+
+	switch {
+	case isNumber(minute) && isNumber(hour):
+		m, _ := strconv.Atoi(minute)
+		h, _ := strconv.Atoi(hour)
+		description = fmt.Sprintf("At %02d:%02d", h, m)
+	case minute == "*" || strings.HasPrefix(minute, "*/"):
+		description = "Every minute"
+		if step := strings.TrimPrefix(minute, "*/"); step != minute && step != "1" {
+			description = "Every " + step + " minutes"
+		}
+		if hour != "*" {
+			description += ", during " + describeField(hour, "hour", "hours", formatNumber)
+		}
+	default:
+		description = "At " + describeField(minute, "minute", "minutes", formatNumber)
+		if hour == "*" {
+			description += " past every hour"
+		} else {
+			description += " past " + describeField(hour, "hour", "hours", formatNumber)
+		}
+	}
+
+	dayOfMonth, dayOfWeek := "", ""
+	if !schedule.dayOfMonthAny {
+		dayOfMonth = "on " + describeField(schedule.fields[2], "day", "days", formatNumber) + " of the month"
+	}
+	if !schedule.dayOfWeekAny {
+		dayOfWeek = "on " + describeField(schedule.fields[4], "day", "", formatWeekday)
+	}
+	switch {
+	case dayOfMonth != "" && dayOfWeek != "":
+		description += ", " + dayOfMonth + " or " + dayOfWeek
+	case dayOfMonth != "":
+		description += ", " + dayOfMonth
+	case dayOfWeek != "":
+		description += ", " + dayOfWeek
+	}
+
+	if schedule.fields[3] != "*" && schedule.fields[3] != "?" {
+		description += ", in " + describeField(schedule.fields[3], "month", "", formatMonth)
+	}
+
+	if schedule.location != nil && schedule.location != time.UTC {
+		description += " (" + schedule.location.String() + ")"
+	}
+
+	return description
+}
+
+// describeField describes a comma separated list of values, ranges and steps, prefixed with
+// unit or units unless units is empty because the values are names
+func describeField(text, unit, units string, format func(string) string) string {
+
+	items := strings.Split(text, ",")
+	phrases := make([]string, 0, len(items))
+	plural := len(items) > 1
+	for _, item := range items {
+		phrase, isPlural := describeItem(item, unit, format)
+		phrases = append(phrases, phrase)
+		plural = plural || isPlural
+	}
+
+	list := joinList(phrases)
+	if strings.HasPrefix(list, "every") || units == "" {
+		return list
+	}
+	if plural {
+		return units + " " + list
+	}
+	return unit + " " + list
+}
+
+// describeItem describes one element of a list and reports whether it covers several values
+func describeItem(item, unit string, format func(string) string) (phrase string, plural bool) {
+
+	rangeText, stepText := item, ""
+	if i := strings.IndexByte(item, '/'); i >= 0 {
+		rangeText, stepText = item[:i], item[i+1:]
+	}
+
+	every := "every " + unit
+	if stepText != "" && stepText != "1" {
+		step, _ := strconv.Atoi(stepText)
+		every = "every " + ordinal(step) + " " + unit
+	}
+
+	switch {
+	case rangeText == "*" || rangeText == "?":
+		return every, true
+	case strings.Contains(rangeText, "-"):
+		parts := strings.SplitN(rangeText, "-", 2)
+		through := format(parts[0]) + " through " + format(parts[1])
+		if stepText == "" {
+			return through, true
+		}
+		return every + " from " + through, true
+	case stepText != "":
+		return every + " from " + format(rangeText), true
+	default:
+		return format(rangeText), false
+	}
+}
+
+// joinList joins phrases as `a`, `a and b` or `a, b and c`
+func joinList(phrases []string) string {
+	if len(phrases) == 1 {
+		return phrases[0]
+	}
+	return strings.Join(phrases[:len(phrases)-1], ", ") + " and " + phrases[len(phrases)-1]
+}
+
+// ordinal formats n as 1st, 2nd, 3rd, 4th...
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// isNumber reports whether text is a plain decimal number
+func isNumber(text string) bool {
+	_, err := strconv.Atoi(text)
+	return err == nil
+}
+
+// formatNumber formats a numeric field value
+func formatNumber(text string) string {
+	return text
+}
+
+// formatMonth formats a month number or name as its English name
+func formatMonth(text string) string {
+	value, err := monthField.value(text)
+	if err != nil {
+		return text
+	}
+	return time.Month(value).String()
+}
+
+// formatWeekday formats a day of week number or name as its English name
+func formatWeekday(text string) string {
+	value, err := dayOfWeekField.value(text)
+	if err != nil {
+		return text
+	}
+	return time.Weekday(value % 7).String()
+}
@@ -0,0 +1,130 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Default retry backoff values used when RetryPolicy leaves them unset
+const (
+	DefaultInitialBackoff    = 10 * time.Second
+	DefaultMaxBackoff        = 10 * time.Minute
+	DefaultBackoffMultiplier = 2.0
+)
+
+// Err* is used to throw errors while validating execution policies
+var (
+	ErrNegativeDuration         = errors.New("duration cannot be negative")
+	ErrNegativeRetries          = errors.New("max retries cannot be negative")
+	ErrInvalidMultiplier        = errors.New("backoff multiplier must be at least 1")
+	ErrInvalidBackoff           = errors.New("initial backoff cannot exceed max backoff")
+	ErrInvalidConcurrencyPolicy = errors.New("concurrency policy must be Allow, Forbid or Replace")
+)
+
+// ConcurrencyPolicy is what happens when a run is due while the previous run is still in progress
+type ConcurrencyPolicy string
+
+const (
+	// AllowConcurrent delivers the new run alongside the running one
+	AllowConcurrent ConcurrencyPolicy = "Allow"
+	// ForbidConcurrent skips the new run
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+	// ReplaceConcurrent stops waiting on the running run, marking it Replaced, and delivers the new one
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// Run statuses introduced by execution policies
+const (
+	// RunTimedOut runs did not complete within the spec Timeout
+	RunTimedOut RunStatus = "TimedOut"
+	// RunReplaced runs were superseded by a newer run under ReplaceConcurrent
+	RunReplaced RunStatus = "Replaced"
+)
+
+// RetryPolicy controls redelivery of runs whose callback fails or times out. Retry n, counting
+// from 1, waits InitialBackoff * BackoffMultiplier^(n-1), capped at MaxBackoff
+type RetryPolicy struct {
+	// MaxRetries is the number of deliveries after the first one. Zero disables retries
+	MaxRetries int `json:"max_retries"`
+	// InitialBackoff is the delay before the first retry, defaults to DefaultInitialBackoff
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty"`
+	// MaxBackoff caps the delay between retries, defaults to DefaultMaxBackoff or InitialBackoff
+	// when it is larger
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"`
+	// BackoffMultiplier is the growth of the delay between retries, defaults to DefaultBackoffMultiplier
+	BackoffMultiplier float64 `json:"backoff_multiplier,omitempty"`
+}
+
+// Backoff returns the delay before the given retry, counting from 1
+func (retryPolicy *RetryPolicy) Backoff(retry int) time.Duration {
+
+	initial, maximum, multiplier := retryPolicy.InitialBackoff, retryPolicy.MaxBackoff, retryPolicy.BackoffMultiplier
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	if maximum <= 0 {
+		maximum = DefaultMaxBackoff
+		if initial > maximum {
+			maximum = initial
+		}
+	}
+	if multiplier < 1 {
+		multiplier = DefaultBackoffMultiplier
+	}
+	if retry < 1 {
+		retry = 1
+	}
+
+	delay := float64(initial) * math.Pow(multiplier, float64(retry-1))
+	if delay > float64(maximum) {
+		return maximum
+	}
+	return time.Duration(delay)
+}
+
+// validate checks the retry policy values
+func (retryPolicy *RetryPolicy) validate() error {
+
+	switch {
+	case retryPolicy.MaxRetries < 0:
+		return fmt.Errorf("retry max_retries: %w", ErrNegativeRetries)
+	case retryPolicy.InitialBackoff < 0:
+		return fmt.Errorf("retry initial_backoff: %w", ErrNegativeDuration)
+	case retryPolicy.MaxBackoff < 0:
+		return fmt.Errorf("retry max_backoff: %w", ErrNegativeDuration)
+	case retryPolicy.BackoffMultiplier != 0 && retryPolicy.BackoffMultiplier < 1:
+		return fmt.Errorf("retry backoff_multiplier: %w", ErrInvalidMultiplier)
+	case retryPolicy.InitialBackoff > 0 && retryPolicy.MaxBackoff > 0 &&
+		retryPolicy.InitialBackoff > retryPolicy.MaxBackoff:
+		return fmt.Errorf("retry initial_backoff: %w", ErrInvalidBackoff)
+	}
+
+	return nil
+}
+
+// validatePolicies checks the execution policy fields of the spec
+func (spec *Spec) validatePolicies() error {
+
+	switch spec.ConcurrencyPolicy {
+	case "", AllowConcurrent, ForbidConcurrent, ReplaceConcurrent:
+	default:
+		return fmt.Errorf("concurrency_policy %q: %w", spec.ConcurrencyPolicy, ErrInvalidConcurrencyPolicy)
+	}
+
+	switch {
+	case spec.Timeout < 0:
+		return fmt.Errorf("timeout: %w", ErrNegativeDuration)
+	case spec.Jitter < 0:
+		return fmt.Errorf("jitter: %w", ErrNegativeDuration)
+	case spec.StartingDeadline < 0:
+		return fmt.Errorf("starting_deadline: %w", ErrNegativeDuration)
+	}
+
+	if spec.Retry != nil {
+		return spec.Retry.validate()
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		retry  int
+		want   time.Duration
+	}{
+		{"defaults", RetryPolicy{}, 1, DefaultInitialBackoff},
+		{"grows", RetryPolicy{InitialBackoff: time.Second, BackoffMultiplier: 3}, 3, 9 * time.Second},
+		{"capped", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 10, 5 * time.Second},
+		{"default cap", RetryPolicy{InitialBackoff: time.Minute}, 20, DefaultMaxBackoff},
+		{"initial above default cap", RetryPolicy{InitialBackoff: time.Hour}, 1, time.Hour},
+		{"initial above default cap grows no further", RetryPolicy{InitialBackoff: time.Hour}, 5, time.Hour},
+	}
+
+	for _, test := range tests {
+		got := test.policy.Backoff(test.retry)
+		if got != test.want {
+			t.Errorf("%s: Backoff(%d) = %v, want %v", test.name, test.retry, got, test.want)
+		}
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+
+	tests := []struct {
+		policy RetryPolicy
+		want   error
+	}{
+		{RetryPolicy{InitialBackoff: time.Hour}, nil},
+		{RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Minute}, ErrInvalidBackoff},
+		{RetryPolicy{MaxRetries: -1}, ErrNegativeRetries},
+		{RetryPolicy{BackoffMultiplier: 0.5}, ErrInvalidMultiplier},
+	}
+
+	for _, test := range tests {
+		err := test.policy.validate()
+		if !errors.Is(err, test.want) {
+			t.Errorf("%+v: validate() = %v, want %v", test.policy, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Labels set by a Reconciler on the cron jobs it owns
+const (
+	// OwnerLabel is the label marking cron jobs managed by a Reconciler, its value is the owner
+	OwnerLabel = "semut.io/managed-by"
+	// ManagedFieldsLabel lists the JSON fields set in the desired spec of an owned job, so that a
+	// field removed from the desired spec is detected and reset to its platform default
+	ManagedFieldsLabel = "semut.io/managed-fields"
+)
+
+// Err* is used to throw errors during reconciliation
+var (
+	ErrMissingOwner = errors.New("reconciler owner is required")
+	ErrMissingName  = errors.New("desired cron job name is required")
+)
+
+// ActionType is the change made to a cron job to converge it
+type ActionType string
+
+const (
+	// ActionCreate creates a desired job that does not exist
+	ActionCreate ActionType = "Create"
+	// ActionUpdate updates an owned job whose spec differs from the desired spec
+	ActionUpdate ActionType = "Update"
+	// ActionDelete deletes an owned job that is no longer desired, or a duplicate of one
+	ActionDelete ActionType = "Delete"
+)
+
+// Action is a single change planned by a Reconciler
+type Action struct {
+	// Type of change
+	Type ActionType
+	// Name of the job
+	Name string
+	// ID of the existing job, empty for ActionCreate
+	CronID string
+	// Spec the job will have, empty for ActionDelete
+	Spec Spec
+	// Changes lists the JSON fields that differ, for ActionUpdate
+	Changes []string
+}
+
+// String describes the action
+func (action Action) String() string {
+	switch action.Type {
+	case ActionCreate:
+		return fmt.Sprintf("create %q", action.Name)
+	case ActionUpdate:
+		return fmt.Sprintf("update %q (%s): %v", action.Name, action.CronID, action.Changes)
+	default:
+		return fmt.Sprintf("delete %q (%s)", action.Name, action.CronID)
+	}
+}
+
+// Plan is the set of actions converging the platform to the desired cron jobs
+type Plan struct {
+	// Actions to apply, in order
+	Actions []Action
+	// Names of desired jobs that are already up to date
+	Unchanged []string
+}
+
+// Reconciler converges the cron jobs it owns to a desired set of named specs. Jobs are owned
+// when they carry OwnerLabel set to Owner, jobs created by other tooling are never modified.
+// Fields omitted from a desired spec keep the value defaulted by the platform, and removing a
+// field from a desired spec resets it to the default.
+type Reconciler struct {
+	// Owner identifies the reconciler, eg. the name of the app manager
+	Owner string
+}
+
+// Plan lists the cron jobs and computes the actions needed to converge them to desired, a map
+// of job name to spec. Nothing is changed on the platform.
+func (reconciler *Reconciler) Plan(desired map[string]Spec) (Plan, error) {
+
+	if reconciler.Owner == "" {
+		return Plan{}, ErrMissingOwner
+	}
+
+	names := make([]string, 0, len(desired))
+	for name, spec := range desired {
+		if name == "" {
+			return Plan{}, ErrMissingName
+		}
+		err := spec.Validate()
+		if err != nil {
+			return Plan{}, fmt.Errorf("cron job %q: %w", name, err)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	listRequest := ListRequest{}
+	cronList, apiErr := listRequest.List()
+	if apiErr != nil {
+		return Plan{}, apiErr
+	}
+
+	owned := map[string][]Cron{}
+	for _, cron := range cronList {
+		if cron.Labels[OwnerLabel] == reconciler.Owner {
+			owned[cron.Name] = append(owned[cron.Name], cron)
+		}
+	}
+
+	plan := Plan{}
+	for _, name := range names {
+		spec, err := reconciler.ownedSpec(name, desired[name])
+		if err != nil {
+			return Plan{}, fmt.Errorf("cron job %q: %w", name, err)
+		}
+		existing := owned[name]
+		delete(owned, name)
+
+		if len(existing) == 0 {
+			plan.Actions = append(plan.Actions, Action{Type: ActionCreate, Name: name, Spec: spec})
+			continue
+		}
+
+		sort.Slice(existing, func(i, j int) bool { return existing[i].CronID < existing[j].CronID })
+		changes, err := specChanges(existing[0].Spec, spec)
+		if err != nil {
+			return Plan{}, fmt.Errorf("cron job %q: %w", name, err)
+		}
+		if len(changes) == 0 {
+			plan.Unchanged = append(plan.Unchanged, name)
+		} else {
+			plan.Actions = append(plan.Actions, Action{
+				Type: ActionUpdate, Name: name, CronID: existing[0].CronID, Spec: spec, Changes: changes,
+			})
+		}
+		for _, duplicate := range existing[1:] {
+			plan.Actions = append(plan.Actions, Action{Type: ActionDelete, Name: name, CronID: duplicate.CronID})
+		}
+	}
+
+	stale := make([]Cron, 0, len(owned))
+	for _, crons := range owned {
+		stale = append(stale, crons...)
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].CronID < stale[j].CronID })
+	for _, cron := range stale {
+		plan.Actions = append(plan.Actions, Action{Type: ActionDelete, Name: cron.Name, CronID: cron.CronID})
+	}
+
+	return plan, nil
+}
+
+// Apply applies the actions of a plan in order, stopping at the first failure. It returns the
+// actions applied, running Reconcile again resumes from the remaining ones.
+func (reconciler *Reconciler) Apply(plan Plan) (applied []Action, err error) {
+
+	for _, action := range plan.Actions {
+		switch action.Type {
+		case ActionCreate:
+			createRequest := CreateRequest{Spec: action.Spec}
+			cronID, apiErr := createRequest.Create()
+			if apiErr != nil {
+				return applied, fmt.Errorf("%s: %w", action, apiErr)
+			}
+			action.CronID = cronID
+		case ActionUpdate:
+			updateRequest := UpdateRequest{CronID: action.CronID, Spec: action.Spec}
+			_, apiErr := updateRequest.Update()
+			if apiErr != nil {
+				return applied, fmt.Errorf("%s: %w", action, apiErr)
+			}
+		case ActionDelete:
+			deleteRequest := DeleteRequest{CronID: action.CronID}
+			_, apiErr := deleteRequest.Delete()
+			if apiErr != nil {
+				return applied, fmt.Errorf("%s: %w", action, apiErr)
+			}
+		}
+		applied = append(applied, action)
+	}
+
+	return applied, nil
+}
+
+// Reconcile plans and, unless dryRun is set, applies the actions converging the owned cron jobs
+// to desired. The plan is returned in both cases.
+func (reconciler *Reconciler) Reconcile(desired map[string]Spec, dryRun bool) (Plan, error) {
+
+	plan, err := reconciler.Plan(desired)
+	if err != nil || dryRun {
+		return plan, err
+	}
+
+	_, err = reconciler.Apply(plan)
+	return plan, err
+}
+
+// ownedSpec returns the desired spec with its name, ownership and managed fields labels set
+func (reconciler *Reconciler) ownedSpec(name string, spec Spec) (Spec, error) {
+
+	spec.Name = name
+	fields, err := specFields(spec)
+	if err != nil {
+		return Spec{}, err
+	}
+
+	labels := make(map[string]string, len(spec.Labels)+2)
+	for key, value := range spec.Labels {
+		labels[key] = value
+	}
+	labels[OwnerLabel] = reconciler.Owner
+	labels[ManagedFieldsLabel] = strings.Join(managedFields("", fields), ",")
+
+	spec.Labels = labels
+	return spec, nil
+}
+
+// managedFields returns the sorted paths of the JSON fields set in a spec, members of objects
+// are listed as `object.member`. Fields compared as a whole are not descended into
+func managedFields(prefix string, fields map[string]interface{}) []string {
+
+	var paths []string
+	for name, value := range fields {
+		path := prefix + name
+		paths = append(paths, path)
+		if object, ok := value.(map[string]interface{}); ok && !exactFields[path] {
+			paths = append(paths, managedFields(path+".", object)...)
+		}
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// exactFields are the JSON fields of a spec compared as a whole, extra entries set on the
+// platform are a change
+var exactFields = map[string]bool{"labels": true}
+
+// specChanges returns the sorted JSON field names whose values differ between two specs. Only
+// the fields set in desired are compared, fields it omits keep the value defaulted by the platform
+// unless ManagedFieldsLabel of current shows they were set before, then they are reset.
+func specChanges(current, desired Spec) ([]string, error) {
+
+	currentFields, err := specFields(current)
+	if err != nil {
+		return nil, err
+	}
+	desiredFields, err := specFields(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for name, value := range desiredFields {
+		if exactFields[name] {
+			changed[name] = !reflect.DeepEqual(currentFields[name], value)
+		} else {
+			changed[name] = fieldChanged(currentFields[name], value)
+		}
+	}
+
+	// fields removed from the desired spec since the job was last reconciled
+	managed := map[string]bool{}
+	for _, path := range strings.Split(desired.Labels[ManagedFieldsLabel], ",") {
+		managed[path] = true
+	}
+	for _, path := range strings.Split(current.Labels[ManagedFieldsLabel], ",") {
+		if path != "" && !managed[path] {
+			changed[strings.SplitN(path, ".", 2)[0]] = true
+		}
+	}
+
+	var changes []string
+	for name, isChanged := range changed {
+		if isChanged {
+			changes = append(changes, name)
+		}
+	}
+	sort.Strings(changes)
+
+	return changes, nil
+}
+
+// fieldChanged reports whether the desired JSON value differs from the current one, comparing
+// only the members set in desired objects
+func fieldChanged(current, desired interface{}) bool {
+
+	desiredObject, ok := desired.(map[string]interface{})
+	if !ok {
+		return !reflect.DeepEqual(current, desired)
+	}
+	currentObject, ok := current.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	for name, value := range desiredObject {
+		if fieldChanged(currentObject[name], value) {
+			return true
+		}
+	}
+	return false
+}
+
+// specFields returns the JSON fields of a spec, so that every field sent to the platform is compared
+func specFields(spec Spec) (map[string]interface{}, error) {
+	content, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	err = json.Unmarshal(content, &fields)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,166 @@
+package cron
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+func TestPlanComparesDesiredFields(t *testing.T) {
+
+	reconciler := Reconciler{Owner: "app-manager"}
+	desired := map[string]Spec{
+		"defaulted": {Schedule: "*/5 * * * *", CallbackURL: "https://example.com/a", Retry: &RetryPolicy{MaxRetries: 3}},
+		"changed":   {Schedule: "*/10 * * * *", CallbackURL: "https://example.com/b"},
+		"labelled":  {Schedule: "0 * * * *", CallbackURL: "https://example.com/c"},
+	}
+
+	// existing jobs carry the defaults filled in by the platform
+	existing := func(cronID, name string, spec Spec) Cron {
+		spec, err := reconciler.ownedSpec(name, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spec.Timeout = time.Minute
+		spec.ConcurrencyPolicy = AllowConcurrent
+		if spec.Retry != nil {
+			retry := *spec.Retry
+			retry.InitialBackoff = DefaultInitialBackoff
+			retry.BackoffMultiplier = DefaultBackoffMultiplier
+			spec.Retry = &retry
+		}
+		return Cron{CronID: cronID, Spec: spec}
+	}
+	changed := desired["changed"]
+	changed.Schedule = "*/15 * * * *"
+	labelled := existing("3", "labelled", desired["labelled"])
+	labelled.Labels["extra"] = "label"
+	cronList := []Cron{
+		existing("1", "defaulted", desired["defaulted"]),
+		existing("2", "changed", changed),
+		labelled,
+	}
+
+	server := platformtest.Start(t)
+	server.Handle("CronList", func(json.RawMessage) interface{} {
+		return ListResponse{APIResponse: platformtest.OK(), CronList: cronList}
+	})
+
+	plan, err := reconciler.Plan(desired)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if !reflect.DeepEqual(plan.Unchanged, []string{"defaulted"}) {
+		t.Fatalf("unchanged %v, want [defaulted]", plan.Unchanged)
+	}
+
+	changes := map[string][]string{}
+	for _, action := range plan.Actions {
+		if action.Type != ActionUpdate {
+			t.Fatalf("unexpected action %s", action)
+		}
+		changes[action.Name] = action.Changes
+	}
+	want := map[string][]string{"changed": {"schedule"}, "labelled": {"labels"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes %v, want %v", changes, want)
+	}
+}
+
+func TestPlanResetsRemovedFields(t *testing.T) {
+
+	reconciler := Reconciler{Owner: "app-manager"}
+	previous := Spec{
+		Schedule: "0 * * * *", CallbackURL: "https://example.com/a", Timeout: 5 * time.Minute,
+		Jitter: time.Minute, Retry: &RetryPolicy{MaxRetries: 3, InitialBackoff: time.Second},
+	}
+	desired := Spec{
+		Schedule: "0 * * * *", CallbackURL: "https://example.com/a", Jitter: time.Minute,
+		Retry: &RetryPolicy{MaxRetries: 3},
+	}
+
+	// job was reconciled from the previous spec, unlabelled before the managed fields label existed
+	reconciled, err := reconciler.ownedSpec("job", previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlabelled := reconciled
+	unlabelled.Name = "unlabelled"
+	unlabelled.Labels = map[string]string{OwnerLabel: "app-manager"}
+	cronList := []Cron{{CronID: "1", Spec: reconciled}, {CronID: "2", Spec: unlabelled}}
+
+	server := platformtest.Start(t)
+	server.Handle("CronList", func(json.RawMessage) interface{} {
+		return ListResponse{APIResponse: platformtest.OK(), CronList: cronList}
+	})
+	server.Handle("CronUpdate", func(json.RawMessage) interface{} {
+		return UpdateResponse{APIResponse: platformtest.OK()}
+	})
+
+	plan, err := reconciler.Reconcile(map[string]Spec{"job": desired, "unlabelled": previous}, false)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	changes := map[string][]string{}
+	for _, action := range plan.Actions {
+		changes[action.Name] = action.Changes
+	}
+	want := map[string][]string{"job": {"labels", "retry", "timeout"}, "unlabelled": {"labels"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes %v, want %v", changes, want)
+	}
+
+	// the update sends the full spec, resetting the removed fields to their defaults
+	updated := map[string]interface{}{}
+	json.Unmarshal(server.Requests("CronUpdate")[0], &updated)
+	if _, ok := updated["timeout"]; ok {
+		t.Fatalf("update %v sets the removed timeout", updated)
+	}
+	if retry := updated["retry"].(map[string]interface{}); retry["initial_backoff"] != nil {
+		t.Fatalf("update %v sets the removed initial backoff", updated)
+	}
+
+	// once the platform has applied its defaults the job has converged
+	converged, err := reconciler.ownedSpec("job", desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converged.Timeout = time.Minute
+	converged.Retry = &RetryPolicy{MaxRetries: 3, InitialBackoff: DefaultInitialBackoff}
+	unlabelled, err = reconciler.ownedSpec("unlabelled", previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cronList = []Cron{{CronID: "1", Spec: converged}, {CronID: "2", Spec: unlabelled}}
+
+	plan, err = reconciler.Plan(map[string]Spec{"job": desired, "unlabelled": previous})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.Actions) != 0 || !reflect.DeepEqual(plan.Unchanged, []string{"job", "unlabelled"}) {
+		t.Fatalf("plan %+v, want both jobs unchanged", plan)
+	}
+}
+
+func TestManagedFields(t *testing.T) {
+
+	reconciler := Reconciler{Owner: "app-manager"}
+	spec, err := reconciler.ownedSpec("job", Spec{
+		Schedule: "0 * * * *", CallbackURL: "https://example.com/a", Labels: map[string]string{"team": "a"},
+		Retry: &RetryPolicy{MaxRetries: 3, MaxBackoff: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "callback_message_content,callback_url,labels,name,retry,retry.max_backoff,retry.max_retries,schedule"
+	if spec.Labels[ManagedFieldsLabel] != want {
+		t.Fatalf("managed fields %q, want %q", spec.Labels[ManagedFieldsLabel], want)
+	}
+	if spec.Labels[OwnerLabel] != "app-manager" || spec.Labels["team"] != "a" || spec.Name != "job" {
+		t.Fatalf("owned spec %+v, want the name and labels set", spec)
+	}
+}
@@ -0,0 +1,189 @@
+package cron
+
+import (
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// RunStatus is the state of a single execution of a cron job
+type RunStatus string
+
+const (
+	// RunPending runs are scheduled but not delivered yet
+	RunPending RunStatus = "Pending"
+	// RunSucceeded runs were delivered and the callback returned a 2xx status
+	RunSucceeded RunStatus = "Succeeded"
+	// RunRetrying runs failed at least once and will be delivered again
+	RunRetrying RunStatus = "Retrying"
+	// RunFailed runs failed on every delivery attempt
+	RunFailed RunStatus = "Failed"
+	// RunSkipped runs were not delivered, eg. because the job was paused
+	RunSkipped RunStatus = "Skipped"
+)
+
+// RunTrigger is what caused a run
+type RunTrigger string
+
+const (
+	// TriggerSchedule runs were started by the schedule
+	TriggerSchedule RunTrigger = "Schedule"
+	// TriggerManual runs were started by a trigger request
+	TriggerManual RunTrigger = "Manual"
+)
+
+// Run is a single execution of a cron job
+type Run struct {
+	// Unique ID of the run
+	RunID string `json:"run_id"`
+	// ID of the cron job
+	CronID string `json:"cron_id"`
+	// What caused the run
+	Trigger RunTrigger `json:"trigger"`
+	// Status of the run
+	Status RunStatus `json:"status"`
+	// Time at which the run was due
+	ScheduledAt time.Time `json:"scheduled_at"`
+	// Time at which the callback was last delivered, zero if never delivered
+	DeliveredAt time.Time `json:"delivered_at,omitempty"`
+	// HTTP status returned by the callback on the last delivery, zero if no response was received
+	CallbackStatusCode int `json:"callback_status_code,omitempty"`
+	// Time taken by the callback to respond on the last delivery
+	Latency time.Duration `json:"latency,omitempty"`
+	// Number of deliveries after the first one
+	Retries int `json:"retries"`
+	// Error of the last failed delivery
+	Error string `json:"error,omitempty"`
+}
+
+// ListRunsRequest is used to fetch the run history of a cron job
+type ListRunsRequest struct {
+	// ID of the cron job
+	CronID string `json:"cron_id"`
+	// Only list runs scheduled at or after Since. Omit to list from the oldest run retained
+	Since *time.Time `json:"since,omitempty"`
+	// Only list runs scheduled before Until. Omit to list up to the latest run
+	Until *time.Time `json:"until,omitempty"`
+	// Only list runs in this status. Omit to list runs in all statuses
+	Status RunStatus `json:"status,omitempty"`
+	// Maximum number of runs returned, newest first. Omit to use the platform default
+	Limit int `json:"limit,omitempty"`
+}
+
+// ListRunsResponse is the run history of a cron job
+type ListRunsResponse struct {
+	common.APIResponse
+	// Runs, newest first
+	Runs []Run `json:"runs,omitempty"`
+}
+
+// TriggerRequest is used to run a cron job immediately, outside its schedule
+type TriggerRequest struct {
+	// ID of the cron job
+	CronID string `json:"cron_id"`
+	// Content to pass to the callback instead of the job CallbackMessageContent. Optional
+	CallbackMessageContent string `json:"callback_message_content,omitempty"`
+}
+
+// TriggerResponse is the response to the trigger request
+type TriggerResponse struct {
+	common.APIResponse
+	// Unique ID of the run started
+	RunID string `json:"run_id"`
+}
+
+// PauseRequest is used to stop a cron job from running without deleting it
+type PauseRequest struct {
+	// ID of the cron job
+	CronID string `json:"cron_id"`
+}
+
+// ResumeRequest is used to resume a paused cron job. Runs missed while paused are not delivered
+type ResumeRequest struct {
+	// ID of the cron job
+	CronID string `json:"cron_id"`
+}
+
+// PauseResponse is the response to pause and resume requests
+type PauseResponse struct {
+	common.APIResponse
+}
+
+// ListRuns returns the run history of a cron job
+func (listRequest *ListRunsRequest) ListRuns() (runs []Run, apiErr *common.Error) {
+
+	listResponse := ListRunsResponse{}
+	err := common.Execute("CronListRuns", listRequest, &listResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if listResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        listResponse.StatusCode,
+			ErrorDescription: listResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return listResponse.Runs, nil
+}
+
+// Trigger runs the cron job now and returns the ID of the run, the job schedule is unchanged
+func (triggerRequest *TriggerRequest) Trigger() (runID string, apiErr *common.Error) {
+
+	triggerResponse := TriggerResponse{}
+	err := common.Execute("CronTrigger", triggerRequest, &triggerResponse)
+
+	if err != nil {
+		return "", err
+	}
+
+	if triggerResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        triggerResponse.StatusCode,
+			ErrorDescription: triggerResponse.Description,
+		}
+
+		return "", apiErr
+	}
+
+	return triggerResponse.RunID, nil
+}
+
+// Pause stops the cron job from running until it is resumed
+func (pauseRequest *PauseRequest) Pause() (success bool, apiErr *common.Error) {
+	return setPaused("CronPause", pauseRequest)
+}
+
+// Resume resumes a paused cron job from its next scheduled time
+func (resumeRequest *ResumeRequest) Resume() (success bool, apiErr *common.Error) {
+	return setPaused("CronResume", resumeRequest)
+}
+
+// setPaused executes a pause or resume request
+func setPaused(endpoint string, request interface{}) (success bool, apiErr *common.Error) {
+
+	pauseResponse := PauseResponse{}
+	err := common.Execute(endpoint, request, &pauseResponse)
+
+	if err != nil {
+		return false, err
+	}
+
+	if pauseResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        pauseResponse.StatusCode,
+			ErrorDescription: pauseResponse.Description,
+		}
+
+		return false, apiErr
+	}
+
+	return true, nil
+}
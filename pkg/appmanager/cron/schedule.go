@@ -0,0 +1,399 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field names used in parse errors
+const (
+	FieldMinute     = "minute"
+	FieldHour       = "hour"
+	FieldDayOfMonth = "day of month"
+	FieldMonth      = "month"
+	FieldDayOfWeek  = "day of week"
+	FieldTimeZone   = "time zone"
+)
+
+// maxSearchYears bounds the search for the next fire time, schedules such as `0 0 30 2 *` never fire
+const maxSearchYears = 5
+
+// Err* is used to throw errors while parsing a schedule
+var (
+	ErrFieldCount      = errors.New("expected 5 fields: minute hour day-of-month month day-of-week")
+	ErrUnknownMacro    = errors.New("unknown macro, expected one of @yearly @annually @monthly @weekly @daily @midnight @hourly")
+	ErrEmptyValue      = errors.New("empty value")
+	ErrInvalidValue    = errors.New("invalid value")
+	ErrOutOfRange      = errors.New("value out of range")
+	ErrInvalidRange    = errors.New("range start is after range end")
+	ErrInvalidStep     = errors.New("step must be a positive number")
+	ErrUnknownTimeZone = errors.New("unknown time zone")
+)
+
+// ParseError points at the field of a schedule that could not be parsed
+type ParseError struct {
+	// Field is one of the Field* constants
+	Field string
+	// Value is the text of the field
+	Value string
+	// Err is the reason the field is invalid
+	Err error
+}
+
+// Error describes the invalid field
+func (parseError *ParseError) Error() string {
+	if parseError.Field == "" {
+		return fmt.Sprintf("invalid schedule %q: %s", parseError.Value, parseError.Err)
+	}
+	return fmt.Sprintf("invalid %s field %q: %s", parseError.Field, parseError.Value, parseError.Err)
+}
+
+// Unwrap returns the reason the field is invalid
+func (parseError *ParseError) Unwrap() error {
+	return parseError.Err
+}
+
+// field describes the allowed values of a schedule field
+type field struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minuteField     = field{name: FieldMinute, min: 0, max: 59}
+	hourField       = field{name: FieldHour, min: 0, max: 23}
+	dayOfMonthField = field{name: FieldDayOfMonth, min: 1, max: 31}
+	monthField      = field{name: FieldMonth, min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr",
+		"may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	// 7 is accepted as Sunday and folded onto 0
+	dayOfWeekField = field{name: FieldDayOfWeek, min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed",
+		"thu", "fri", "sat"}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a parsed crontab expression
+type Schedule struct {
+	expression string
+	fields     [5]string
+	location   *time.Location
+
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	// dayOfMonthAny and dayOfWeekAny are set for fields starting with `*`. When both day fields
+	// are restricted a day matches if either matches, as in the standard crontab
+	dayOfMonthAny, dayOfWeekAny bool
+}
+
+// Parse parses a crontab expression evaluated in UTC. The expression is either 5 space separated
+// fields or one of the @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly
+// macros. Fields accept `*`, values, ranges `a-b`, steps `*/n` `a-b/n` `a/n` and comma separated
+// lists of them. Months and days of week also accept three letter English names.
+func Parse(expression string) (*Schedule, error) {
+	return ParseInLocation(expression, time.UTC)
+}
+
+// ParseInLocation parses a crontab expression evaluated in the given location
+func ParseInLocation(expression string, location *time.Location) (*Schedule, error) {
+
+	expanded := strings.TrimSpace(expression)
+	if strings.HasPrefix(expanded, "@") {
+		macro, ok := macros[strings.ToLower(expanded)]
+		if !ok {
+			return nil, &ParseError{Value: expression, Err: ErrUnknownMacro}
+		}
+		expanded = macro
+	}
+
+	fields := strings.Fields(expanded)
+	if len(fields) != 5 {
+		return nil, &ParseError{Value: expression, Err: ErrFieldCount}
+	}
+
+	schedule := &Schedule{expression: expression, location: location}
+	copy(schedule.fields[:], fields)
+
+	var err error
+	schedule.minute, err = minuteField.parse(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	schedule.hour, err = hourField.parse(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	schedule.dayOfMonth, err = dayOfMonthField.parse(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	schedule.month, err = monthField.parse(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	schedule.dayOfWeek, err = dayOfWeekField.parse(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek = schedule.dayOfWeek&^(1<<7) | 1
+	}
+
+	schedule.dayOfMonthAny = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	schedule.dayOfWeekAny = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+
+	return schedule, nil
+}
+
+// parse parses a field into a bit set of allowed values
+func (f field) parse(text string) (uint64, error) {
+
+	var bits uint64
+	for _, item := range strings.Split(text, ",") {
+		itemBits, err := f.parseItem(item)
+		if err != nil {
+			return 0, &ParseError{Field: f.name, Value: text, Err: err}
+		}
+		bits |= itemBits
+	}
+
+	return bits, nil
+}
+
+// parseItem parses one element of a comma separated list
+func (f field) parseItem(item string) (uint64, error) {
+
+	if item == "" {
+		return 0, ErrEmptyValue
+	}
+
+	rangeText, stepText, hasStep := item, "", false
+	if i := strings.IndexByte(item, '/'); i >= 0 {
+		rangeText, stepText, hasStep = item[:i], item[i+1:], true
+	}
+
+	start, end := f.min, f.max
+	switch {
+	case rangeText == "*" || rangeText == "?":
+	case strings.Contains(rangeText, "-"):
+		parts := strings.SplitN(rangeText, "-", 2)
+		var err error
+		start, err = f.value(parts[0])
+		if err != nil {
+			return 0, err
+		}
+		end, err = f.value(parts[1])
+		if err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidRange, rangeText)
+		}
+	default:
+		var err error
+		start, err = f.value(rangeText)
+		if err != nil {
+			return 0, err
+		}
+		end = start
+		if hasStep {
+			end = f.max
+		}
+	}
+
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepText)
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidStep, stepText)
+		}
+	}
+
+	var bits uint64
+	for value := start; value <= end; value += step {
+		bits |= 1 << uint(value)
+	}
+
+	return bits, nil
+}
+
+// value parses a number or a name within the bounds of the field
+func (f field) value(text string) (int, error) {
+
+	if text == "" {
+		return 0, ErrEmptyValue
+	}
+
+	lower := strings.ToLower(text)
+	for i, name := range f.names {
+		if name != "" && name == lower {
+			return i, nil
+		}
+	}
+
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidValue, text)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%w: %d is not within %d-%d", ErrOutOfRange, value, f.min, f.max)
+	}
+
+	return value, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (schedule *Schedule) String() string {
+	return schedule.expression
+}
+
+// Location returns the location the schedule is evaluated in
+func (schedule *Schedule) Location() *time.Location {
+	return schedule.location
+}
+
+// Next returns the first fire time strictly after the given time, or the zero time if the
+// schedule does not fire within the next five years. On a daylight saving transition, times
+// skipped by the clock do not fire and repeated times may fire twice.
+func (schedule *Schedule) Next(after time.Time) time.Time {
+
+	location := schedule.location
+	t := after.In(location)
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + maxSearchYears
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for schedule.month&(1<<uint(t.Month())) == 0 {
+		t = startOfDay(t.Year(), t.Month()+1, 1, location)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !schedule.dayMatches(t) {
+		t = startOfDay(t.Year(), t.Month(), t.Day()+1, location)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	// hours are stepped in elapsed time, the wall clock hour after one skipped by a daylight
+	// saving transition does not exist and time.Date could normalise it backwards
+	for schedule.hour&(1<<uint(t.Hour())) == 0 {
+		day := t.Day()
+		t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		if t.Day() != day {
+			goto wrap
+		}
+	}
+
+	for schedule.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+// startOfDay returns the first instant of a day, which is after midnight when a daylight saving
+// transition skips midnight. Day overflows are normalised as in time.Date
+func startOfDay(year int, month time.Month, day int, location *time.Location) time.Time {
+
+	noon := time.Date(year, month, day, 12, 0, 0, 0, location)
+	t := time.Date(noon.Year(), noon.Month(), noon.Day(), 0, 0, 0, 0, location)
+	for t.Day() != noon.Day() {
+		t = t.Add(time.Hour)
+	}
+
+	return t
+}
+
+// NextN returns the next n fire times after the given time
+func (schedule *Schedule) NextN(after time.Time, n int) []time.Time {
+
+	times := make([]time.Time, 0, n)
+	for len(times) < n {
+		after = schedule.Next(after)
+		if after.IsZero() {
+			break
+		}
+		times = append(times, after)
+	}
+
+	return times
+}
+
+// dayMatches reports whether the day of t is allowed by the day of month and day of week fields
+func (schedule *Schedule) dayMatches(t time.Time) bool {
+
+	dayOfMonth := schedule.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := schedule.dayOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if schedule.dayOfMonthAny || schedule.dayOfWeekAny {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// ParseSchedule parses the schedule of the spec in its time zone
+func (spec *Spec) ParseSchedule() (*Schedule, error) {
+
+	location := time.UTC
+	if spec.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(spec.TimeZone)
+		if err != nil {
+			return nil, &ParseError{Field: FieldTimeZone, Value: spec.TimeZone, Err: ErrUnknownTimeZone}
+		}
+	}
+
+	return ParseInLocation(spec.Schedule, location)
+}
+
+// Validate checks the schedule, time zone and execution policies of the spec
+func (spec *Spec) Validate() error {
+	_, err := spec.ParseSchedule()
+	if err != nil {
+		return err
+	}
+	return spec.validatePolicies()
+}
+
+// Next returns the next n fire times of the spec from now
+func (spec *Spec) Next(n int) ([]time.Time, error) {
+
+	schedule, err := spec.ParseSchedule()
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule.NextN(time.Now(), n), nil
+}
+
+// Describe returns a human-readable description of the spec schedule
+func (spec *Spec) Describe() (string, error) {
+
+	schedule, err := spec.ParseSchedule()
+	if err != nil {
+		return "", err
+	}
+
+	return schedule.Describe(), nil
+}
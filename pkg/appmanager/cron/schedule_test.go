@@ -0,0 +1,377 @@
+package cron
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// bits returns the bit set of the given values
+func bits(values ...int) uint64 {
+	var set uint64
+	for _, value := range values {
+		set |= 1 << uint(value)
+	}
+	return set
+}
+
+// span returns the values from start to end with a step
+func span(start, end, step int) []int {
+	values := []int{}
+	for value := start; value <= end; value += step {
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestParseFields(t *testing.T) {
+
+	tests := []struct {
+		expression                                 string
+		minute, hour, dayOfMonth, month, dayOfWeek uint64
+	}{
+		{"* * * * *", bits(span(0, 59, 1)...), bits(span(0, 23, 1)...), bits(span(1, 31, 1)...),
+			bits(span(1, 12, 1)...), bits(span(0, 6, 1)...)},
+		{"5 4 3 2 1", bits(5), bits(4), bits(3), bits(2), bits(1)},
+		{"0-10 9-17 1-7 6-8 1-5", bits(span(0, 10, 1)...), bits(span(9, 17, 1)...), bits(span(1, 7, 1)...),
+			bits(6, 7, 8), bits(1, 2, 3, 4, 5)},
+		{"*/15 */6 */10 */3 */2", bits(0, 15, 30, 45), bits(0, 6, 12, 18), bits(1, 11, 21, 31),
+			bits(1, 4, 7, 10), bits(0, 2, 4, 6)},
+		{"5/20 1/12 2/15 2/6 1/3", bits(5, 25, 45), bits(1, 13), bits(2, 17), bits(2, 8), bits(0, 1, 4)},
+		{"10-30/10 0-12/4 1-10/3 1-6/2 0-6/3", bits(10, 20, 30), bits(0, 4, 8, 12), bits(1, 4, 7, 10),
+			bits(1, 3, 5), bits(0, 3, 6)},
+		{"0,15,45 0,12 1,15,31 1,7 0,6", bits(0, 15, 45), bits(0, 12), bits(1, 15, 31), bits(1, 7), bits(0, 6)},
+		{"1,10-12,*/30 * * * *", bits(0, 1, 10, 11, 12, 30), bits(span(0, 23, 1)...), bits(span(1, 31, 1)...),
+			bits(span(1, 12, 1)...), bits(span(0, 6, 1)...)},
+		{"0 0 ? jan-mar,DEC mon-fri", bits(0), bits(0), bits(span(1, 31, 1)...), bits(1, 2, 3, 12),
+			bits(1, 2, 3, 4, 5)},
+		{"0 0 * Jun SUN,sat", bits(0), bits(0), bits(span(1, 31, 1)...), bits(6), bits(0, 6)},
+		{"0 0 * * 7", bits(0), bits(0), bits(span(1, 31, 1)...), bits(span(1, 12, 1)...), bits(0)},
+		{"0 0 * * 5-7", bits(0), bits(0), bits(span(1, 31, 1)...), bits(span(1, 12, 1)...), bits(0, 5, 6)},
+		{"@hourly", bits(0), bits(span(0, 23, 1)...), bits(span(1, 31, 1)...), bits(span(1, 12, 1)...),
+			bits(span(0, 6, 1)...)},
+		{"@YEARLY", bits(0), bits(0), bits(1), bits(1), bits(span(0, 6, 1)...)},
+		{"  @weekly ", bits(0), bits(0), bits(span(1, 31, 1)...), bits(span(1, 12, 1)...), bits(0)},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.expression)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.expression, err)
+			continue
+		}
+		got := [5]uint64{schedule.minute, schedule.hour, schedule.dayOfMonth, schedule.month, schedule.dayOfWeek}
+		want := [5]uint64{test.minute, test.hour, test.dayOfMonth, test.month, test.dayOfWeek}
+		for i, name := range []string{FieldMinute, FieldHour, FieldDayOfMonth, FieldMonth, FieldDayOfWeek} {
+			if got[i] != want[i] {
+				t.Errorf("Parse(%q): %s = %b, want %b", test.expression, name, got[i], want[i])
+			}
+		}
+		if schedule.String() != test.expression {
+			t.Errorf("Parse(%q): String() = %q", test.expression, schedule.String())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	tests := []struct {
+		expression string
+		field      string
+		err        error
+		message    string
+	}{
+		{"", "", ErrFieldCount,
+			`invalid schedule "": expected 5 fields: minute hour day-of-month month day-of-week`},
+		{"* * * *", "", ErrFieldCount,
+			`invalid schedule "* * * *": expected 5 fields: minute hour day-of-month month day-of-week`},
+		{"* * * * * *", "", ErrFieldCount,
+			`invalid schedule "* * * * * *": expected 5 fields: minute hour day-of-month month day-of-week`},
+		{"@fortnightly", "", ErrUnknownMacro,
+			`invalid schedule "@fortnightly": unknown macro, expected one of @yearly @annually @monthly @weekly @daily @midnight @hourly`},
+		{"1,,2 * * * *", FieldMinute, ErrEmptyValue, `invalid minute field "1,,2": empty value`},
+		{"-5 * * * *", FieldMinute, ErrEmptyValue, `invalid minute field "-5": empty value`},
+		{"* 1- * * *", FieldHour, ErrEmptyValue, `invalid hour field "1-": empty value`},
+		{"x * * * *", FieldMinute, ErrInvalidValue, `invalid minute field "x": invalid value: "x"`},
+		{"1-2-3 * * * *", FieldMinute, ErrInvalidValue, `invalid minute field "1-2-3": invalid value: "2-3"`},
+		{"* * * * monday", FieldDayOfWeek, ErrInvalidValue, `invalid day of week field "monday": invalid value: "monday"`},
+		{"* * * jan-foo *", FieldMonth, ErrInvalidValue, `invalid month field "jan-foo": invalid value: "foo"`},
+		{"60 * * * *", FieldMinute, ErrOutOfRange, `invalid minute field "60": value out of range: 60 is not within 0-59`},
+		{"* 24 * * *", FieldHour, ErrOutOfRange, `invalid hour field "24": value out of range: 24 is not within 0-23`},
+		{"* * 0 * *", FieldDayOfMonth, ErrOutOfRange,
+			`invalid day of month field "0": value out of range: 0 is not within 1-31`},
+		{"* * 32 * *", FieldDayOfMonth, ErrOutOfRange,
+			`invalid day of month field "32": value out of range: 32 is not within 1-31`},
+		{"* * * 13 *", FieldMonth, ErrOutOfRange, `invalid month field "13": value out of range: 13 is not within 1-12`},
+		{"* * * * 8", FieldDayOfWeek, ErrOutOfRange,
+			`invalid day of week field "8": value out of range: 8 is not within 0-7`},
+		{"* 5-3 * * *", FieldHour, ErrInvalidRange, `invalid hour field "5-3": range start is after range end: 5-3`},
+		{"* * * dec-jan *", FieldMonth, ErrInvalidRange,
+			`invalid month field "dec-jan": range start is after range end: dec-jan`},
+		{"*/0 * * * *", FieldMinute, ErrInvalidStep, `invalid minute field "*/0": step must be a positive number: "0"`},
+		{"*/-1 * * * *", FieldMinute, ErrInvalidStep,
+			`invalid minute field "*/-1": step must be a positive number: "-1"`},
+		{"* */x * * *", FieldHour, ErrInvalidStep, `invalid hour field "*/x": step must be a positive number: "x"`},
+		{"5/ * * * *", FieldMinute, ErrInvalidStep, `invalid minute field "5/": step must be a positive number: ""`},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.expression)
+		parseError := &ParseError{}
+		if !errors.As(err, &parseError) {
+			t.Errorf("Parse(%q) error = %v, want a ParseError", test.expression, err)
+			continue
+		}
+		if parseError.Field != test.field || !errors.Is(err, test.err) || err.Error() != test.message {
+			t.Errorf("Parse(%q) error = %q in field %q, want %q in field %q", test.expression, err,
+				parseError.Field, test.message, test.field)
+		}
+	}
+}
+
+func TestSpecParseSchedule(t *testing.T) {
+
+	spec := Spec{Schedule: "0 9 * * *", TimeZone: "Europe/Berlin"}
+	schedule, err := spec.ParseSchedule()
+	if err != nil || schedule.Location().String() != "Europe/Berlin" {
+		t.Fatalf("ParseSchedule = %v, %v, want a schedule in Europe/Berlin", schedule, err)
+	}
+
+	spec = Spec{Schedule: "0 9 * * *"}
+	schedule, err = spec.ParseSchedule()
+	if err != nil || schedule.Location() != time.UTC {
+		t.Fatalf("ParseSchedule = %v, %v, want a schedule in UTC", schedule, err)
+	}
+
+	spec = Spec{Schedule: "0 9 * * *", TimeZone: "Mars/Olympus_Mons"}
+	_, err = spec.ParseSchedule()
+	if !errors.Is(err, ErrUnknownTimeZone) || err.Error() != `invalid time zone field "Mars/Olympus_Mons": unknown time zone` {
+		t.Fatalf("ParseSchedule error = %v, want an unknown time zone error", err)
+	}
+	if err := spec.Validate(); !errors.Is(err, ErrUnknownTimeZone) {
+		t.Fatalf("Validate error = %v, want ErrUnknownTimeZone", err)
+	}
+	if _, err := spec.Describe(); !errors.Is(err, ErrUnknownTimeZone) {
+		t.Fatalf("Describe error = %v, want ErrUnknownTimeZone", err)
+	}
+	if _, err := spec.Next(1); !errors.Is(err, ErrUnknownTimeZone) {
+		t.Fatalf("Next error = %v, want ErrUnknownTimeZone", err)
+	}
+}
+
+// utc returns a UTC time on the given day and minute
+func utc(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestScheduleNext(t *testing.T) {
+
+	tests := []struct {
+		expression string
+		after      time.Time
+		want       time.Time
+	}{
+		{"* * * * *", utc(2024, 1, 1, 0, 0), utc(2024, 1, 1, 0, 1)},
+		{"* * * * *", time.Date(2024, 1, 1, 0, 0, 59, 999, time.UTC), utc(2024, 1, 1, 0, 1)},
+		{"30 9 * * *", utc(2024, 1, 1, 9, 30), utc(2024, 1, 2, 9, 30)},
+		{"30 9 * * *", utc(2024, 1, 1, 9, 29), utc(2024, 1, 1, 9, 30)},
+		{"*/15 * * * *", utc(2024, 1, 1, 23, 50), utc(2024, 1, 2, 0, 0)},
+		{"0 */6 * * *", utc(2024, 1, 1, 7, 0), utc(2024, 1, 1, 12, 0)},
+		{"0 0 1 * *", utc(2024, 1, 15, 0, 0), utc(2024, 2, 1, 0, 0)},
+		{"0 0 31 * *", utc(2024, 2, 1, 0, 0), utc(2024, 3, 31, 0, 0)},
+		{"0 0 29 2 *", utc(2025, 1, 1, 0, 0), utc(2028, 2, 29, 0, 0)},
+		{"@yearly", utc(2024, 12, 31, 23, 59), utc(2025, 1, 1, 0, 0)},
+		{"0 12 * jun-aug *", utc(2024, 9, 1, 0, 0), utc(2025, 6, 1, 12, 0)},
+		{"0 9 * * mon-fri", utc(2024, 1, 5, 10, 0), utc(2024, 1, 8, 9, 0)},
+		{"0 9 * * 7", utc(2024, 1, 1, 0, 0), utc(2024, 1, 7, 9, 0)},
+		{"0 0 30 2 *", utc(2024, 1, 1, 0, 0), time.Time{}},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.expression)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.expression, err)
+		}
+		got := schedule.Next(test.after)
+		if !got.Equal(test.want) {
+			t.Errorf("%q: Next(%v) = %v, want %v", test.expression, test.after, got, test.want)
+		}
+	}
+}
+
+func TestScheduleNextDayInteraction(t *testing.T) {
+
+	// January 2024 starts on a Monday, the 5th, 12th, 19th and 26th are Fridays
+	tests := []struct {
+		expression string
+		want       []int
+	}{
+		// both days restricted, either matches
+		{"0 0 13 * 5", []int{5, 12, 13, 19, 26}},
+		{"0 0 1,20 * fri", []int{1, 5, 12, 19, 20, 26}},
+		// a day field starting with `*` restricts the other
+		{"0 0 * * 5", []int{5, 12, 19, 26}},
+		{"0 0 13 * *", []int{13}},
+		{"0 0 13 * ?", []int{13}},
+		{"0 0 */2 * 5", []int{5, 19}},
+		{"0 0 1-10 * */3", []int{3, 6, 7, 10}},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.expression)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.expression, err)
+		}
+		days := []int{}
+		for _, fire := range schedule.NextN(utc(2023, 12, 31, 0, 0), 31) {
+			if fire.Year() == 2024 && fire.Month() == time.January {
+				days = append(days, fire.Day())
+			}
+		}
+		if !reflect.DeepEqual(days, test.want) {
+			t.Errorf("%q fires on January %v, want %v", test.expression, days, test.want)
+		}
+	}
+}
+
+func TestScheduleNextDaylightSaving(t *testing.T) {
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	local := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, newYork)
+	}
+	// 01:30 occurs twice on 3 November in New York, first in EDT then in EST
+	firstOneThirty := time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC)
+	secondOneThirty := time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC)
+	// clocks skip from 00:00 to 01:00 on 8 September in Santiago
+	chile := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, santiago)
+	}
+
+	tests := []struct {
+		name       string
+		location   *time.Location
+		expression string
+		after      time.Time
+		want       []time.Time
+	}{
+		// clocks skip from 02:00 to 03:00 on 10 March in New York
+		{"skipped time does not fire", newYork, "30 2 * * *", local(3, 9, 12, 0), []time.Time{
+			local(3, 11, 2, 30), local(3, 12, 2, 30),
+		}},
+		{"hourly across the gap", newYork, "0 * * * *", local(3, 10, 0, 30), []time.Time{
+			local(3, 10, 1, 0), local(3, 10, 3, 0), local(3, 10, 4, 0),
+		}},
+		{"daily after the gap", newYork, "0 9 * * *", local(3, 9, 12, 0), []time.Time{
+			local(3, 10, 9, 0), local(3, 11, 9, 0),
+		}},
+		{"repeated time fires twice", newYork, "30 1 * * *", local(11, 2, 12, 0), []time.Time{
+			firstOneThirty, secondOneThirty, local(11, 4, 1, 30),
+		}},
+		{"daily after the repeat", newYork, "0 9 * * *", local(11, 2, 12, 0), []time.Time{
+			local(11, 3, 9, 0), local(11, 4, 9, 0),
+		}},
+		{"skipped midnight does not fire", santiago, "0 0 * * *", chile(9, 7, 12, 0), []time.Time{
+			chile(9, 9, 0, 0), chile(9, 10, 0, 0),
+		}},
+		{"day starting after a skipped midnight", santiago, "0 1 8 9 *", chile(9, 7, 12, 0), []time.Time{
+			chile(9, 8, 1, 0), time.Date(2025, 9, 8, 1, 0, 0, 0, santiago),
+		}},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseInLocation(test.expression, test.location)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.expression, err)
+		}
+		got := schedule.NextN(test.after, len(test.want))
+		if len(got) != len(test.want) {
+			t.Errorf("%s: NextN = %v, want %v", test.name, got, test.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(test.want[i]) {
+				t.Errorf("%s: NextN = %v, want %v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestScheduleNextN(t *testing.T) {
+
+	schedule, err := Parse("0 9,17 * * mon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := schedule.NextN(utc(2024, 1, 1, 12, 0), 4)
+	want := []time.Time{utc(2024, 1, 1, 17, 0), utc(2024, 1, 8, 9, 0), utc(2024, 1, 8, 17, 0), utc(2024, 1, 15, 9, 0)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("NextN = %v, want %v", got, want)
+	}
+
+	if got := schedule.NextN(utc(2024, 1, 1, 12, 0), 0); len(got) != 0 {
+		t.Fatalf("NextN(0) = %v, want no times", got)
+	}
+
+	never, err := Parse("0 0 31 4 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := never.NextN(utc(2024, 1, 1, 0, 0), 3); len(got) != 0 {
+		t.Fatalf("NextN of a schedule that never fires = %v, want no times", got)
+	}
+}
+
+func TestScheduleDescribe(t *testing.T) {
+
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"30 9 * * 1-5", "At 09:30, on Monday through Friday"},
+		{"* * * * *", "Every minute"},
+		{"*/1 * * * *", "Every minute"},
+		{"*/15 * * * *", "Every 15 minutes"},
+		{"*/15 9-17 * * *", "Every 15 minutes, during hours 9 through 17"},
+		{"* 9 * * *", "Every minute, during hour 9"},
+		{"0,30 * * * *", "At minutes 0 and 30 past every hour"},
+		{"5 */2 * * *", "At minute 5 past every 2nd hour"},
+		{"10-20/5 * * * *", "At every 5th minute from 10 through 20 past every hour"},
+		{"0 0 1,15 * *", "At 00:00, on days 1 and 15 of the month"},
+		{"0 0 1-7 * *", "At 00:00, on days 1 through 7 of the month"},
+		{"0 0 13 * 5", "At 00:00, on day 13 of the month or on Friday"},
+		{"0 0 * * sat,sun", "At 00:00, on Saturday and Sunday"},
+		{"0 0 * * 7", "At 00:00, on Sunday"},
+		{"0 12 * jan-mar,dec *", "At 12:00, in January through March and December"},
+		{"0 12 1 */3 *", "At 12:00, on day 1 of the month, in every 3rd month"},
+		{"0 0 * * 1/2", "At 00:00, on every 2nd day from Monday"},
+		{"@weekly", "At 00:00, on Sunday"},
+		{"@monthly", "At 00:00, on day 1 of the month"},
+		{"@hourly", "At minute 0 past every hour"},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.expression)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.expression, err)
+		}
+		if got := schedule.Describe(); got != test.want {
+			t.Errorf("%q: Describe() = %q, want %q", test.expression, got, test.want)
+		}
+	}
+
+	spec := Spec{Schedule: "0 9 * * *", TimeZone: "America/New_York"}
+	got, err := spec.Describe()
+	if err != nil || got != "At 09:00 (America/New_York)" {
+		t.Fatalf("Describe = %q, %v, want the time zone named", got, err)
+	}
+}
@@ -1,6 +1,8 @@
 package deployment
 
 import (
+	"fmt"
+
 	"go.semut.io/sdk/go-sdk/pkg/appmanager/workergroup"
 	"go.semut.io/sdk/go-sdk/pkg/common"
 )
@@ -140,6 +142,15 @@ func (describeRequest *DescribeRequest) Describe() (deployment Deployment, apiEr
 // Launch a new deployment, returns id of the new deployment and request ID
 func (launchRequest *LaunchRequest) Launch() (deploymentID, requestID string, apiErr *common.Error) {
 
+	for _, workerGroup := range launchRequest.WorkerGroups {
+		if err := workerGroup.ValidateSecrets(); err != nil {
+			return "", "", &common.Error{
+				ErrorCode:        "400",
+				ErrorDescription: fmt.Sprintf("worker group %q: %s", workerGroup.Name, err),
+			}
+		}
+	}
+
 	launchResponse := LaunchResponse{}
 	err := common.Execute("DeploymentLaunch", launchRequest, &launchResponse)
 
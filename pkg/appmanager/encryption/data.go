@@ -0,0 +1,193 @@
+package encryption
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// Encoding is the encoding of binary fields on the wire
+type Encoding string
+
+const (
+	// EncodingBase64 is standard base64 with padding, as produced by encoding/json for []byte
+	EncodingBase64 Encoding = "base64"
+)
+
+// MaxDataSize is the largest payload EncryptReader and DecryptReader send to the platform,
+// larger payloads should be encrypted locally with an Encrypter
+const MaxDataSize = 4 << 20
+
+// Err* is used to throw errors while encrypting binary data
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported encoding in platform response")
+	ErrDataTooLarge        = errors.New("data exceeds the maximum size, use an Encrypter instead")
+)
+
+// EncryptDataRequest is used to request encryption of binary data
+type EncryptDataRequest struct {
+	// Data to encrypt
+	Data []byte `json:"data"`
+	// AssociatedData is authenticated but not encrypted, binding the ciphertext to a context
+	// such as a deployment ID or KV key. The same value must be given to decrypt
+	AssociatedData []byte `json:"associated_data,omitempty"`
+	// Key identifier of an existing key to encrypt with. Omit to generate a new key
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier,omitempty"`
+	// Encoding of the binary fields, always set to EncodingBase64 by EncryptData
+	Encoding Encoding `json:"encoding"`
+}
+
+// EncryptDataResponse is the response to the encrypt data request
+type EncryptDataResponse struct {
+	common.APIResponse
+	// Encrypted data
+	Ciphertext []byte `json:"ciphertext"`
+	// Key identifier used during encryption
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+	// Encoding of the binary fields
+	Encoding Encoding `json:"encoding"`
+}
+
+// DecryptDataRequest is used to request decryption of binary data
+type DecryptDataRequest struct {
+	// Encrypted data
+	Ciphertext []byte `json:"ciphertext"`
+	// AssociatedData given when the data was encrypted
+	AssociatedData []byte `json:"associated_data,omitempty"`
+	// Key identifier used during encryption
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+	// Encoding of the binary fields, always set to EncodingBase64 by DecryptData
+	Encoding Encoding `json:"encoding"`
+}
+
+// DecryptDataResponse is the response to the decrypt data request
+type DecryptDataResponse struct {
+	common.APIResponse
+	// Decrypted data
+	Data []byte `json:"data"`
+	// Encoding of the binary fields
+	Encoding Encoding `json:"encoding"`
+}
+
+// EncryptData encrypts binary data and returns the ciphertext with the encryption key identifier
+func (encryptRequest *EncryptDataRequest) EncryptData() (ciphertext []byte, encryptionKeyID string, apiErr *common.Error) {
+
+	encryptRequest.Encoding = EncodingBase64
+
+	encryptResponse := EncryptDataResponse{}
+	err := common.Execute("EncryptionEncryptData", encryptRequest, &encryptResponse)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if encryptResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        encryptResponse.StatusCode,
+			ErrorDescription: encryptResponse.Description,
+		}
+
+		return nil, "", apiErr
+	}
+
+	if encryptResponse.Encoding != "" && encryptResponse.Encoding != EncodingBase64 {
+		return nil, "", &common.Error{
+			ErrorCode:        "500",
+			ErrorDescription: ErrUnsupportedEncoding.Error(),
+		}
+	}
+
+	return encryptResponse.Ciphertext, encryptResponse.EncryptionKeyIdentifier, nil
+}
+
+// DecryptData decrypts binary data and returns the plaintext
+func (decryptRequest *DecryptDataRequest) DecryptData() (data []byte, apiErr *common.Error) {
+
+	decryptRequest.Encoding = EncodingBase64
+
+	decryptResponse := DecryptDataResponse{}
+	err := common.Execute("EncryptionDecryptData", decryptRequest, &decryptResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if decryptResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        decryptResponse.StatusCode,
+			ErrorDescription: decryptResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	if decryptResponse.Encoding != "" && decryptResponse.Encoding != EncodingBase64 {
+		return nil, &common.Error{
+			ErrorCode:        "500",
+			ErrorDescription: ErrUnsupportedEncoding.Error(),
+		}
+	}
+
+	return decryptResponse.Data, nil
+}
+
+// EncryptReader reads reader until EOF and encrypts its content through the platform,
+// binding it to associatedData. Content larger than MaxDataSize is rejected.
+func EncryptReader(reader io.Reader, associatedData []byte, encryptionKeyIdentifier string) (
+	ciphertext []byte, encryptionKeyID string, err error) {
+
+	data, err := readLimited(reader)
+	if err != nil {
+		return nil, "", err
+	}
+
+	encryptRequest := EncryptDataRequest{
+		Data:                    data,
+		AssociatedData:          associatedData,
+		EncryptionKeyIdentifier: encryptionKeyIdentifier,
+	}
+	ciphertext, encryptionKeyID, apiErr := encryptRequest.EncryptData()
+	if apiErr != nil {
+		return nil, "", apiErr
+	}
+
+	return ciphertext, encryptionKeyID, nil
+}
+
+// DecryptReader reads a ciphertext from reader until EOF and decrypts it through the platform.
+// Ciphertexts larger than MaxDataSize are rejected.
+func DecryptReader(reader io.Reader, associatedData []byte, encryptionKeyIdentifier string) (data []byte, err error) {
+
+	ciphertext, err := readLimited(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	decryptRequest := DecryptDataRequest{
+		Ciphertext:              ciphertext,
+		AssociatedData:          associatedData,
+		EncryptionKeyIdentifier: encryptionKeyIdentifier,
+	}
+	data, apiErr := decryptRequest.DecryptData()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return data, nil
+}
+
+// readLimited reads reader until EOF, failing if it holds more than MaxDataSize bytes
+func readLimited(reader io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(reader, MaxDataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDataSize {
+		return nil, ErrDataTooLarge
+	}
+	return data, nil
+}
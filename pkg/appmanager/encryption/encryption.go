@@ -8,6 +8,8 @@ import (
 type EncryptContentRequest struct {
 	// String to encrypt
 	Content string `json:"content"`
+	// Key identifier of an existing key to encrypt with. Omit to generate a new key
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier,omitempty"`
 }
 
 // EncryptContentResponse is the response to the encrypt content request
@@ -46,7 +48,8 @@ type DeleteEncryptionKeyIdentifierResponse struct {
 	common.APIResponse
 }
 
-// EncryptContent ecrypts and returns content with encryption key identifier. This is useful for encrypting as well as storing secrets
+// EncryptContent ecrypts and returns content with encryption key identifier. This is useful for encrypting as well as storing secrets.
+// For bulk data, use an Encrypter to encrypt locally under a platform generated data key
 func (encryptContentRequest *EncryptContentRequest) EncryptContent() (encryptedContent, encryptionKeyID string, apiErr *common.Error) {
 
 	encryptContentResponse := EncryptContentResponse{}
@@ -73,7 +76,7 @@ func (encryptContentRequest *EncryptContentRequest) EncryptContent() (encryptedC
 func (decryptContentRequest *DecryptContentRequest) DecryptContent() (content string, apiErr *common.Error) {
 
 	decryptContentResponse := DecryptContentResponse{}
-	err := common.Execute("EncryptionEncryptContent", decryptContentRequest, &decryptContentResponse)
+	err := common.Execute("EncryptionDecryptContent", decryptContentRequest, &decryptContentResponse)
 
 	if err != nil {
 		return "", err
@@ -0,0 +1,630 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// Envelope format constants. An envelope is laid out as
+//
+//	magic (4) | version (1) | key identifier length (2) | key identifier |
+//	wrapped key length (2) | wrapped key | chunk size (4) | key salt (32, version 2 only) |
+//	nonce prefix (7) | chunks...
+//
+// Each chunk is the AES-256-GCM sealed form of up to chunk size bytes of plaintext. The nonce of
+// a chunk is the nonce prefix, a 4 byte big endian counter and a byte set to 1 on the last chunk.
+// The header, followed by any caller supplied associated data, is passed as additional data to
+// every chunk, binding both to the ciphertext. Associated data is not stored in the envelope.
+//
+// Version 1 envelopes seal chunks with the data key itself, so a data key shared by many
+// envelopes only has the 56 bit random nonce prefix to keep nonces apart. Version 2 envelopes,
+// which are the ones sealed, use a key derived from the data key and the random key salt with
+// HKDF-SHA256, so that nonces only need to be unique within an envelope.
+const (
+	EnvelopeVersion1 byte = 1
+	EnvelopeVersion2 byte = 2
+
+	// DefaultChunkSize is the size of the plaintext sealed in each chunk
+	DefaultChunkSize = 64 << 10
+
+	envelopeMagic     = "SENV"
+	dataKeySize       = 32
+	noncePrefixSize   = 7
+	keySaltSize       = 32
+	envelopeKeyInfo   = "semut envelope chunk key"
+	maxChunkSize      = 16 << 20
+	maxEnvelopeChunks = 1<<32 - 1
+)
+
+// Err* is used to throw errors while sealing or opening envelopes
+var (
+	ErrInvalidEnvelope     = errors.New("invalid envelope")
+	ErrUnsupportedEnvelope = errors.New("unsupported envelope version")
+	ErrTruncatedEnvelope   = errors.New("envelope is truncated")
+	ErrInvalidDataKey      = errors.New("data key must be 32 bytes")
+	ErrEnvelopeTooLarge    = errors.New("content too large for a single envelope")
+	ErrDataKeyDestroyed    = errors.New("data key has been destroyed")
+)
+
+// DataKey is a data encryption key generated by the platform. The plaintext key is only held
+// in memory, the wrapped key is stored with the ciphertext and unwrapped by the platform.
+type DataKey struct {
+	// Plaintext key used for local encryption
+	Plaintext []byte
+	// Wrapped key, encrypted under EncryptionKeyIdentifier
+	Wrapped []byte
+	// Key identifier of the platform key wrapping the data key
+	EncryptionKeyIdentifier string
+}
+
+// GenerateDataKeyRequest is used to request a new data key wrapped by a platform key
+type GenerateDataKeyRequest struct {
+	// Key identifier to wrap the data key with. Omit to have the platform create a new key
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier,omitempty"`
+}
+
+// GenerateDataKeyResponse is the response to the generate data key request
+type GenerateDataKeyResponse struct {
+	common.APIResponse
+	// Plaintext data key
+	DataKey []byte `json:"data_key"`
+	// Data key wrapped by the platform key
+	WrappedDataKey []byte `json:"wrapped_data_key"`
+	// Key identifier of the platform key
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+}
+
+// UnwrapDataKeyRequest is used to request the plaintext of a wrapped data key
+type UnwrapDataKeyRequest struct {
+	// Data key wrapped by the platform key
+	WrappedDataKey []byte `json:"wrapped_data_key"`
+	// Key identifier of the platform key
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+}
+
+// UnwrapDataKeyResponse is the response to the unwrap data key request
+type UnwrapDataKeyResponse struct {
+	common.APIResponse
+	// Plaintext data key
+	DataKey []byte `json:"data_key"`
+}
+
+// GenerateDataKey generates a new data key and returns it in both plaintext and wrapped form
+func (generateRequest *GenerateDataKeyRequest) GenerateDataKey() (dataKey DataKey, apiErr *common.Error) {
+
+	generateResponse := GenerateDataKeyResponse{}
+	err := common.Execute("EncryptionGenerateDataKey", generateRequest, &generateResponse)
+
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	if generateResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        generateResponse.StatusCode,
+			ErrorDescription: generateResponse.Description,
+		}
+
+		return DataKey{}, apiErr
+	}
+
+	return DataKey{
+		Plaintext:               generateResponse.DataKey,
+		Wrapped:                 generateResponse.WrappedDataKey,
+		EncryptionKeyIdentifier: generateResponse.EncryptionKeyIdentifier,
+	}, nil
+}
+
+// UnwrapDataKey returns the plaintext of a wrapped data key
+func (unwrapRequest *UnwrapDataKeyRequest) UnwrapDataKey() (dataKey []byte, apiErr *common.Error) {
+
+	unwrapResponse := UnwrapDataKeyResponse{}
+	err := common.Execute("EncryptionUnwrapDataKey", unwrapRequest, &unwrapResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if unwrapResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        unwrapResponse.StatusCode,
+			ErrorDescription: unwrapResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return unwrapResponse.DataKey, nil
+}
+
+// Encrypter seals payloads locally under a single data key, so that only one platform call
+// is needed for any number of payloads. Each envelope is sealed with its own key derived from
+// the data key, see EnvelopeVersion2.
+type Encrypter struct {
+	// ChunkSize is the size of the plaintext sealed in each chunk, defaults to DefaultChunkSize
+	ChunkSize int
+
+	mutex   sync.Mutex
+	dataKey DataKey
+}
+
+// NewEncrypter is used to create an Encrypter with a new data key wrapped by the platform key
+// encryptionKeyIdentifier, or by a new platform key if it is empty
+func NewEncrypter(encryptionKeyIdentifier string) (*Encrypter, error) {
+
+	generateRequest := GenerateDataKeyRequest{EncryptionKeyIdentifier: encryptionKeyIdentifier}
+	dataKey, apiErr := generateRequest.GenerateDataKey()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return NewEncrypterWithDataKey(dataKey)
+}
+
+// NewEncrypterWithDataKey is used to create an Encrypter from an existing data key
+func NewEncrypterWithDataKey(dataKey DataKey) (*Encrypter, error) {
+	if len(dataKey.Plaintext) != dataKeySize {
+		return nil, ErrInvalidDataKey
+	}
+	return &Encrypter{dataKey: dataKey}, nil
+}
+
+// EncryptionKeyIdentifier returns the key identifier of the platform key wrapping the data key
+func (encrypter *Encrypter) EncryptionKeyIdentifier() string {
+	return encrypter.dataKey.EncryptionKeyIdentifier
+}
+
+// Seal encrypts plaintext and returns it as an envelope
+func (encrypter *Encrypter) Seal(plaintext []byte) ([]byte, error) {
+	return encrypter.SealWithAssociatedData(plaintext, nil)
+}
+
+// SealWithAssociatedData encrypts plaintext and returns it as an envelope bound to
+// associatedData, such as a deployment ID or KV key. The envelope can only be opened
+// with the same associated data.
+func (encrypter *Encrypter) SealWithAssociatedData(plaintext, associatedData []byte) ([]byte, error) {
+	envelope := bytes.Buffer{}
+	_, err := encrypter.EncryptStreamWithAssociatedData(&envelope, bytes.NewReader(plaintext), associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Bytes(), nil
+}
+
+// EncryptStream reads plaintext from src until EOF and writes it to dst as an envelope,
+// returning the number of plaintext bytes encrypted
+func (encrypter *Encrypter) EncryptStream(dst io.Writer, src io.Reader) (n int64, err error) {
+	return encrypter.EncryptStreamWithAssociatedData(dst, src, nil)
+}
+
+// EncryptStreamWithAssociatedData is EncryptStream with the envelope bound to associatedData
+func (encrypter *Encrypter) EncryptStreamWithAssociatedData(dst io.Writer, src io.Reader,
+	associatedData []byte) (n int64, err error) {
+
+	chunkSize := encrypter.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > maxChunkSize {
+		return 0, ErrInvalidEnvelope
+	}
+
+	random := make([]byte, keySaltSize+noncePrefixSize)
+	_, err = rand.Read(random)
+	if err != nil {
+		return 0, err
+	}
+
+	header := envelopeHeader{
+		version:     EnvelopeVersion2,
+		chunkSize:   uint32(chunkSize),
+		keySalt:     random[:keySaltSize],
+		noncePrefix: random[keySaltSize:],
+	}
+
+	// the envelope key is derived under the lock, so that Destroy cannot zero the data key
+	// while it is in use and the stream never touches the shared data key afterwards
+	encrypter.mutex.Lock()
+	if encrypter.dataKey.Plaintext == nil {
+		encrypter.mutex.Unlock()
+		return 0, ErrDataKeyDestroyed
+	}
+	header.encryptionKeyIdentifier = encrypter.dataKey.EncryptionKeyIdentifier
+	header.wrappedKey = encrypter.dataKey.Wrapped
+	envelopeKey := header.envelopeKey(encrypter.dataKey.Plaintext)
+	encrypter.mutex.Unlock()
+	defer zero(envelopeKey)
+
+	headerBytes, err := header.marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	aead, err := newAEAD(envelopeKey)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = dst.Write(headerBytes)
+	if err != nil {
+		return 0, err
+	}
+	additionalData := append(headerBytes[:len(headerBytes):len(headerBytes)], associatedData...)
+
+	reader := bufio.NewReaderSize(src, chunkSize+1)
+	plaintext := make([]byte, chunkSize)
+	sealed := make([]byte, 0, chunkSize+aead.Overhead())
+	for counter := uint64(0); ; counter++ {
+		if counter > maxEnvelopeChunks {
+			return n, ErrEnvelopeTooLarge
+		}
+
+		read, err := io.ReadFull(reader, plaintext)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return n, err
+		}
+
+		last := err != nil
+		if !last {
+			_, peekErr := reader.Peek(1)
+			last = peekErr == io.EOF
+		}
+
+		sealed = aead.Seal(sealed[:0], chunkNonce(header.noncePrefix, uint32(counter), last),
+			plaintext[:read], additionalData)
+		_, err = dst.Write(sealed)
+		if err != nil {
+			return n, err
+		}
+		n += int64(read)
+
+		if last {
+			return n, nil
+		}
+	}
+}
+
+// Destroy zeroes the plaintext data key, the Encrypter cannot be used afterwards. Streams
+// already in progress complete with their own envelope key.
+func (encrypter *Encrypter) Destroy() {
+	encrypter.mutex.Lock()
+	defer encrypter.mutex.Unlock()
+
+	zero(encrypter.dataKey.Plaintext)
+	encrypter.dataKey.Plaintext = nil
+}
+
+// Decrypter opens envelopes, unwrapping their data keys through the platform. Unwrapped keys
+// are cached so that envelopes sharing a data key need a single platform call.
+type Decrypter struct {
+	mutex sync.Mutex
+	keys  map[string][]byte
+}
+
+// NewDecrypter is used to create a Decrypter with an empty key cache
+func NewDecrypter() *Decrypter {
+	return &Decrypter{keys: map[string][]byte{}}
+}
+
+// Open decrypts an envelope and returns the plaintext
+func (decrypter *Decrypter) Open(envelope []byte) ([]byte, error) {
+	return decrypter.OpenWithAssociatedData(envelope, nil)
+}
+
+// OpenWithAssociatedData decrypts an envelope sealed with associatedData and returns the plaintext
+func (decrypter *Decrypter) OpenWithAssociatedData(envelope, associatedData []byte) ([]byte, error) {
+	plaintext := bytes.Buffer{}
+	_, err := decrypter.DecryptStreamWithAssociatedData(&plaintext, bytes.NewReader(envelope), associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return plaintext.Bytes(), nil
+}
+
+// DecryptStream reads an envelope from src and writes the plaintext to dst, returning the
+// number of plaintext bytes written. Chunks are authenticated before being written, so dst
+// never receives unauthenticated plaintext, but a failure leaves dst with a partial prefix.
+func (decrypter *Decrypter) DecryptStream(dst io.Writer, src io.Reader) (n int64, err error) {
+	return decrypter.DecryptStreamWithAssociatedData(dst, src, nil)
+}
+
+// DecryptStreamWithAssociatedData is DecryptStream for an envelope bound to associatedData.
+// A mismatched associated data fails authentication with ErrInvalidEnvelope.
+func (decrypter *Decrypter) DecryptStreamWithAssociatedData(dst io.Writer, src io.Reader,
+	associatedData []byte) (n int64, err error) {
+
+	reader := bufio.NewReader(src)
+	header, headerBytes, err := readEnvelopeHeader(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	dataKey, err := decrypter.unwrap(header)
+	if err != nil {
+		return 0, err
+	}
+	envelopeKey := header.envelopeKey(dataKey)
+	zero(dataKey)
+	defer zero(envelopeKey)
+
+	aead, err := newAEAD(envelopeKey)
+	if err != nil {
+		return 0, err
+	}
+
+	additionalData := append(headerBytes[:len(headerBytes):len(headerBytes)], associatedData...)
+	sealedSize := int(header.chunkSize) + aead.Overhead()
+	reader = bufio.NewReaderSize(reader, sealedSize+1)
+	sealed := make([]byte, sealedSize)
+	plaintext := make([]byte, 0, header.chunkSize)
+	for counter := uint64(0); ; counter++ {
+		if counter > maxEnvelopeChunks {
+			return n, ErrEnvelopeTooLarge
+		}
+
+		read, err := io.ReadFull(reader, sealed)
+		if err == io.EOF {
+			return n, ErrTruncatedEnvelope
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return n, err
+		}
+
+		last := err != nil
+		if !last {
+			_, peekErr := reader.Peek(1)
+			last = peekErr == io.EOF
+		}
+
+		plaintext, err = aead.Open(plaintext[:0], chunkNonce(header.noncePrefix, uint32(counter), last),
+			sealed[:read], additionalData)
+		if err != nil {
+			return n, ErrInvalidEnvelope
+		}
+
+		written, err := dst.Write(plaintext)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+
+		if last {
+			return n, nil
+		}
+	}
+}
+
+// unwrap returns a copy of the plaintext data key of an envelope, from the cache if possible.
+// The copy is owned by the caller, so that Purge cannot zero a key in use.
+func (decrypter *Decrypter) unwrap(header envelopeHeader) ([]byte, error) {
+
+	cacheKey := header.encryptionKeyIdentifier + "/" + string(header.wrappedKey)
+
+	decrypter.mutex.Lock()
+	dataKey, ok := decrypter.keys[cacheKey]
+	if ok {
+		dataKey = append([]byte(nil), dataKey...)
+	}
+	decrypter.mutex.Unlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	unwrapRequest := UnwrapDataKeyRequest{
+		WrappedDataKey:          header.wrappedKey,
+		EncryptionKeyIdentifier: header.encryptionKeyIdentifier,
+	}
+	dataKey, apiErr := unwrapRequest.UnwrapDataKey()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if len(dataKey) != dataKeySize {
+		return nil, ErrInvalidDataKey
+	}
+
+	decrypter.mutex.Lock()
+	if decrypter.keys == nil {
+		decrypter.keys = map[string][]byte{}
+	}
+	decrypter.keys[cacheKey] = append([]byte(nil), dataKey...)
+	decrypter.mutex.Unlock()
+
+	return dataKey, nil
+}
+
+// Purge zeroes and removes all cached data keys
+func (decrypter *Decrypter) Purge() {
+	decrypter.mutex.Lock()
+	defer decrypter.mutex.Unlock()
+
+	for cacheKey, dataKey := range decrypter.keys {
+		zero(dataKey)
+		delete(decrypter.keys, cacheKey)
+	}
+}
+
+// EnvelopeKeyIdentifier returns the key identifier of the platform key an envelope was sealed
+// under, without decrypting it
+func EnvelopeKeyIdentifier(envelope []byte) (string, error) {
+	header, _, err := readEnvelopeHeader(bufio.NewReader(bytes.NewReader(envelope)))
+	if err != nil {
+		return "", err
+	}
+	return header.encryptionKeyIdentifier, nil
+}
+
+// envelopeHeader is the unencrypted header of an envelope
+type envelopeHeader struct {
+	version                 byte
+	encryptionKeyIdentifier string
+	wrappedKey              []byte
+	chunkSize               uint32
+	keySalt                 []byte
+	noncePrefix             []byte
+}
+
+// marshal encodes the header
+func (header envelopeHeader) marshal() ([]byte, error) {
+
+	if len(header.encryptionKeyIdentifier) > 0xffff || len(header.wrappedKey) > 0xffff {
+		return nil, ErrInvalidEnvelope
+	}
+
+	buffer := bytes.Buffer{}
+	buffer.WriteString(envelopeMagic)
+	buffer.WriteByte(header.version)
+	binary.Write(&buffer, binary.BigEndian, uint16(len(header.encryptionKeyIdentifier)))
+	buffer.WriteString(header.encryptionKeyIdentifier)
+	binary.Write(&buffer, binary.BigEndian, uint16(len(header.wrappedKey)))
+	buffer.Write(header.wrappedKey)
+	binary.Write(&buffer, binary.BigEndian, header.chunkSize)
+	if header.version >= EnvelopeVersion2 {
+		buffer.Write(header.keySalt)
+	}
+	buffer.Write(header.noncePrefix)
+
+	return buffer.Bytes(), nil
+}
+
+// readEnvelopeHeader decodes a header and returns it along with its encoded bytes
+func readEnvelopeHeader(reader *bufio.Reader) (header envelopeHeader, headerBytes []byte, err error) {
+
+	raw := bytes.Buffer{}
+	read := func(size int) ([]byte, error) {
+		data := make([]byte, size)
+		_, err := io.ReadFull(reader, data)
+		if err != nil {
+			return nil, ErrInvalidEnvelope
+		}
+		raw.Write(data)
+		return data, nil
+	}
+	readLength := func() (int, error) {
+		data, err := read(2)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.BigEndian.Uint16(data)), nil
+	}
+
+	magic, err := read(len(envelopeMagic) + 1)
+	if err != nil {
+		return envelopeHeader{}, nil, err
+	}
+	if string(magic[:len(envelopeMagic)]) != envelopeMagic {
+		return envelopeHeader{}, nil, ErrInvalidEnvelope
+	}
+	header.version = magic[len(envelopeMagic)]
+	if header.version != EnvelopeVersion1 && header.version != EnvelopeVersion2 {
+		return envelopeHeader{}, nil, ErrUnsupportedEnvelope
+	}
+
+	length, err := readLength()
+	if err != nil {
+		return envelopeHeader{}, nil, err
+	}
+	keyIdentifier, err := read(length)
+	if err != nil {
+		return envelopeHeader{}, nil, err
+	}
+	header.encryptionKeyIdentifier = string(keyIdentifier)
+
+	length, err = readLength()
+	if err != nil {
+		return envelopeHeader{}, nil, err
+	}
+	header.wrappedKey, err = read(length)
+	if err != nil {
+		return envelopeHeader{}, nil, err
+	}
+
+	chunkSize, err := read(4)
+	if err != nil {
+		return envelopeHeader{}, nil, err
+	}
+	header.chunkSize = binary.BigEndian.Uint32(chunkSize)
+	if header.chunkSize == 0 || header.chunkSize > maxChunkSize {
+		return envelopeHeader{}, nil, ErrInvalidEnvelope
+	}
+
+	if header.version >= EnvelopeVersion2 {
+		header.keySalt, err = read(keySaltSize)
+		if err != nil {
+			return envelopeHeader{}, nil, err
+		}
+	}
+
+	header.noncePrefix, err = read(noncePrefixSize)
+	if err != nil {
+		return envelopeHeader{}, nil, err
+	}
+
+	return header, raw.Bytes(), nil
+}
+
+// envelopeKey returns a new slice holding the key sealing the chunks of the envelope. Version 1
+// envelopes use the data key, later versions a key derived from it and the key salt.
+func (header envelopeHeader) envelopeKey(dataKey []byte) []byte {
+	if header.version == EnvelopeVersion1 {
+		return append([]byte(nil), dataKey...)
+	}
+	return hkdfSHA256(dataKey, header.keySalt, []byte(envelopeKeyInfo))
+}
+
+// hkdfSHA256 derives a 32 byte key with HKDF-SHA256 (RFC 5869), a single expand block
+// being enough for the key size
+func hkdfSHA256(secret, salt, info []byte) []byte {
+
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	pseudoRandomKey := extract.Sum(nil)
+	defer zero(pseudoRandomKey)
+
+	expand := hmac.New(sha256.New, pseudoRandomKey)
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)
+}
+
+// zero overwrites key material
+func zero(key []byte) {
+	for i := range key {
+		key[i] = 0
+	}
+}
+
+// newAEAD creates the AES-256-GCM cipher for a data key
+func newAEAD(dataKey []byte) (cipher.AEAD, error) {
+	if len(dataKey) != dataKeySize {
+		return nil, ErrInvalidDataKey
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce builds the nonce of a chunk from the envelope nonce prefix
+func chunkNonce(noncePrefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, noncePrefix...)
+	nonce = append(nonce, byte(counter>>24), byte(counter>>16), byte(counter>>8), byte(counter))
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
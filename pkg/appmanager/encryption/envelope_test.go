@@ -0,0 +1,168 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+// testDataKey returns a data key whose wrapped form is the plaintext, as unwrapped by
+// startUnwrapServer
+func testDataKey() DataKey {
+	plaintext := bytes.Repeat([]byte{7}, dataKeySize)
+	return DataKey{
+		Plaintext:               plaintext,
+		Wrapped:                 append([]byte(nil), plaintext...),
+		EncryptionKeyIdentifier: "key-1",
+	}
+}
+
+func startUnwrapServer(t *testing.T) *platformtest.Server {
+	server := platformtest.Start(t)
+	server.Handle("EncryptionUnwrapDataKey", func(body json.RawMessage) interface{} {
+		request := UnwrapDataKeyRequest{}
+		json.Unmarshal(body, &request)
+		return UnwrapDataKeyResponse{APIResponse: platformtest.OK(), DataKey: request.WrappedDataKey}
+	})
+	return server
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+
+	startUnwrapServer(t)
+	encrypter, err := NewEncrypterWithDataKey(testDataKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypter.ChunkSize = 16
+	decrypter := NewDecrypter()
+
+	for _, size := range []int{0, 1, 15, 16, 17, 100} {
+		plaintext := bytes.Repeat([]byte("x"), size)
+
+		envelope, err := encrypter.SealWithAssociatedData(plaintext, []byte("deployment-1"))
+		if err != nil {
+			t.Fatalf("size %d: Seal: %v", size, err)
+		}
+
+		opened, err := decrypter.OpenWithAssociatedData(envelope, []byte("deployment-1"))
+		if err != nil {
+			t.Fatalf("size %d: Open: %v", size, err)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Fatalf("size %d: opened %q", size, opened)
+		}
+
+		_, err = decrypter.OpenWithAssociatedData(envelope, []byte("deployment-2"))
+		if !errors.Is(err, ErrInvalidEnvelope) {
+			t.Fatalf("size %d: Open with other associated data: %v, want ErrInvalidEnvelope", size, err)
+		}
+	}
+}
+
+func TestEnvelopesUseDistinctKeys(t *testing.T) {
+
+	encrypter, err := NewEncrypterWithDataKey(testDataKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := make([]envelopeHeader, 2)
+	for i := range headers {
+		envelope, err := encrypter.Seal([]byte("same plaintext"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers[i], _, err = readEnvelopeHeader(bufio.NewReader(bytes.NewReader(envelope)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if headers[i].version != EnvelopeVersion2 {
+			t.Fatalf("sealed version %d, want %d", headers[i].version, EnvelopeVersion2)
+		}
+	}
+
+	dataKey := testDataKey().Plaintext
+	first, second := headers[0].envelopeKey(dataKey), headers[1].envelopeKey(dataKey)
+	if bytes.Equal(first, second) || bytes.Equal(first, dataKey) {
+		t.Fatal("envelopes are sealed with the same key")
+	}
+}
+
+func TestOpenVersion1Envelope(t *testing.T) {
+
+	startUnwrapServer(t)
+	dataKey := testDataKey()
+
+	header := envelopeHeader{
+		version:                 EnvelopeVersion1,
+		encryptionKeyIdentifier: dataKey.EncryptionKeyIdentifier,
+		wrappedKey:              dataKey.Wrapped,
+		chunkSize:               DefaultChunkSize,
+		noncePrefix:             []byte{1, 2, 3, 4, 5, 6, 7},
+	}
+	headerBytes, err := header.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := newAEAD(dataKey.Plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope := aead.Seal(headerBytes, chunkNonce(header.noncePrefix, 0, true), []byte("legacy"), headerBytes)
+
+	opened, err := NewDecrypter().Open(envelope)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if string(opened) != "legacy" {
+		t.Fatalf("opened %q, want legacy", opened)
+	}
+}
+
+// destroyingReader destroys the encrypter after the first read
+type destroyingReader struct {
+	encrypter *Encrypter
+	reader    io.Reader
+}
+
+func (reader *destroyingReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.encrypter.Destroy()
+	return n, err
+}
+
+func TestDestroyDuringStream(t *testing.T) {
+
+	startUnwrapServer(t)
+	encrypter, err := NewEncrypterWithDataKey(testDataKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypter.ChunkSize = 4
+
+	plaintext := []byte("streamed across several chunks")
+	envelope := bytes.Buffer{}
+	_, err = encrypter.EncryptStream(&envelope, &destroyingReader{encrypter, bytes.NewReader(plaintext)})
+	if err != nil {
+		t.Fatalf("EncryptStream: %v", err)
+	}
+
+	opened, err := NewDecrypter().Open(envelope.Bytes())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatalf("opened %q, want %q", opened, plaintext)
+	}
+
+	_, err = encrypter.Seal(plaintext)
+	if !errors.Is(err, ErrDataKeyDestroyed) {
+		t.Fatalf("Seal after Destroy: %v, want ErrDataKeyDestroyed", err)
+	}
+}
@@ -0,0 +1,341 @@
+package encryption
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// DefaultReEncryptConcurrency is the number of targets migrated in parallel when
+// ReEncryptJob leaves it unset
+const DefaultReEncryptConcurrency = 4
+
+// Err* is used to throw errors during re-encryption
+var (
+	ErrMissingNewKey    = errors.New("new encryption key identifier is required")
+	ErrReEncryptFailed  = errors.New("one or more targets failed to re-encrypt")
+	ErrDuplicateTarget  = errors.New("duplicate re-encryption target ID")
+	ErrCheckpointFormat = errors.New("invalid checkpoint file")
+	ErrMissingStore     = errors.New("re-encryption target has no Store function")
+)
+
+// ReEncryptTarget is a single ciphertext or file to migrate to a new encryption key
+type ReEncryptTarget interface {
+	// ID uniquely identifies the target, it is used to record progress for resuming
+	ID() string
+	// ReEncrypt decrypts the target and encrypts it again under keys.NewEncryptionKeyIdentifier
+	ReEncrypt(ctx context.Context, keys *ReEncryptKeys) error
+}
+
+// Checkpoint records targets that have been migrated, so that an interrupted job can resume
+type Checkpoint interface {
+	// Done reports whether the target has already been migrated
+	Done(id string) bool
+	// MarkDone records the target as migrated
+	MarkDone(id string) error
+}
+
+// ReEncryptKeys gives targets access to the new key, and to an Encrypter and Decrypter shared
+// by all targets of a job so that data keys are generated and unwrapped once
+type ReEncryptKeys struct {
+	// Key identifier of the key to migrate to
+	NewEncryptionKeyIdentifier string
+	// Decrypter shared by all targets
+	Decrypter *Decrypter
+
+	once      sync.Once
+	encrypter *Encrypter
+	err       error
+}
+
+// Encrypter returns an Encrypter with a data key wrapped by the new key, created on first use
+func (keys *ReEncryptKeys) Encrypter() (*Encrypter, error) {
+	keys.once.Do(func() {
+		keys.encrypter, keys.err = NewEncrypter(keys.NewEncryptionKeyIdentifier)
+	})
+	return keys.encrypter, keys.err
+}
+
+// ReEncryptProgress is passed to progress callbacks after each target
+type ReEncryptProgress struct {
+	// Total number of targets
+	Total int
+	// Completed is the number of targets migrated by this run
+	Completed int
+	// Skipped is the number of targets already migrated by a previous run
+	Skipped int
+	// Failed is the number of targets that failed
+	Failed int
+	// ID of the target that was just processed
+	LastID string
+	// Err of the target that was just processed, nil on success
+	LastErr error
+}
+
+// ReEncryptReport is the outcome of a re-encryption job
+type ReEncryptReport struct {
+	ReEncryptProgress
+	// Errors by target ID
+	Errors map[string]error
+}
+
+// ReEncryptJob migrates a set of targets to a new encryption key
+type ReEncryptJob struct {
+	// Key identifier of the key to migrate to
+	NewEncryptionKeyIdentifier string
+	// Concurrency is the number of targets migrated in parallel, defaults to DefaultReEncryptConcurrency
+	Concurrency int
+	// Checkpoint records migrated targets. Omit to migrate every target on each run
+	Checkpoint Checkpoint
+	// Progress is called after each target is processed
+	Progress func(ReEncryptProgress)
+}
+
+// Run migrates every target not already recorded by the checkpoint. Failed targets are listed
+// in the report and cause ErrReEncryptFailed to be returned, running the job again retries them.
+func (job *ReEncryptJob) Run(ctx context.Context, targets []ReEncryptTarget) (report ReEncryptReport, err error) {
+
+	if job.NewEncryptionKeyIdentifier == "" {
+		return ReEncryptReport{}, ErrMissingNewKey
+	}
+
+	seen := map[string]bool{}
+	for _, target := range targets {
+		if seen[target.ID()] {
+			return ReEncryptReport{}, ErrDuplicateTarget
+		}
+		seen[target.ID()] = true
+	}
+
+	concurrency := job.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultReEncryptConcurrency
+	}
+
+	keys := &ReEncryptKeys{
+		NewEncryptionKeyIdentifier: job.NewEncryptionKeyIdentifier,
+		Decrypter:                  NewDecrypter(),
+	}
+	defer keys.Decrypter.Purge()
+
+	report.Total = len(targets)
+	report.Errors = map[string]error{}
+	mutex := sync.Mutex{}
+	record := func(id string, skipped bool, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch {
+		case skipped:
+			report.Skipped++
+		case err != nil:
+			report.Failed++
+			report.Errors[id] = err
+		default:
+			report.Completed++
+		}
+		report.LastID = id
+		report.LastErr = err
+
+		if job.Progress != nil {
+			job.Progress(report.ReEncryptProgress)
+		}
+	}
+
+	pending := make(chan ReEncryptTarget)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range pending {
+				err := target.ReEncrypt(ctx, keys)
+				if err == nil && job.Checkpoint != nil {
+					err = job.Checkpoint.MarkDone(target.ID())
+				}
+				record(target.ID(), false, err)
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		if job.Checkpoint != nil && job.Checkpoint.Done(target.ID()) {
+			record(target.ID(), true, nil)
+			continue
+		}
+		pending <- target
+	}
+	close(pending)
+	wg.Wait()
+
+	if keys.encrypter != nil {
+		keys.encrypter.Destroy()
+	}
+
+	if ctx.Err() != nil {
+		return report, ctx.Err()
+	}
+	if report.Failed != 0 {
+		return report, ErrReEncryptFailed
+	}
+
+	return report, nil
+}
+
+// ContentTarget is content encrypted with EncryptContent, migrated by decrypting and encrypting
+// it again through the platform
+type ContentTarget struct {
+	// TargetID uniquely identifies the content
+	TargetID string
+	// Encrypted content
+	Content string
+	// Key identifier the content is currently encrypted with
+	EncryptionKeyIdentifier string
+	// Store persists the re-encrypted content in place of the old content, required
+	Store func(ctx context.Context, encryptedContent, encryptionKeyIdentifier string) error
+}
+
+// ID returns the target ID
+func (target *ContentTarget) ID() string {
+	return target.TargetID
+}
+
+// ReEncrypt migrates the content to the new key and stores it
+func (target *ContentTarget) ReEncrypt(ctx context.Context, keys *ReEncryptKeys) error {
+
+	if target.Store == nil {
+		return ErrMissingStore
+	}
+
+	decryptRequest := DecryptContentRequest{
+		Content:                 target.Content,
+		EncryptionKeyIdentifier: target.EncryptionKeyIdentifier,
+	}
+	content, apiErr := decryptRequest.DecryptContent()
+	if apiErr != nil {
+		return apiErr
+	}
+
+	encryptRequest := EncryptContentRequest{
+		Content:                 content,
+		EncryptionKeyIdentifier: keys.NewEncryptionKeyIdentifier,
+	}
+	encryptedContent, encryptionKeyIdentifier, apiErr := encryptRequest.EncryptContent()
+	if apiErr != nil {
+		return apiErr
+	}
+
+	return target.Store(ctx, encryptedContent, encryptionKeyIdentifier)
+}
+
+// EnvelopeTarget is an envelope sealed by an Encrypter, migrated locally under a data key
+// wrapped by the new key
+type EnvelopeTarget struct {
+	// TargetID uniquely identifies the envelope
+	TargetID string
+	// Envelope to migrate
+	Envelope []byte
+	// Store persists the new envelope in place of the old envelope, required
+	Store func(ctx context.Context, envelope []byte) error
+}
+
+// ID returns the target ID
+func (target *EnvelopeTarget) ID() string {
+	return target.TargetID
+}
+
+// ReEncrypt migrates the envelope to the new key and stores it
+func (target *EnvelopeTarget) ReEncrypt(ctx context.Context, keys *ReEncryptKeys) error {
+
+	if target.Store == nil {
+		return ErrMissingStore
+	}
+
+	plaintext, err := keys.Decrypter.Open(target.Envelope)
+	if err != nil {
+		return err
+	}
+
+	encrypter, err := keys.Encrypter()
+	if err != nil {
+		return err
+	}
+
+	envelope, err := encrypter.Seal(plaintext)
+	if err != nil {
+		return err
+	}
+
+	return target.Store(ctx, envelope)
+}
+
+// FileCheckpoint is a Checkpoint persisted as a file with one quoted target ID per line
+type FileCheckpoint struct {
+	mutex sync.Mutex
+	file  *os.File
+	done  map[string]bool
+}
+
+// NewFileCheckpoint is used to open, or create, a checkpoint file and load the targets it records
+func NewFileCheckpoint(path string) (*FileCheckpoint, error) {
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &FileCheckpoint{file: file, done: map[string]bool{}}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		id, err := strconv.Unquote(scanner.Text())
+		if err != nil {
+			file.Close()
+			return nil, ErrCheckpointFormat
+		}
+		checkpoint.done[id] = true
+	}
+	if scanner.Err() != nil {
+		file.Close()
+		return nil, scanner.Err()
+	}
+
+	return checkpoint, nil
+}
+
+// Done reports whether the target is recorded in the checkpoint
+func (checkpoint *FileCheckpoint) Done(id string) bool {
+	checkpoint.mutex.Lock()
+	defer checkpoint.mutex.Unlock()
+	return checkpoint.done[id]
+}
+
+// MarkDone appends the target to the checkpoint file and syncs it to disk
+func (checkpoint *FileCheckpoint) MarkDone(id string) error {
+	checkpoint.mutex.Lock()
+	defer checkpoint.mutex.Unlock()
+
+	_, err := checkpoint.file.WriteString(strconv.Quote(id) + "\n")
+	if err != nil {
+		return err
+	}
+
+	err = checkpoint.file.Sync()
+	if err != nil {
+		return err
+	}
+
+	checkpoint.done[id] = true
+	return nil
+}
+
+// Close closes the checkpoint file
+func (checkpoint *FileCheckpoint) Close() error {
+	return checkpoint.file.Close()
+}
@@ -0,0 +1,127 @@
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+func TestReEncryptJob(t *testing.T) {
+
+	server := startUnwrapServer(t)
+	server.Handle("EncryptionGenerateDataKey", func(body json.RawMessage) interface{} {
+		request := GenerateDataKeyRequest{}
+		json.Unmarshal(body, &request)
+		dataKey := testDataKey()
+		dataKey.Plaintext[0] = 8
+		dataKey.Wrapped[0] = 8
+		return GenerateDataKeyResponse{
+			APIResponse:             platformtest.OK(),
+			DataKey:                 dataKey.Plaintext,
+			WrappedDataKey:          dataKey.Wrapped,
+			EncryptionKeyIdentifier: request.EncryptionKeyIdentifier,
+		}
+	})
+
+	encrypter, err := NewEncrypterWithDataKey(testDataKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := encrypter.Seal([]byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := map[string][]byte{}
+	store := func(id string) func(ctx context.Context, envelope []byte) error {
+		return func(ctx context.Context, envelope []byte) error {
+			stored[id] = envelope
+			return nil
+		}
+	}
+	targets := []ReEncryptTarget{
+		&EnvelopeTarget{TargetID: "stored", Envelope: envelope, Store: store("stored")},
+		&EnvelopeTarget{TargetID: "no-store", Envelope: envelope},
+		&ContentTarget{TargetID: "no-store-content", Content: "content"},
+	}
+
+	checkpoint, err := NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := ReEncryptJob{NewEncryptionKeyIdentifier: "key-2", Concurrency: 1, Checkpoint: checkpoint}
+
+	report, err := job.Run(context.Background(), targets)
+	if !errors.Is(err, ErrReEncryptFailed) {
+		t.Fatalf("Run error = %v, want ErrReEncryptFailed", err)
+	}
+	if report.Completed != 1 || report.Failed != 2 {
+		t.Fatalf("completed %d and failed %d, want 1 and 2", report.Completed, report.Failed)
+	}
+	for _, id := range []string{"no-store", "no-store-content"} {
+		if !errors.Is(report.Errors[id], ErrMissingStore) {
+			t.Errorf("target %s error = %v, want ErrMissingStore", id, report.Errors[id])
+		}
+	}
+
+	keyIdentifier, err := EnvelopeKeyIdentifier(stored["stored"])
+	if err != nil || keyIdentifier != "key-2" {
+		t.Fatalf("stored envelope key = %q, %v, want key-2", keyIdentifier, err)
+	}
+	plaintext, err := NewDecrypter().Open(stored["stored"])
+	if err != nil || string(plaintext) != "payload" {
+		t.Fatalf("stored envelope opened to %q, %v, want payload", plaintext, err)
+	}
+
+	report, _ = job.Run(context.Background(), targets)
+	if report.Skipped != 1 {
+		t.Fatalf("second run skipped %d targets, want the 1 checkpointed target", report.Skipped)
+	}
+}
+
+func TestContentTargetReEncrypt(t *testing.T) {
+
+	server := platformtest.Start(t)
+	server.Handle("EncryptionDecryptContent", func(body json.RawMessage) interface{} {
+		request := DecryptContentRequest{}
+		json.Unmarshal(body, &request)
+		prefix := "sealed(" + request.EncryptionKeyIdentifier + "):"
+		if !strings.HasPrefix(request.Content, prefix) {
+			return platformtest.Fail("400", "content is not encrypted with "+request.EncryptionKeyIdentifier)
+		}
+		return DecryptContentResponse{APIResponse: platformtest.OK(), Content: strings.TrimPrefix(request.Content, prefix)}
+	})
+	server.Handle("EncryptionEncryptContent", func(body json.RawMessage) interface{} {
+		request := EncryptContentRequest{}
+		json.Unmarshal(body, &request)
+		return EncryptContentResponse{
+			APIResponse:             platformtest.OK(),
+			EncryptedContent:        "sealed(" + request.EncryptionKeyIdentifier + "):" + request.Content,
+			EncryptionKeyIdentifier: request.EncryptionKeyIdentifier,
+		}
+	})
+
+	var stored, storedKey string
+	target := ContentTarget{
+		TargetID:                "content",
+		Content:                 "sealed(key-1):payload",
+		EncryptionKeyIdentifier: "key-1",
+		Store: func(ctx context.Context, encryptedContent, encryptionKeyIdentifier string) error {
+			stored, storedKey = encryptedContent, encryptionKeyIdentifier
+			return nil
+		},
+	}
+
+	err := target.ReEncrypt(context.Background(), &ReEncryptKeys{NewEncryptionKeyIdentifier: "key-2"})
+	if err != nil {
+		t.Fatalf("ReEncrypt: %v", err)
+	}
+	if stored != "sealed(key-2):payload" || storedKey != "key-2" {
+		t.Fatalf("stored %q under %q, want the payload re-encrypted under key-2", stored, storedKey)
+	}
+}
@@ -0,0 +1,184 @@
+package encryption
+
+import (
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// KeyState describes whether an encryption key can be used
+type KeyState string
+
+const (
+	// KeyEnabled keys can encrypt and decrypt
+	KeyEnabled KeyState = "Enabled"
+	// KeyRotated keys have been replaced by a newer key and can only decrypt
+	KeyRotated KeyState = "Rotated"
+	// KeyDisabled keys can neither encrypt nor decrypt until enabled again
+	KeyDisabled KeyState = "Disabled"
+	// KeyPendingDeletion keys are scheduled to be deleted
+	KeyPendingDeletion KeyState = "PendingDeletion"
+)
+
+// EncryptionKey is the metadata of a platform encryption key, the key material is never returned
+type EncryptionKey struct {
+	// Key identifier of the key
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+	// Algorithm of the key eg. `AES-256-GCM`
+	Algorithm string `json:"algorithm"`
+	// State of the key
+	State KeyState `json:"state"`
+	// Time at which the key was created
+	CreatedAt time.Time `json:"created_at"`
+	// Time at which the key was rotated, zero if it has not been rotated
+	RotatedAt time.Time `json:"rotated_at,omitempty"`
+	// Key identifier of the key that replaced this key on rotation
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// Interval at which the key is automatically rotated, zero if not scheduled
+	RotationInterval time.Duration `json:"rotation_interval,omitempty"`
+	// Time of the next scheduled rotation
+	NextRotationAt time.Time `json:"next_rotation_at,omitempty"`
+}
+
+// ListEncryptionKeysRequest is used to list encryption keys
+type ListEncryptionKeysRequest struct {
+	// Only list keys in this state. Omit to list keys in all states
+	State KeyState `json:"state,omitempty"`
+}
+
+// ListEncryptionKeysResponse is the list of encryption keys
+type ListEncryptionKeysResponse struct {
+	common.APIResponse
+	// List of encryption keys
+	Keys []EncryptionKey `json:"keys,omitempty"`
+}
+
+// DescribeEncryptionKeyRequest is used to get the metadata of an encryption key
+type DescribeEncryptionKeyRequest struct {
+	// Key identifier of the key
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+}
+
+// DescribeEncryptionKeyResponse is the metadata of the encryption key
+type DescribeEncryptionKeyResponse struct {
+	common.APIResponse
+	EncryptionKey
+}
+
+// RotateEncryptionKeyRequest is used to replace an encryption key with a new one. The old key
+// moves to the Rotated state and keeps decrypting existing ciphertexts.
+type RotateEncryptionKeyRequest struct {
+	// Key identifier of the key to rotate
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+}
+
+// RotateEncryptionKeyResponse is the new key created on rotation
+type RotateEncryptionKeyResponse struct {
+	common.APIResponse
+	EncryptionKey
+}
+
+// ScheduleRotationRequest is used to have the platform rotate an encryption key periodically
+type ScheduleRotationRequest struct {
+	// Key identifier of the key to rotate
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+	// Interval between rotations. Zero disables scheduled rotation
+	RotationInterval time.Duration `json:"rotation_interval"`
+}
+
+// ScheduleRotationResponse is the response to the schedule rotation request
+type ScheduleRotationResponse struct {
+	common.APIResponse
+}
+
+// List returns the metadata of all encryption keys
+func (listRequest *ListEncryptionKeysRequest) List() (keys []EncryptionKey, apiErr *common.Error) {
+
+	listResponse := ListEncryptionKeysResponse{}
+	err := common.Execute("EncryptionListKeys", listRequest, &listResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if listResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        listResponse.StatusCode,
+			ErrorDescription: listResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return listResponse.Keys, nil
+}
+
+// Describe returns the metadata of an encryption key
+func (describeRequest *DescribeEncryptionKeyRequest) Describe() (key EncryptionKey, apiErr *common.Error) {
+
+	describeResponse := DescribeEncryptionKeyResponse{}
+	err := common.Execute("EncryptionDescribeKey", describeRequest, &describeResponse)
+
+	if err != nil {
+		return EncryptionKey{}, err
+	}
+
+	if describeResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        describeResponse.StatusCode,
+			ErrorDescription: describeResponse.Description,
+		}
+
+		return EncryptionKey{}, apiErr
+	}
+
+	return describeResponse.EncryptionKey, nil
+}
+
+// Rotate replaces the encryption key and returns the metadata of the new key
+func (rotateRequest *RotateEncryptionKeyRequest) Rotate() (key EncryptionKey, apiErr *common.Error) {
+
+	rotateResponse := RotateEncryptionKeyResponse{}
+	err := common.Execute("EncryptionRotateKey", rotateRequest, &rotateResponse)
+
+	if err != nil {
+		return EncryptionKey{}, err
+	}
+
+	if rotateResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        rotateResponse.StatusCode,
+			ErrorDescription: rotateResponse.Description,
+		}
+
+		return EncryptionKey{}, apiErr
+	}
+
+	return rotateResponse.EncryptionKey, nil
+}
+
+// ScheduleRotation sets or clears the automatic rotation interval of an encryption key
+func (scheduleRequest *ScheduleRotationRequest) ScheduleRotation() (success bool, apiErr *common.Error) {
+
+	scheduleResponse := ScheduleRotationResponse{}
+	err := common.Execute("EncryptionScheduleRotation", scheduleRequest, &scheduleResponse)
+
+	if err != nil {
+		return false, err
+	}
+
+	if scheduleResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        scheduleResponse.StatusCode,
+			ErrorDescription: scheduleResponse.Description,
+		}
+
+		return false, apiErr
+	}
+
+	return true, nil
+}
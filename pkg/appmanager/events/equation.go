@@ -0,0 +1,286 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Err* is used to throw errors while parsing an equation
+var (
+	ErrUnexpectedToken  = errors.New("unexpected token")
+	ErrUnexpectedEnd    = errors.New("unexpected end of equation")
+	ErrUnbalanced       = errors.New("unbalanced parenthesis")
+	ErrUnknownCriterion = errors.New("unknown criterion")
+	ErrInvalidCharacter = errors.New("invalid character")
+)
+
+// EquationError points at the position of an equation that could not be parsed
+type EquationError struct {
+	// Equation is the text being parsed
+	Equation string
+	// Column is the 1-based byte position of the offending token
+	Column int
+	// Token is the offending token, empty at the end of the equation
+	Token string
+	// Err is the reason the equation is invalid
+	Err error
+}
+
+// Error describes the error and its position
+func (equationError *EquationError) Error() string {
+	if equationError.Token == "" {
+		return fmt.Sprintf("equation %q: column %d: %s", equationError.Equation, equationError.Column,
+			equationError.Err)
+	}
+	return fmt.Sprintf("equation %q: column %d: %s %q", equationError.Equation, equationError.Column,
+		equationError.Err, equationError.Token)
+}
+
+// Unwrap returns the reason the equation is invalid
+func (equationError *EquationError) Unwrap() error {
+	return equationError.Err
+}
+
+// tokenKind is the kind of a lexical token
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenName
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// token is a lexical token with its 0-based offset
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// node is a node of a parsed equation
+type node interface {
+	evaluate(results map[string]bool) bool
+}
+
+type nameNode string
+type notNode struct{ operand node }
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+
+func (n nameNode) evaluate(results map[string]bool) bool { return results[string(n)] }
+func (n notNode) evaluate(results map[string]bool) bool  { return !n.operand.evaluate(results) }
+func (n andNode) evaluate(results map[string]bool) bool {
+	return n.left.evaluate(results) && n.right.evaluate(results)
+}
+func (n orNode) evaluate(results map[string]bool) bool {
+	return n.left.evaluate(results) || n.right.evaluate(results)
+}
+
+// lex splits an equation into tokens. Names are letters, digits, `_`, `-` and `.`; operators are
+// AND, OR and NOT in any case, or `&&`, `||` and `!`
+func lex(equation string) ([]token, error) {
+
+	var tokens []token
+	runes := []rune(equation)
+	offsets := make([]int, 0, len(runes)+1)
+	for offset := range equation {
+		offsets = append(offsets, offset)
+	}
+	offsets = append(offsets, len(equation))
+
+	isName := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		offset := offsets[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenOpen, "(", offset})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenClose, ")", offset})
+			i++
+		case r == '!':
+			tokens = append(tokens, token{tokenNot, "!", offset})
+			i++
+		case (r == '&' || r == '|') && i+1 < len(runes) && runes[i+1] == r:
+			kind := tokenAnd
+			if r == '|' {
+				kind = tokenOr
+			}
+			tokens = append(tokens, token{kind, string([]rune{r, r}), offset})
+			i += 2
+		case isName(r):
+			start := i
+			for i < len(runes) && isName(runes[i]) {
+				i++
+			}
+			text := string(runes[start:i])
+			kind := tokenName
+			switch strings.ToUpper(text) {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, token{kind, text, offset})
+		default:
+			return nil, &EquationError{Equation: equation, Column: offset + 1, Token: string(r),
+				Err: ErrInvalidCharacter}
+		}
+	}
+
+	return append(tokens, token{tokenEnd, "", len(equation)}), nil
+}
+
+// parser is a recursive descent parser for the grammar
+//
+//	or      = and { OR and }
+//	and     = not { AND not }
+//	not     = NOT not | primary
+//	primary = name | "(" or ")"
+type parser struct {
+	equation string
+	tokens   []token
+	position int
+	names    map[string]bool
+}
+
+// parseEquation parses an equation whose names must all be in names
+func parseEquation(equation string, names map[string]bool) (node, error) {
+
+	tokens, err := lex(equation)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{equation: equation, tokens: tokens, names: names}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	next := p.peek()
+	if next.kind == tokenClose {
+		return nil, p.errorAt(next, ErrUnbalanced)
+	}
+	if next.kind != tokenEnd {
+		return nil, p.errorAt(next, ErrUnexpectedToken)
+	}
+
+	return root, nil
+}
+
+// peek returns the current token
+func (p *parser) peek() token {
+	return p.tokens[p.position]
+}
+
+// next returns the current token and advances
+func (p *parser) next() token {
+	t := p.tokens[p.position]
+	if t.kind != tokenEnd {
+		p.position++
+	}
+	return t
+}
+
+// errorAt builds an error pointing at a token
+func (p *parser) errorAt(t token, err error) error {
+	if t.kind == tokenEnd && err == ErrUnexpectedToken {
+		err = ErrUnexpectedEnd
+	}
+	return &EquationError{Equation: p.equation, Column: t.offset + 1, Token: t.text, Err: err}
+}
+
+func (p *parser) parseOr() (node, error) {
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+
+	if p.peek().kind == tokenNot {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+
+	t := p.next()
+	switch t.kind {
+	case tokenName:
+		if !p.names[t.text] {
+			return nil, p.errorAt(t, ErrUnknownCriterion)
+		}
+		return nameNode(t.text), nil
+	case tokenOpen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing := p.next()
+		if closing.kind != tokenClose {
+			if closing.kind == tokenEnd {
+				return nil, p.errorAt(t, ErrUnbalanced)
+			}
+			return nil, p.errorAt(closing, ErrUnexpectedToken)
+		}
+		return inner, nil
+	default:
+		return nil, p.errorAt(t, ErrUnexpectedToken)
+	}
+}
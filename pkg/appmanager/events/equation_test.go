@@ -0,0 +1,109 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseEquation(t *testing.T) {
+
+	names := map[string]bool{"a": true, "b": true, "c": true}
+	results := []map[string]bool{
+		{},
+		{"a": true},
+		{"b": true},
+		{"c": true},
+		{"a": true, "b": true},
+		{"a": true, "c": true},
+		{"b": true, "c": true},
+		{"a": true, "b": true, "c": true},
+	}
+
+	tests := []struct {
+		equation string
+		want     []bool
+	}{
+		{"a", []bool{false, true, false, false, true, true, false, true}},
+		{"a AND b", []bool{false, false, false, false, true, false, false, true}},
+		{"a OR b", []bool{false, true, true, false, true, true, true, true}},
+		{"NOT a", []bool{true, false, true, true, false, false, true, false}},
+		{"a and b or c", []bool{false, false, false, true, true, true, true, true}},
+		{"a AND (b OR c)", []bool{false, false, false, false, true, true, false, true}},
+		{"c OR a AND b", []bool{false, false, false, true, true, true, true, true}},
+		{"a || b && c", []bool{false, true, false, false, true, true, true, true}},
+		{"!a && b", []bool{false, false, true, false, false, false, true, false}},
+		{"NOT (a OR b)", []bool{true, false, false, true, false, false, false, false}},
+		{"not not a", []bool{false, true, false, false, true, true, false, true}},
+		{"NOT a AND NOT b", []bool{true, false, false, true, false, false, false, false}},
+		{"((a))OR(b)", []bool{false, true, true, false, true, true, true, true}},
+		{" \ta\nOr b ", []bool{false, true, true, false, true, true, true, true}},
+	}
+
+	for _, test := range tests {
+		root, err := parseEquation(test.equation, names)
+		if err != nil {
+			t.Errorf("parse %q: %v", test.equation, err)
+			continue
+		}
+		for i, result := range results {
+			if got := root.evaluate(result); got != test.want[i] {
+				t.Errorf("%q with %v = %v, want %v", test.equation, result, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestParseEquationNames(t *testing.T) {
+
+	names := map[string]bool{"cpu.high": true, "ram-high": true, "disk_full": true, "café": true}
+	root, err := parseEquation("cpu.high AND ram-high OR disk_full AND café", names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !root.evaluate(map[string]bool{"disk_full": true, "café": true}) {
+		t.Fatal("names with dots, dashes, underscores and letters not matched")
+	}
+}
+
+func TestParseEquationErrors(t *testing.T) {
+
+	names := map[string]bool{"a": true, "b": true}
+
+	tests := []struct {
+		equation string
+		err      error
+		column   int
+		token    string
+		message  string
+	}{
+		{"a AND", ErrUnexpectedEnd, 6, "", `equation "a AND": column 6: unexpected end of equation`},
+		{"NOT", ErrUnexpectedEnd, 4, "", `equation "NOT": column 4: unexpected end of equation`},
+		{"a b", ErrUnexpectedToken, 3, "b", `equation "a b": column 3: unexpected token "b"`},
+		{"a AND OR b", ErrUnexpectedToken, 7, "OR", `equation "a AND OR b": column 7: unexpected token "OR"`},
+		{"()", ErrUnexpectedToken, 2, ")", `equation "()": column 2: unexpected token ")"`},
+		{"(a b)", ErrUnexpectedToken, 4, "b", `equation "(a b)": column 4: unexpected token "b"`},
+		{"(a OR b", ErrUnbalanced, 1, "(", `equation "(a OR b": column 1: unbalanced parenthesis "("`},
+		{"a OR (b", ErrUnbalanced, 6, "(", `equation "a OR (b": column 6: unbalanced parenthesis "("`},
+		{"a OR b)", ErrUnbalanced, 7, ")", `equation "a OR b)": column 7: unbalanced parenthesis ")"`},
+		{"a AND c", ErrUnknownCriterion, 7, "c", `equation "a AND c": column 7: unknown criterion "c"`},
+		{"A", ErrUnknownCriterion, 1, "A", `equation "A": column 1: unknown criterion "A"`},
+		{"a # b", ErrInvalidCharacter, 3, "#", `equation "a # b": column 3: invalid character "#"`},
+		{"a & b", ErrInvalidCharacter, 3, "&", `equation "a & b": column 3: invalid character "&"`},
+		{"a |", ErrInvalidCharacter, 3, "|", `equation "a |": column 3: invalid character "|"`},
+		// columns count bytes
+		{"é & a", ErrInvalidCharacter, 4, "&", `equation "é & a": column 4: invalid character "&"`},
+	}
+
+	for _, test := range tests {
+		_, err := parseEquation(test.equation, names)
+		equationError := &EquationError{}
+		if !errors.As(err, &equationError) {
+			t.Errorf("parse %q error = %v, want an EquationError", test.equation, err)
+			continue
+		}
+		if !errors.Is(err, test.err) || equationError.Column != test.column || equationError.Token != test.token ||
+			err.Error() != test.message {
+			t.Errorf("parse %q error = %q, want %q", test.equation, err, test.message)
+		}
+	}
+}
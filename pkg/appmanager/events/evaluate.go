@@ -0,0 +1,243 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operands accepted in Criterion.Operand. String function names are case insensitive and may
+// be written with or without underscores, eg. `starts_with` or `startsWith`
+const (
+	OperandGreater        = ">"
+	OperandGreaterOrEqual = ">="
+	OperandLess           = "<"
+	OperandLessOrEqual    = "<="
+	OperandEqual          = "=="
+	OperandNotEqual       = "!="
+	OperandEquals         = "equals"
+	OperandNotEquals      = "not_equals"
+	OperandContains       = "contains"
+	OperandNotContains    = "not_contains"
+	OperandStartsWith     = "starts_with"
+	OperandEndsWith       = "ends_with"
+	OperandRegex          = "regex"
+	OperandNotRegex       = "not_regex"
+)
+
+// Err* is used to throw errors while compiling or evaluating criteria
+var (
+	ErrMissingCriterionName = errors.New("criterion name is required")
+	ErrDuplicateCriterion   = errors.New("duplicate criterion name")
+	ErrMissingField         = errors.New("criterion field is required")
+	ErrUnknownOperand       = errors.New("unknown operand")
+	ErrNotNumber            = errors.New("value is not a number")
+	ErrInvalidRegex         = errors.New("invalid regular expression")
+	ErrNoCriteria           = errors.New("at least one criterion is required")
+)
+
+// CriterionError points at the part of a criterion that is invalid
+type CriterionError struct {
+	// Name of the criterion
+	Name string
+	// Part is `name`, `field`, `operand` or `value`
+	Part string
+	// Err is the reason the criterion is invalid
+	Err error
+}
+
+// Error describes the invalid criterion
+func (criterionError *CriterionError) Error() string {
+	return fmt.Sprintf("criterion %q: %s: %s", criterionError.Name, criterionError.Part, criterionError.Err)
+}
+
+// Unwrap returns the reason the criterion is invalid
+func (criterionError *CriterionError) Unwrap() error {
+	return criterionError.Err
+}
+
+// compiledCriterion is a criterion ready for evaluation
+type compiledCriterion struct {
+	Criterion
+	test func(value string) (bool, error)
+}
+
+// Evaluator evaluates compiled event criteria against sample values
+type Evaluator struct {
+	criteria []compiledCriterion
+	equation node
+}
+
+// Result is the outcome of an evaluation
+type Result struct {
+	// Matched is the value of the equation
+	Matched bool
+	// Criteria is the value of each criterion by name
+	Criteria map[string]bool
+	// Missing lists criteria whose field had no sample value, they evaluate to false
+	Missing []string
+}
+
+// Validate checks the criteria and equation without evaluating them. New and Update leave
+// validation to the platform, call Validate first to report errors with their position
+func (eventCriteria *EventCriteria) Validate() error {
+	_, err := eventCriteria.Compile()
+	return err
+}
+
+// Compile parses the criteria and equation. An empty equation combines all criteria with AND.
+func (eventCriteria *EventCriteria) Compile() (*Evaluator, error) {
+
+	if len(eventCriteria.Criteria) == 0 {
+		return nil, ErrNoCriteria
+	}
+
+	evaluator := &Evaluator{}
+	names := map[string]bool{}
+	for _, criterion := range eventCriteria.Criteria {
+		if criterion.Name == "" {
+			return nil, &CriterionError{Part: "name", Err: ErrMissingCriterionName}
+		}
+		if names[criterion.Name] {
+			return nil, &CriterionError{Name: criterion.Name, Part: "name", Err: ErrDuplicateCriterion}
+		}
+		names[criterion.Name] = true
+
+		compiled, err := compileCriterion(criterion)
+		if err != nil {
+			return nil, err
+		}
+		evaluator.criteria = append(evaluator.criteria, compiled)
+	}
+
+	if strings.TrimSpace(eventCriteria.Equation) == "" {
+		var root node = nameNode(eventCriteria.Criteria[0].Name)
+		for _, criterion := range eventCriteria.Criteria[1:] {
+			root = andNode{root, nameNode(criterion.Name)}
+		}
+		evaluator.equation = root
+		return evaluator, nil
+	}
+
+	root, err := parseEquation(eventCriteria.Equation, names)
+	if err != nil {
+		return nil, err
+	}
+	evaluator.equation = root
+
+	return evaluator, nil
+}
+
+// Evaluate evaluates the criteria against sample values keyed by Criterion.Field, such as metric
+// values or the fields of a log line. Numeric operands return an error for non-numeric samples.
+func (evaluator *Evaluator) Evaluate(values map[string]string) (Result, error) {
+
+	result := Result{Criteria: map[string]bool{}}
+	for _, criterion := range evaluator.criteria {
+		value, ok := values[criterion.Field]
+		if !ok {
+			result.Missing = append(result.Missing, criterion.Name)
+			result.Criteria[criterion.Name] = false
+			continue
+		}
+
+		matched, err := criterion.test(value)
+		if err != nil {
+			return Result{}, fmt.Errorf("criterion %q: field %q: %w", criterion.Name, criterion.Field, err)
+		}
+		result.Criteria[criterion.Name] = matched
+	}
+	sort.Strings(result.Missing)
+
+	result.Matched = evaluator.equation.evaluate(result.Criteria)
+	return result, nil
+}
+
+// EvaluateMetrics evaluates the criteria against metric values keyed by metric name
+func (evaluator *Evaluator) EvaluateMetrics(metrics map[string]float64) (Result, error) {
+
+	values := make(map[string]string, len(metrics))
+	for name, value := range metrics {
+		values[name] = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+
+	return evaluator.Evaluate(values)
+}
+
+// compileCriterion builds the test function of a criterion
+func compileCriterion(criterion Criterion) (compiledCriterion, error) {
+
+	compiled := compiledCriterion{Criterion: criterion}
+	fail := func(part string, err error) (compiledCriterion, error) {
+		return compiledCriterion{}, &CriterionError{Name: criterion.Name, Part: part, Err: err}
+	}
+
+	if criterion.Field == "" {
+		return fail("field", ErrMissingField)
+	}
+
+	operand := strings.TrimSpace(criterion.Operand)
+	switch operand {
+	case OperandGreater, OperandGreaterOrEqual, OperandLess, OperandLessOrEqual, OperandEqual, OperandNotEqual:
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(criterion.Value), 64)
+		if err != nil {
+			return fail("value", fmt.Errorf("%w: %q", ErrNotNumber, criterion.Value))
+		}
+		compare := numericComparisons[operand]
+		compiled.test = func(value string) (bool, error) {
+			sample, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return false, fmt.Errorf("%w: %q", ErrNotNumber, value)
+			}
+			return compare(sample, threshold), nil
+		}
+		return compiled, nil
+	}
+
+	normalized := strings.ToLower(strings.Replace(operand, "_", "", -1))
+	expected := criterion.Value
+	switch normalized {
+	case "equals":
+		compiled.test = stringTest(func(value string) bool { return value == expected })
+	case "notequals":
+		compiled.test = stringTest(func(value string) bool { return value != expected })
+	case "contains":
+		compiled.test = stringTest(func(value string) bool { return strings.Contains(value, expected) })
+	case "notcontains":
+		compiled.test = stringTest(func(value string) bool { return !strings.Contains(value, expected) })
+	case "startswith":
+		compiled.test = stringTest(func(value string) bool { return strings.HasPrefix(value, expected) })
+	case "endswith":
+		compiled.test = stringTest(func(value string) bool { return strings.HasSuffix(value, expected) })
+	case "regex", "notregex":
+		pattern, err := regexp.Compile(expected)
+		if err != nil {
+			return fail("value", fmt.Errorf("%w: %s", ErrInvalidRegex, err))
+		}
+		negate := normalized == "notregex"
+		compiled.test = stringTest(func(value string) bool { return pattern.MatchString(value) != negate })
+	default:
+		return fail("operand", fmt.Errorf("%w: %q", ErrUnknownOperand, criterion.Operand))
+	}
+
+	return compiled, nil
+}
+
+// stringTest adapts a string predicate to a test function
+func stringTest(predicate func(value string) bool) func(string) (bool, error) {
+	return func(value string) (bool, error) {
+		return predicate(value), nil
+	}
+}
+
+var numericComparisons = map[string]func(sample, threshold float64) bool{
+	OperandGreater:        func(sample, threshold float64) bool { return sample > threshold },
+	OperandGreaterOrEqual: func(sample, threshold float64) bool { return sample >= threshold },
+	OperandLess:           func(sample, threshold float64) bool { return sample < threshold },
+	OperandLessOrEqual:    func(sample, threshold float64) bool { return sample <= threshold },
+	OperandEqual:          func(sample, threshold float64) bool { return sample == threshold },
+	OperandNotEqual:       func(sample, threshold float64) bool { return sample != threshold },
+}
@@ -0,0 +1,211 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+func TestCriterionOperands(t *testing.T) {
+
+	tests := []struct {
+		operand, value, sample string
+		want                   bool
+		err                    error
+	}{
+		{">", "80", "81", true, nil},
+		{">", "80", "80", false, nil},
+		{">=", "80", "80", true, nil},
+		{"<", "1.5", "1.25", true, nil},
+		{"<=", "-1", "-1", true, nil},
+		{"==", "1e3", "1000", true, nil},
+		{"!=", "0", "0", false, nil},
+		{" > ", " 80 ", " 90 ", true, nil},
+		{">", "80", "high", false, ErrNotNumber},
+		{"equals", "ok", "ok", true, nil},
+		{"equals", "ok", "OK", false, nil},
+		{"not_equals", "ok", "failed", true, nil},
+		{"contains", "err", "an error", true, nil},
+		{"NotContains", "err", "an error", false, nil},
+		{"starts_with", "GET ", "GET /index", true, nil},
+		{"startsWith", "GET ", "POST /index", false, nil},
+		{"ends_with", ".json", "a.json", true, nil},
+		{"ENDS_WITH", ".json", "a.yaml", false, nil},
+		{"regex", `^5\d\d$`, "503", true, nil},
+		{"regex", `^5\d\d$`, "200", false, nil},
+		{"not_regex", `^5\d\d$`, "200", true, nil},
+	}
+
+	for _, test := range tests {
+		criteria := EventCriteria{Criteria: []Criterion{
+			{Name: "criterion", Field: "field", Operand: test.operand, Value: test.value},
+		}}
+		evaluator, err := criteria.Compile()
+		if err != nil {
+			t.Errorf("%q %q: Compile: %v", test.operand, test.value, err)
+			continue
+		}
+		result, err := evaluator.Evaluate(map[string]string{"field": test.sample})
+		if !errors.Is(err, test.err) || result.Matched != test.want {
+			t.Errorf("%q %s %q = %v, %v, want %v, %v", test.sample, test.operand, test.value, result.Matched, err,
+				test.want, test.err)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+
+	valid := Criterion{Name: "a", Field: "cpu", Operand: ">", Value: "90"}
+	with := func(modify func(criterion *Criterion)) Criterion {
+		criterion := valid
+		modify(&criterion)
+		return criterion
+	}
+
+	tests := []struct {
+		name     string
+		criteria EventCriteria
+		err      error
+		message  string
+	}{
+		{"no criteria", EventCriteria{}, ErrNoCriteria, "at least one criterion is required"},
+		{"missing name", EventCriteria{Criteria: []Criterion{with(func(c *Criterion) { c.Name = "" })}},
+			ErrMissingCriterionName, `criterion "": name: criterion name is required`},
+		{"duplicate name", EventCriteria{Criteria: []Criterion{valid, valid}},
+			ErrDuplicateCriterion, `criterion "a": name: duplicate criterion name`},
+		{"missing field", EventCriteria{Criteria: []Criterion{with(func(c *Criterion) { c.Field = "" })}},
+			ErrMissingField, `criterion "a": field: criterion field is required`},
+		{"unknown operand", EventCriteria{Criteria: []Criterion{with(func(c *Criterion) { c.Operand = "~=" })}},
+			ErrUnknownOperand, `criterion "a": operand: unknown operand: "~="`},
+		{"threshold not a number", EventCriteria{Criteria: []Criterion{with(func(c *Criterion) { c.Value = "high" })}},
+			ErrNotNumber, `criterion "a": value: value is not a number: "high"`},
+		{"invalid regex", EventCriteria{Criteria: []Criterion{with(func(c *Criterion) {
+			c.Operand, c.Value = "regex", "5(0"
+		})}}, ErrInvalidRegex, `criterion "a": value: invalid regular expression: error parsing regexp: missing closing ): ` + "`5(0`"},
+		{"equation", EventCriteria{Criteria: []Criterion{valid}, Equation: "a OR b"},
+			ErrUnknownCriterion, `equation "a OR b": column 6: unknown criterion "b"`},
+	}
+
+	for _, test := range tests {
+		_, err := test.criteria.Compile()
+		if !errors.Is(err, test.err) || err.Error() != test.message {
+			t.Errorf("%s: Compile error = %q, want %q", test.name, err, test.message)
+		}
+		if validateErr := test.criteria.Validate(); validateErr == nil || validateErr.Error() != err.Error() {
+			t.Errorf("%s: Validate error = %v, want the Compile error", test.name, validateErr)
+		}
+	}
+}
+
+func TestEvaluatorEvaluate(t *testing.T) {
+
+	criteria := EventCriteria{
+		Criteria: []Criterion{
+			{Name: "cpu", Field: "cpu_percent", Operand: ">", Value: "90"},
+			{Name: "ram", Field: "ram_percent", Operand: ">", Value: "80"},
+			{Name: "errors", Field: "level", Operand: "equals", Value: "error"},
+		},
+		Equation: "(cpu OR ram) AND NOT errors",
+	}
+	evaluator, err := criteria.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		matched bool
+		results map[string]bool
+		missing []string
+	}{
+		{"cpu breach", map[string]string{"cpu_percent": "95", "ram_percent": "10", "level": "info"}, true,
+			map[string]bool{"cpu": true, "ram": false, "errors": false}, nil},
+		{"excluded by errors", map[string]string{"cpu_percent": "95", "ram_percent": "85", "level": "error"}, false,
+			map[string]bool{"cpu": true, "ram": true, "errors": true}, nil},
+		{"missing fields are false", map[string]string{"ram_percent": "85"}, true,
+			map[string]bool{"cpu": false, "ram": true, "errors": false}, []string{"cpu", "errors"}},
+		{"no values", map[string]string{}, false,
+			map[string]bool{"cpu": false, "ram": false, "errors": false}, []string{"cpu", "errors", "ram"}},
+	}
+
+	for _, test := range tests {
+		result, err := evaluator.Evaluate(test.values)
+		if err != nil {
+			t.Errorf("%s: Evaluate: %v", test.name, err)
+			continue
+		}
+		if result.Matched != test.matched || !reflect.DeepEqual(result.Criteria, test.results) ||
+			!reflect.DeepEqual(result.Missing, test.missing) {
+			t.Errorf("%s: Evaluate = %+v, want matched %v, %v, missing %v", test.name, result, test.matched,
+				test.results, test.missing)
+		}
+	}
+
+	_, err = evaluator.Evaluate(map[string]string{"cpu_percent": "n/a"})
+	if !errors.Is(err, ErrNotNumber) || err.Error() != `criterion "cpu": field "cpu_percent": value is not a number: "n/a"` {
+		t.Fatalf("Evaluate error = %v, want the criterion and field named", err)
+	}
+}
+
+func TestEvaluatorDefaultEquation(t *testing.T) {
+
+	criteria := EventCriteria{Criteria: []Criterion{
+		{Name: "cpu", Field: "cpu", Operand: ">=", Value: "90"},
+		{Name: "ram", Field: "ram", Operand: "<", Value: "0.5"},
+	}, Equation: "  "}
+	evaluator, err := criteria.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		metrics map[string]float64
+		want    bool
+	}{
+		{map[string]float64{"cpu": 90, "ram": 0.25}, true},
+		{map[string]float64{"cpu": 90, "ram": 0.5}, false},
+		{map[string]float64{"cpu": 89.99, "ram": 0.25}, false},
+		{map[string]float64{"cpu": 1e6, "ram": 1e-9}, true},
+	}
+
+	for _, test := range tests {
+		result, err := evaluator.EvaluateMetrics(test.metrics)
+		if err != nil || result.Matched != test.want {
+			t.Errorf("EvaluateMetrics(%v) = %v, %v, want every criterion ANDed: %v", test.metrics, result.Matched,
+				err, test.want)
+		}
+	}
+}
+
+func TestNewLeavesValidationToPlatform(t *testing.T) {
+
+	server := platformtest.Start(t)
+	server.Handle("EventsNew", func(json.RawMessage) interface{} {
+		return platformtest.Fail("400", "equation is invalid")
+	})
+	server.Handle("EventsUpdate", func(json.RawMessage) interface{} {
+		return UpdateResponse{APIResponse: platformtest.OK()}
+	})
+
+	spec := EventSpec{Name: "breach", EventCriteria: EventCriteria{
+		Criteria: []Criterion{{Name: "cpu", Field: "cpu", Operand: "above", Value: "90"}},
+	}}
+	newRequest := NewRequest{EventSpec: spec}
+	_, apiErr := newRequest.New()
+	if apiErr == nil || !strings.Contains(apiErr.ErrorDescription, "equation is invalid") {
+		t.Fatalf("New error = %v, want the platform error", apiErr)
+	}
+
+	updateRequest := UpdateRequest{EventID: "event", EventSpec: spec}
+	if apiErr := updateRequest.Update(); apiErr != nil {
+		t.Fatalf("Update error = %v, want the request sent to the platform", apiErr)
+	}
+	if len(server.Requests("EventsNew")) != 1 || len(server.Requests("EventsUpdate")) != 1 {
+		t.Fatal("requests not sent to the platform")
+	}
+}
@@ -0,0 +1,197 @@
+package objectstore
+
+import (
+	"strings"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// CopyRequest is used to copy an object within the object store without downloading it.
+// The encryption flag, content type, metadata and tags of the source are preserved.
+type CopyRequest struct {
+	// ID of the deployment owning the source object
+	SourceDeploymentID string `json:"source_deployment_id"`
+	// ID of the source object
+	SourceObjectID string `json:"source_object_id,omitempty"`
+	// Name of the source object including its path
+	SourceFullName string `json:"source_full_name,omitempty"`
+	// ID of the version of the source to copy. Omit to copy the latest version
+	SourceVersionID string `json:"source_version_id,omitempty"`
+	// ID of the deployment that will own the copy. Omit to copy within the source deployment
+	DestinationDeploymentID string `json:"destination_deployment_id,omitempty"`
+	// Name of the copy including its path
+	DestinationFullName string `json:"destination_full_name"`
+	// Overwrite when set to true, will add a new version to an existing destination object
+	Overwrite bool `json:"overwrite"`
+}
+
+// CopyResponse is the object created by the copy request
+type CopyResponse struct {
+	common.APIResponse
+	Object
+}
+
+// MoveRequest is used to move an object within the object store without downloading it. All
+// versions are moved and the encryption flag, content type, metadata and tags are preserved.
+type MoveRequest struct {
+	// ID of the deployment owning the source object
+	SourceDeploymentID string `json:"source_deployment_id"`
+	// ID of the source object
+	SourceObjectID string `json:"source_object_id,omitempty"`
+	// Name of the source object including its path
+	SourceFullName string `json:"source_full_name,omitempty"`
+	// ID of the deployment that will own the object. Omit to move within the source deployment
+	DestinationDeploymentID string `json:"destination_deployment_id,omitempty"`
+	// New name of the object including its path
+	DestinationFullName string `json:"destination_full_name"`
+	// Overwrite when set to true, will replace an existing destination object
+	Overwrite bool `json:"overwrite"`
+}
+
+// MoveResponse is the object after the move request
+type MoveResponse struct {
+	common.APIResponse
+	Object
+}
+
+// BulkRequest is used to copy or move all objects under SourcePrefix, matched on a path boundary
+// so that `staging` does not match `staging-old/a`. Each object keeps its name relative to
+// SourcePrefix under DestinationPrefix.
+type BulkRequest struct {
+	// ID of the deployment owning the source objects
+	SourceDeploymentID string
+	// Prefix of the full names of the objects to copy or move
+	SourcePrefix string
+	// ID of the deployment that will own the objects. Omit to stay within the source deployment
+	DestinationDeploymentID string
+	// Prefix replacing SourcePrefix in the destination full names
+	DestinationPrefix string
+	// Overwrite when set to true, will replace existing destination objects
+	Overwrite bool
+}
+
+// BulkResult is the outcome of copying or moving a single object in a bulk request
+type BulkResult struct {
+	// Name of the source object including its path
+	SourceFullName string
+	// Name of the destination object including its path
+	DestinationFullName string
+	// Object at the destination, set on success
+	Object Object
+	// Error returned for this object, nil on success
+	Err *common.Error
+}
+
+// Copy copies an object and returns the created copy
+func (copyRequest *CopyRequest) Copy() (object Object, apiErr *common.Error) {
+
+	copyResponse := CopyResponse{}
+	err := common.Execute("ObjectStoreCopy", copyRequest, &copyResponse)
+
+	if err != nil {
+		return Object{}, err
+	}
+
+	if copyResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        copyResponse.StatusCode,
+			ErrorDescription: copyResponse.Description,
+		}
+
+		return Object{}, apiErr
+	}
+
+	return copyResponse.Object, nil
+}
+
+// Move moves an object and returns it as stored at the destination
+func (moveRequest *MoveRequest) Move() (object Object, apiErr *common.Error) {
+
+	moveResponse := MoveResponse{}
+	err := common.Execute("ObjectStoreMove", moveRequest, &moveResponse)
+
+	if err != nil {
+		return Object{}, err
+	}
+
+	if moveResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        moveResponse.StatusCode,
+			ErrorDescription: moveResponse.Description,
+		}
+
+		return Object{}, apiErr
+	}
+
+	return moveResponse.Object, nil
+}
+
+// Copy copies every object under the source prefix and returns a result per object. The
+// returned error is only set if the source objects could not be listed.
+func (bulkRequest *BulkRequest) Copy() (results []BulkResult, apiErr *common.Error) {
+	return bulkRequest.each(func(object Object, destinationFullName string) (Object, *common.Error) {
+		copyRequest := CopyRequest{
+			SourceDeploymentID:      bulkRequest.SourceDeploymentID,
+			SourceObjectID:          object.ObjectID,
+			DestinationDeploymentID: bulkRequest.DestinationDeploymentID,
+			DestinationFullName:     destinationFullName,
+			Overwrite:               bulkRequest.Overwrite,
+		}
+		return copyRequest.Copy()
+	})
+}
+
+// Move moves every object under the source prefix and returns a result per object. The
+// returned error is only set if the source objects could not be listed.
+func (bulkRequest *BulkRequest) Move() (results []BulkResult, apiErr *common.Error) {
+	return bulkRequest.each(func(object Object, destinationFullName string) (Object, *common.Error) {
+		moveRequest := MoveRequest{
+			SourceDeploymentID:      bulkRequest.SourceDeploymentID,
+			SourceObjectID:          object.ObjectID,
+			DestinationDeploymentID: bulkRequest.DestinationDeploymentID,
+			DestinationFullName:     destinationFullName,
+			Overwrite:               bulkRequest.Overwrite,
+		}
+		return moveRequest.Move()
+	})
+}
+
+// each lists the source objects and applies fn to each of them
+func (bulkRequest *BulkRequest) each(
+	fn func(object Object, destinationFullName string) (Object, *common.Error)) (results []BulkResult, apiErr *common.Error) {
+
+	listRequest := ListRequest{
+		DeploymentID: bulkRequest.SourceDeploymentID,
+		Pattern:      bulkRequest.SourcePrefix,
+	}
+	objects, apiErr := listRequest.List()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	for _, object := range objects {
+		if !hasPathPrefix(object.FullName, bulkRequest.SourcePrefix) {
+			continue
+		}
+
+		result := BulkResult{
+			SourceFullName: object.FullName,
+			DestinationFullName: bulkRequest.DestinationPrefix +
+				strings.TrimPrefix(object.FullName, bulkRequest.SourcePrefix),
+		}
+		result.Object, result.Err = fn(object, result.DestinationFullName)
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// hasPathPrefix reports whether a full name is the prefix or lies under it, so that prefix
+// `staging` matches `staging/a` but not `staging-old/a`
+func hasPathPrefix(fullName, prefix string) bool {
+	directory := strings.TrimSuffix(prefix, "/")
+	return directory == "" || fullName == prefix || strings.HasPrefix(fullName, directory+"/")
+}
@@ -0,0 +1,71 @@
+package objectstore
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+func TestBulkMoveIgnoresSiblingPrefix(t *testing.T) {
+
+	server := platformtest.Start(t)
+	objects := []Object{
+		{ObjectID: "1", FullName: "staging"},
+		{ObjectID: "2", FullName: "staging/a.txt"},
+		{ObjectID: "3", FullName: "staging/nested/b.txt"},
+		{ObjectID: "4", FullName: "staging-old/c.txt"},
+		{ObjectID: "5", FullName: "staging.txt"},
+	}
+
+	server.Handle("ObjectStoreList", func(body json.RawMessage) interface{} {
+		request := ListRequest{}
+		json.Unmarshal(body, &request)
+
+		response := ListResponse{APIResponse: platformtest.OK()}
+		for _, object := range objects {
+			// like the platform, the pattern is a plain string prefix
+			if strings.HasPrefix(object.FullName, request.Pattern) {
+				response.Objects = append(response.Objects, object)
+			}
+		}
+		return response
+	})
+	server.Handle("ObjectStoreMove", func(body json.RawMessage) interface{} {
+		request := MoveRequest{}
+		json.Unmarshal(body, &request)
+		return MoveResponse{APIResponse: platformtest.OK(), Object: Object{FullName: request.DestinationFullName}}
+	})
+
+	tests := []struct {
+		sourcePrefix, destinationPrefix string
+		want                            []string
+	}{
+		{"staging", "archive", []string{"archive", "archive/a.txt", "archive/nested/b.txt"}},
+		{"staging/", "archive/", []string{"archive/a.txt", "archive/nested/b.txt"}},
+		{"staging/nested", "archive", []string{"archive/b.txt"}},
+	}
+
+	for _, test := range tests {
+		bulkRequest := BulkRequest{SourcePrefix: test.sourcePrefix, DestinationPrefix: test.destinationPrefix}
+		results, apiErr := bulkRequest.Move()
+		if apiErr != nil {
+			t.Fatalf("Move %q: %v", test.sourcePrefix, apiErr)
+		}
+
+		moved := []string{}
+		for _, result := range results {
+			if result.Err != nil {
+				t.Fatalf("Move %q: %s: %v", test.sourcePrefix, result.SourceFullName, result.Err)
+			}
+			moved = append(moved, result.DestinationFullName)
+		}
+		sort.Strings(moved)
+
+		if strings.Join(moved, ",") != strings.Join(test.want, ",") {
+			t.Errorf("Move %q to %q moved to %v, want %v", test.sourcePrefix, test.destinationPrefix, moved, test.want)
+		}
+	}
+}
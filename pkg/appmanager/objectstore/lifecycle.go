@@ -0,0 +1,322 @@
+package objectstore
+
+import (
+	"sort"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// LifecycleAction is an action taken by a lifecycle rule
+type LifecycleAction string
+
+const (
+	// ExpireObject deletes the latest version of an object that is older than the expiry
+	ExpireObject LifecycleAction = "ExpireObject"
+	// ExpireVersion deletes a previous version of an object beyond the versions to keep
+	ExpireVersion LifecycleAction = "ExpireVersion"
+	// AbortUpload aborts an incomplete multipart upload older than the limit
+	AbortUpload LifecycleAction = "AbortUpload"
+)
+
+// day is the length of a day used to compute ages for lifecycle rules
+const day = 24 * time.Hour
+
+// LifecycleRuleSpec is the specification of a lifecycle rule applied to objects of a deployment
+// under the Prefix path
+type LifecycleRuleSpec struct {
+	// ID of the deployment whose objects the rule applies to
+	DeploymentID string `json:"deployment_id"`
+	// Prefix is the path of the objects the rule applies to, matched on a path boundary so that
+	// `logs` covers `logs/a` but not `logs-archive/a`. Omit to apply to all objects
+	Prefix string `json:"prefix,omitempty"`
+	// Enabled whether the platform applies the rule or not
+	Enabled bool `json:"enabled"`
+	// ExpireAfterDays deletes objects whose latest version is older than the given days
+	ExpireAfterDays int `json:"expire_after_days,omitempty"`
+	// KeepLastVersions deletes versions of an object except the given number of newest versions
+	KeepLastVersions int `json:"keep_last_versions,omitempty"`
+	// AbortIncompleteUploadsAfterDays aborts multipart uploads initiated more than the given days ago
+	AbortIncompleteUploadsAfterDays int `json:"abort_incomplete_uploads_after_days,omitempty"`
+}
+
+// LifecycleRule is a lifecycle rule with an ID
+type LifecycleRule struct {
+	// Unique ID of the lifecycle rule
+	RuleID string `json:"rule_id"`
+	LifecycleRuleSpec
+}
+
+// LifecycleResult is an action a lifecycle rule takes on an object version or upload
+type LifecycleResult struct {
+	// Action taken
+	Action LifecycleAction
+	// ID of the rule taking the action
+	RuleID string
+	// Object version the action applies to, for ExpireObject and ExpireVersion
+	Object Object
+	// Upload the action applies to, for AbortUpload
+	Upload MultipartUpload
+}
+
+// CreateLifecycleRuleRequest is used to create a lifecycle rule
+type CreateLifecycleRuleRequest struct {
+	LifecycleRuleSpec
+}
+
+// CreateLifecycleRuleResponse is the response to the create request
+type CreateLifecycleRuleResponse struct {
+	common.APIResponse
+	// Unique ID of the lifecycle rule
+	RuleID string `json:"rule_id"`
+}
+
+// ListLifecycleRulesRequest is used to list lifecycle rules of a deployment
+type ListLifecycleRulesRequest struct {
+	DeploymentID string `json:"deployment_id"`
+}
+
+// ListLifecycleRulesResponse is the list of lifecycle rules
+type ListLifecycleRulesResponse struct {
+	common.APIResponse
+	// List of lifecycle rules
+	Rules []LifecycleRule `json:"rules,omitempty"`
+}
+
+// UpdateLifecycleRuleRequest is used to update all fields of a lifecycle rule
+type UpdateLifecycleRuleRequest struct {
+	// Unique ID of the lifecycle rule to update
+	RuleID string `json:"rule_id"`
+	LifecycleRuleSpec
+}
+
+// UpdateLifecycleRuleResponse is the response to the update request
+type UpdateLifecycleRuleResponse struct {
+	common.APIResponse
+}
+
+// DeleteLifecycleRuleRequest is used to delete a lifecycle rule
+type DeleteLifecycleRuleRequest struct {
+	// Unique ID of the lifecycle rule to delete
+	RuleID string `json:"rule_id"`
+}
+
+// DeleteLifecycleRuleResponse is the response to the delete request
+type DeleteLifecycleRuleResponse struct {
+	common.APIResponse
+}
+
+// Create is used to create a new lifecycle rule
+func (createRequest *CreateLifecycleRuleRequest) Create() (ruleID string, apiErr *common.Error) {
+
+	createResponse := CreateLifecycleRuleResponse{}
+	err := common.Execute("ObjectStoreLifecycleCreate", createRequest, &createResponse)
+
+	if err != nil {
+		return "", err
+	}
+
+	if createResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        createResponse.StatusCode,
+			ErrorDescription: createResponse.Description,
+		}
+
+		return "", apiErr
+	}
+
+	return createResponse.RuleID, nil
+}
+
+// List is used to list lifecycle rules of a deployment
+func (listRequest *ListLifecycleRulesRequest) List() (rules []LifecycleRule, apiErr *common.Error) {
+
+	listResponse := ListLifecycleRulesResponse{}
+	err := common.Execute("ObjectStoreLifecycleList", listRequest, &listResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if listResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        listResponse.StatusCode,
+			ErrorDescription: listResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return listResponse.Rules, nil
+}
+
+// Update is used to update all fields of an existing lifecycle rule
+func (updateRequest *UpdateLifecycleRuleRequest) Update() (success bool, apiErr *common.Error) {
+
+	updateResponse := UpdateLifecycleRuleResponse{}
+	err := common.Execute("ObjectStoreLifecycleUpdate", updateRequest, &updateResponse)
+
+	if err != nil {
+		return false, err
+	}
+
+	if updateResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        updateResponse.StatusCode,
+			ErrorDescription: updateResponse.Description,
+		}
+
+		return false, apiErr
+	}
+
+	return true, nil
+}
+
+// Delete is used to delete an existing lifecycle rule
+func (deleteRequest *DeleteLifecycleRuleRequest) Delete() (success bool, apiErr *common.Error) {
+
+	deleteResponse := DeleteLifecycleRuleResponse{}
+	err := common.Execute("ObjectStoreLifecycleDelete", deleteRequest, &deleteResponse)
+
+	if err != nil {
+		return false, err
+	}
+
+	if deleteResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        deleteResponse.StatusCode,
+			ErrorDescription: deleteResponse.Description,
+		}
+
+		return false, apiErr
+	}
+
+	return true, nil
+}
+
+// Evaluate computes the actions the rule would take at time now, given all versions of the
+// objects of the deployment and its incomplete multipart uploads. Objects and uploads outside
+// the prefix of the rule, and soft deleted objects, are ignored. Evaluate does not change anything.
+func (rule *LifecycleRule) Evaluate(now time.Time, versions []Object, uploads []MultipartUpload) []LifecycleResult {
+
+	results := []LifecycleResult{}
+
+	byName := map[string][]Object{}
+	names := []string{}
+	for _, version := range versions {
+		if !hasPathPrefix(version.FullName, rule.Prefix) || !version.DeletedAt.IsZero() {
+			continue
+		}
+		if _, ok := byName[version.FullName]; !ok {
+			names = append(names, version.FullName)
+		}
+		byName[version.FullName] = append(byName[version.FullName], version)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		objectVersions := byName[name]
+		sort.SliceStable(objectVersions, func(i, j int) bool {
+			if objectVersions[i].IsLatest != objectVersions[j].IsLatest {
+				return objectVersions[i].IsLatest
+			}
+			return lastModified(objectVersions[i]).After(lastModified(objectVersions[j]))
+		})
+
+		latest := objectVersions[0]
+		if rule.ExpireAfterDays > 0 &&
+			now.Sub(lastModified(latest)) >= time.Duration(rule.ExpireAfterDays)*day {
+			results = append(results, LifecycleResult{
+				Action: ExpireObject,
+				RuleID: rule.RuleID,
+				Object: latest,
+			})
+		}
+
+		if rule.KeepLastVersions > 0 {
+			for i := rule.KeepLastVersions; i < len(objectVersions); i++ {
+				results = append(results, LifecycleResult{
+					Action: ExpireVersion,
+					RuleID: rule.RuleID,
+					Object: objectVersions[i],
+				})
+			}
+		}
+	}
+
+	if rule.AbortIncompleteUploadsAfterDays > 0 {
+		for _, upload := range uploads {
+			if !hasPathPrefix(upload.FullName, rule.Prefix) {
+				continue
+			}
+			if now.Sub(upload.InitiatedAt) >= time.Duration(rule.AbortIncompleteUploadsAfterDays)*day {
+				results = append(results, LifecycleResult{
+					Action: AbortUpload,
+					RuleID: rule.RuleID,
+					Upload: upload,
+				})
+			}
+		}
+	}
+
+	return results
+}
+
+// DryRun fetches the objects, versions and incomplete uploads covered by the rule from the
+// platform and returns the actions the rule would take now, without applying them
+func (rule *LifecycleRule) DryRun() (results []LifecycleResult, apiErr *common.Error) {
+
+	listRequest := ListRequest{
+		DeploymentID: rule.DeploymentID,
+		Pattern:      rule.Prefix,
+	}
+	objects, apiErr := listRequest.List()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	versions := objects
+	if rule.KeepLastVersions > 0 {
+		versions = []Object{}
+		for _, object := range objects {
+			if !hasPathPrefix(object.FullName, rule.Prefix) {
+				continue
+			}
+			listVersionsRequest := ListVersionsRequest{
+				DeploymentID: rule.DeploymentID,
+				ObjectID:     object.ObjectID,
+			}
+			objectVersions, apiErr := listVersionsRequest.ListVersions()
+			if apiErr != nil {
+				return nil, apiErr
+			}
+			versions = append(versions, objectVersions...)
+		}
+	}
+
+	uploads := []MultipartUpload{}
+	if rule.AbortIncompleteUploadsAfterDays > 0 {
+		listUploadsRequest := ListMultipartUploadsRequest{
+			DeploymentID: rule.DeploymentID,
+			Pattern:      rule.Prefix,
+		}
+		uploads, apiErr = listUploadsRequest.ListUploads()
+		if apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
+	return rule.Evaluate(time.Now(), versions, uploads), nil
+}
+
+// lastModified is the time at which an object version was last changed
+func lastModified(object Object) time.Time {
+	if object.ModifiedAt.After(object.CreatedAt) {
+		return object.ModifiedAt
+	}
+	return object.CreatedAt
+}
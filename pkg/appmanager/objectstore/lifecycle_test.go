@@ -0,0 +1,134 @@
+package objectstore
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+var lifecycleNow = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// version returns an object version created the given days before lifecycleNow
+func version(fullName, versionID string, ageDays int, latest bool) Object {
+	return Object{
+		ObjectID:  "id-" + fullName,
+		FullName:  fullName,
+		VersionID: versionID,
+		IsLatest:  latest,
+		CreatedAt: lifecycleNow.Add(-time.Duration(ageDays) * day),
+	}
+}
+
+// describeResults summarises results as action:name/version
+func describeResults(results []LifecycleResult) []string {
+	described := []string{}
+	for _, result := range results {
+		if result.Action == AbortUpload {
+			described = append(described, string(result.Action)+":"+result.Upload.FullName)
+		} else {
+			described = append(described, string(result.Action)+":"+result.Object.FullName+"/"+result.Object.VersionID)
+		}
+	}
+	return described
+}
+
+func TestLifecycleRuleEvaluate(t *testing.T) {
+
+	deleted := version("logs/deleted.log", "v1", 100, true)
+	deleted.DeletedAt = lifecycleNow.Add(-day)
+	modified := version("logs/modified.log", "v1", 100, true)
+	modified.ModifiedAt = lifecycleNow.Add(-day)
+
+	versions := []Object{
+		version("logs/old.log", "v1", 40, true),
+		version("logs/new.log", "v1", 10, true),
+		version("logs/exact.log", "v1", 30, true),
+		version("logs", "v1", 40, true),
+		version("logs/nested/old.log", "v1", 40, true),
+		version("logs-archive/old.log", "v1", 40, true),
+		version("logs.txt", "v1", 40, true),
+		deleted,
+		modified,
+		version("logs/versioned.log", "v1", 3, false),
+		version("logs/versioned.log", "v3", 1, true),
+		version("logs/versioned.log", "v2", 2, false),
+	}
+	uploads := []MultipartUpload{
+		{UploadID: "u1", FullName: "logs/stale.log", InitiatedAt: lifecycleNow.Add(-3 * day)},
+		{UploadID: "u2", FullName: "logs/recent.log", InitiatedAt: lifecycleNow.Add(-time.Hour)},
+		{UploadID: "u3", FullName: "logs-archive/stale.log", InitiatedAt: lifecycleNow.Add(-3 * day)},
+	}
+
+	tests := []struct {
+		name string
+		spec LifecycleRuleSpec
+		want []string
+	}{
+		{"expire", LifecycleRuleSpec{Prefix: "logs", ExpireAfterDays: 30}, []string{
+			"ExpireObject:logs/v1", "ExpireObject:logs/exact.log/v1", "ExpireObject:logs/nested/old.log/v1",
+			"ExpireObject:logs/old.log/v1",
+		}},
+		{"expire with trailing slash", LifecycleRuleSpec{Prefix: "logs/", ExpireAfterDays: 30}, []string{
+			"ExpireObject:logs/exact.log/v1", "ExpireObject:logs/nested/old.log/v1", "ExpireObject:logs/old.log/v1",
+		}},
+		{"expire nested", LifecycleRuleSpec{Prefix: "logs/nested", ExpireAfterDays: 1}, []string{
+			"ExpireObject:logs/nested/old.log/v1",
+		}},
+		{"keep versions", LifecycleRuleSpec{Prefix: "logs", KeepLastVersions: 1}, []string{
+			"ExpireVersion:logs/versioned.log/v2", "ExpireVersion:logs/versioned.log/v1",
+		}},
+		{"keep more versions than exist", LifecycleRuleSpec{Prefix: "logs", KeepLastVersions: 3}, []string{}},
+		{"abort uploads", LifecycleRuleSpec{Prefix: "logs", AbortIncompleteUploadsAfterDays: 2}, []string{
+			"AbortUpload:logs/stale.log",
+		}},
+		{"all objects", LifecycleRuleSpec{AbortIncompleteUploadsAfterDays: 2}, []string{
+			"AbortUpload:logs/stale.log", "AbortUpload:logs-archive/stale.log",
+		}},
+		{"no actions", LifecycleRuleSpec{Prefix: "logs"}, []string{}},
+	}
+
+	for _, test := range tests {
+		rule := LifecycleRule{RuleID: "rule", LifecycleRuleSpec: test.spec}
+		got := describeResults(rule.Evaluate(lifecycleNow, versions, uploads))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Evaluate = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLifecycleRuleDryRun(t *testing.T) {
+
+	server := platformtest.Start(t)
+	server.Handle("ObjectStoreList", func(json.RawMessage) interface{} {
+		// like the platform, the pattern is a plain string prefix
+		return ListResponse{APIResponse: platformtest.OK(), Objects: []Object{
+			version("logs/a.log", "v2", 1, true),
+			version("logs-archive/b.log", "v2", 1, true),
+		}}
+	})
+	server.Handle("ObjectStoreListVersions", func(body json.RawMessage) interface{} {
+		request := ListVersionsRequest{}
+		json.Unmarshal(body, &request)
+		fullName := request.ObjectID[len("id-"):]
+		return ListVersionsResponse{APIResponse: platformtest.OK(), Versions: []Object{
+			version(fullName, "v2", 1, true),
+			version(fullName, "v1", 2, false),
+		}}
+	})
+
+	rule := LifecycleRule{RuleID: "rule", LifecycleRuleSpec: LifecycleRuleSpec{Prefix: "logs", KeepLastVersions: 1}}
+	results, apiErr := rule.DryRun()
+	if apiErr != nil {
+		t.Fatalf("DryRun: %v", apiErr)
+	}
+	got := describeResults(results)
+	if !reflect.DeepEqual(got, []string{"ExpireVersion:logs/a.log/v1"}) {
+		t.Fatalf("DryRun = %v, want only the old version of logs/a.log expired", got)
+	}
+	if requests := server.Requests("ObjectStoreListVersions"); len(requests) != 1 {
+		t.Fatalf("listed versions of %d objects, want only the object under the prefix", len(requests))
+	}
+}
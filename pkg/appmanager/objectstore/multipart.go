@@ -0,0 +1,686 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// Default transfer settings used when UploadRequest or DownloadRequest leave them unset
+const (
+	// DefaultPartSize is the size of each part of a multipart upload
+	DefaultPartSize int64 = 8 << 20
+	// MinPartSize is the smallest allowed part size, only the last part may be smaller
+	MinPartSize int64 = 1 << 20
+	// DefaultConcurrency is the number of parts uploaded in parallel
+	DefaultConcurrency = 4
+	// DefaultChunkSize is the size of each byte range fetched during a download
+	DefaultChunkSize int64 = 4 << 20
+)
+
+// Err* is used to throw errors during object transfers
+var (
+	ErrPartSizeTooSmall     = errors.New("part size is smaller than the minimum part size")
+	ErrPartChecksumMismatch = errors.New("part checksum reported by platform does not match uploaded data")
+	ErrInvalidRange         = errors.New("invalid byte range")
+)
+
+// Part is a single uploaded part of a multipart upload
+type Part struct {
+	// Number of the part, starting at 1
+	PartNumber int `json:"part_number"`
+	// Size of the part in bytes
+	Size int64 `json:"size"`
+	// Hex encoded SHA-256 checksum of the part
+	Checksum string `json:"checksum"`
+}
+
+// Progress describes the state of an upload or download and is passed to progress callbacks
+type Progress struct {
+	// UploadID of the multipart upload, empty for downloads
+	UploadID string
+	// BytesTransferred is the number of bytes sent or received so far
+	BytesTransferred int64
+	// TotalBytes is the total number of bytes to transfer, -1 if not yet known
+	TotalBytes int64
+	// PartsCompleted is the number of parts uploaded
+	PartsCompleted int
+	// PartsSkipped is the number of parts that were already uploaded before a resume
+	PartsSkipped int
+}
+
+// InitiateMultipartRequest is used to start a new multipart upload
+type InitiateMultipartRequest struct {
+	// ID of the deployment that will own the object
+	DeploymentID string `json:"deployment_id"`
+	// Name of the object including its path
+	FullName string `json:"full_name"`
+	// Encrypted whether object will be stored with inbuilt encryption or not
+	Encrypted bool `json:"encrypted"`
+	// MIME type of the object, detected from the content if omitted
+	ContentType string `json:"content_type,omitempty"`
+	// User defined metadata of the object
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// User defined tags of the object
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// InitiateMultipartResponse is the response to the initiate request
+type InitiateMultipartResponse struct {
+	common.APIResponse
+	// ID of the multipart upload
+	UploadID string `json:"upload_id"`
+}
+
+// UploadPartRequest is used to upload a single part of a multipart upload
+type UploadPartRequest struct {
+	// ID of the multipart upload
+	UploadID string `json:"upload_id"`
+	// Number of the part, starting at 1
+	PartNumber int `json:"part_number"`
+	// Hex encoded SHA-256 checksum of Data
+	Checksum string `json:"checksum"`
+	// Content of the part
+	Data []byte `json:"data"`
+}
+
+// UploadPartResponse is the response to the upload part request
+type UploadPartResponse struct {
+	common.APIResponse
+	Part
+}
+
+// ListPartsRequest is used to list parts already uploaded for a multipart upload
+type ListPartsRequest struct {
+	// ID of the multipart upload
+	UploadID string `json:"upload_id"`
+}
+
+// ListPartsResponse is the response to the list parts request
+type ListPartsResponse struct {
+	common.APIResponse
+	// List of uploaded parts
+	Parts []Part `json:"parts,omitempty"`
+}
+
+// CompleteMultipartRequest is used to assemble uploaded parts into an object
+type CompleteMultipartRequest struct {
+	// ID of the multipart upload
+	UploadID string `json:"upload_id"`
+	// Parts making up the object, in order
+	Parts []Part `json:"parts"`
+	// Hex encoded SHA-256 hash of the whole object, verified by the platform if set
+	ContentHash string `json:"content_hash,omitempty"`
+}
+
+// CompleteMultipartResponse is the response to the complete request
+type CompleteMultipartResponse struct {
+	common.APIResponse
+	// ID of the object
+	ObjectID string `json:"object_id"`
+}
+
+// MultipartUpload is a multipart upload that has been initiated but not yet completed or aborted
+type MultipartUpload struct {
+	// ID of the multipart upload
+	UploadID string `json:"upload_id"`
+	// ID of the deployment that will own the object
+	DeploymentID string `json:"deployment_id"`
+	// Name of the object including its path
+	FullName string `json:"full_name"`
+	// Time at which the upload was initiated
+	InitiatedAt time.Time `json:"initiated_at"`
+}
+
+// ListMultipartUploadsRequest is used to list incomplete multipart uploads of a deployment
+type ListMultipartUploadsRequest struct {
+	DeploymentID string `json:"deployment_id"`
+	// Find uploads where path or name begins with or is equal to
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// ListMultipartUploadsResponse is the list of incomplete multipart uploads
+type ListMultipartUploadsResponse struct {
+	common.APIResponse
+	// List of incomplete uploads
+	Uploads []MultipartUpload `json:"uploads,omitempty"`
+}
+
+// AbortMultipartRequest is used to abandon a multipart upload and discard its parts
+type AbortMultipartRequest struct {
+	// ID of the multipart upload
+	UploadID string `json:"upload_id"`
+}
+
+// AbortMultipartResponse is the response to the abort request
+type AbortMultipartResponse struct {
+	common.APIResponse
+}
+
+// ReadRangeRequest is used to read a byte range of an object
+type ReadRangeRequest struct {
+	DeploymentID string `json:"deployment_id"`
+	// ID of the object
+	ObjectID string `json:"object_id,omitempty"`
+	// Name of the object including its path
+	FullName string `json:"full_name,omitempty"`
+	// ID of the version to read. Omit to read the latest version
+	VersionID string `json:"version_id,omitempty"`
+	// Offset of the first byte to read
+	Offset int64 `json:"offset"`
+	// Length is the number of bytes to read
+	Length int64 `json:"length"`
+}
+
+// ReadRangeResponse is the response to the read range request
+type ReadRangeResponse struct {
+	common.APIResponse
+	// Content of the byte range, shorter than requested at the end of the object
+	Data []byte `json:"data"`
+	// Size of the whole object
+	Size uint64 `json:"size"`
+}
+
+// UploadRequest is used to upload an object from an io.Reader using a multipart upload
+type UploadRequest struct {
+	// ID of the deployment that will own the object
+	DeploymentID string
+	// Name of the object including its path
+	FullName string
+	// Encrypted whether object will be stored with inbuilt encryption or not
+	Encrypted bool
+	// MIME type of the object, detected from the content if omitted
+	ContentType string
+	// User defined metadata of the object
+	Metadata map[string]string
+	// User defined tags of the object
+	Tags map[string]string
+	// UploadID of a previously failed upload to resume. It is set by Upload once the
+	// upload is initiated, so that a failed upload can be retried with the same request.
+	UploadID string
+	// PartSize is the size of each part, defaults to DefaultPartSize
+	PartSize int64
+	// Concurrency is the number of parts uploaded in parallel, defaults to DefaultConcurrency
+	Concurrency int
+	// Progress is called after each part is uploaded or skipped
+	Progress func(Progress)
+}
+
+// DownloadRequest is used to download an object, or a byte range of it, to an io.Writer
+type DownloadRequest struct {
+	DeploymentID string
+	// ID of the object
+	ObjectID string
+	// Name of the object including its path
+	FullName string
+	// ID of the version to download. Omit to download the latest version
+	VersionID string
+	// Offset of the first byte to download
+	Offset int64
+	// Length is the number of bytes to download, zero downloads to the end of the object
+	Length int64
+	// ChunkSize is the number of bytes fetched per request, defaults to DefaultChunkSize
+	ChunkSize int64
+	// Progress is called after each chunk is written
+	Progress func(Progress)
+}
+
+// Initiate starts a multipart upload and returns its ID
+func (initiateRequest *InitiateMultipartRequest) Initiate() (uploadID string, apiErr *common.Error) {
+
+	initiateResponse := InitiateMultipartResponse{}
+	err := common.Execute("ObjectStoreMultipartInitiate", initiateRequest, &initiateResponse)
+
+	if err != nil {
+		return "", err
+	}
+
+	if initiateResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        initiateResponse.StatusCode,
+			ErrorDescription: initiateResponse.Description,
+		}
+
+		return "", apiErr
+	}
+
+	return initiateResponse.UploadID, nil
+}
+
+// UploadPart uploads a single part and returns the part as recorded by the platform
+func (uploadPartRequest *UploadPartRequest) UploadPart() (part Part, apiErr *common.Error) {
+
+	uploadPartResponse := UploadPartResponse{}
+	err := common.Execute("ObjectStoreMultipartUploadPart", uploadPartRequest, &uploadPartResponse)
+
+	if err != nil {
+		return Part{}, err
+	}
+
+	if uploadPartResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        uploadPartResponse.StatusCode,
+			ErrorDescription: uploadPartResponse.Description,
+		}
+
+		return Part{}, apiErr
+	}
+
+	return uploadPartResponse.Part, nil
+}
+
+// ListParts lists the parts already uploaded for a multipart upload
+func (listPartsRequest *ListPartsRequest) ListParts() (parts []Part, apiErr *common.Error) {
+
+	listPartsResponse := ListPartsResponse{}
+	err := common.Execute("ObjectStoreMultipartListParts", listPartsRequest, &listPartsResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if listPartsResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        listPartsResponse.StatusCode,
+			ErrorDescription: listPartsResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return listPartsResponse.Parts, nil
+}
+
+// Complete assembles the uploaded parts into an object and returns the ID of the object
+func (completeRequest *CompleteMultipartRequest) Complete() (objectID string, apiErr *common.Error) {
+
+	completeResponse := CompleteMultipartResponse{}
+	err := common.Execute("ObjectStoreMultipartComplete", completeRequest, &completeResponse)
+
+	if err != nil {
+		return "", err
+	}
+
+	if completeResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        completeResponse.StatusCode,
+			ErrorDescription: completeResponse.Description,
+		}
+
+		return "", apiErr
+	}
+
+	return completeResponse.ObjectID, nil
+}
+
+// Abort abandons a multipart upload, discarding all of its uploaded parts
+func (abortRequest *AbortMultipartRequest) Abort() (success bool, apiErr *common.Error) {
+
+	abortResponse := AbortMultipartResponse{}
+	err := common.Execute("ObjectStoreMultipartAbort", abortRequest, &abortResponse)
+
+	if err != nil {
+		return false, err
+	}
+
+	if abortResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        abortResponse.StatusCode,
+			ErrorDescription: abortResponse.Description,
+		}
+
+		return false, apiErr
+	}
+
+	return true, nil
+}
+
+// ListUploads lists incomplete multipart uploads
+func (listUploadsRequest *ListMultipartUploadsRequest) ListUploads() (uploads []MultipartUpload, apiErr *common.Error) {
+
+	listUploadsResponse := ListMultipartUploadsResponse{}
+	err := common.Execute("ObjectStoreMultipartListUploads", listUploadsRequest, &listUploadsResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if listUploadsResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        listUploadsResponse.StatusCode,
+			ErrorDescription: listUploadsResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return listUploadsResponse.Uploads, nil
+}
+
+// ReadRange reads a byte range of an object and returns it along with the size of the whole object
+func (readRangeRequest *ReadRangeRequest) ReadRange() (data []byte, size uint64, apiErr *common.Error) {
+
+	readRangeResponse := ReadRangeResponse{}
+	err := common.Execute("ObjectStoreReadRange", readRangeRequest, &readRangeResponse)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if readRangeResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        readRangeResponse.StatusCode,
+			ErrorDescription: readRangeResponse.Description,
+		}
+
+		return nil, 0, apiErr
+	}
+
+	return readRangeResponse.Data, readRangeResponse.Size, nil
+}
+
+// Upload reads the object from reader in parts and uploads them in parallel, returning the ID of
+// the stored object. If UploadID is set, parts already uploaded with a matching checksum are
+// skipped, so a failed upload can be resumed by calling Upload again with the same request and
+// a reader positioned at the start of the content.
+func (uploadRequest *UploadRequest) Upload(ctx context.Context, reader io.Reader) (objectID string, err error) {
+
+	partSize := uploadRequest.PartSize
+	if partSize == 0 {
+		partSize = DefaultPartSize
+	}
+	if partSize < MinPartSize {
+		return "", ErrPartSizeTooSmall
+	}
+
+	concurrency := uploadRequest.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	uploaded := map[int]Part{}
+	if uploadRequest.UploadID == "" {
+		initiateRequest := InitiateMultipartRequest{
+			DeploymentID: uploadRequest.DeploymentID,
+			FullName:     uploadRequest.FullName,
+			Encrypted:    uploadRequest.Encrypted,
+			ContentType:  uploadRequest.ContentType,
+			Metadata:     uploadRequest.Metadata,
+			Tags:         uploadRequest.Tags,
+		}
+		uploadID, apiErr := initiateRequest.Initiate()
+		if apiErr != nil {
+			return "", apiErr
+		}
+		uploadRequest.UploadID = uploadID
+	} else {
+		listPartsRequest := ListPartsRequest{UploadID: uploadRequest.UploadID}
+		parts, apiErr := listPartsRequest.ListParts()
+		if apiErr != nil {
+			return "", apiErr
+		}
+		for _, part := range parts {
+			uploaded[part.PartNumber] = part
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tracker := transferTracker{
+		progress: Progress{UploadID: uploadRequest.UploadID, TotalBytes: -1},
+		callback: uploadRequest.Progress,
+	}
+
+	type job struct {
+		part Part
+		data []byte
+	}
+
+	jobs := make(chan job)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				uploadPartRequest := UploadPartRequest{
+					UploadID:   uploadRequest.UploadID,
+					PartNumber: job.part.PartNumber,
+					Checksum:   job.part.Checksum,
+					Data:       job.data,
+				}
+				part, apiErr := uploadPartRequest.UploadPart()
+				if apiErr != nil {
+					tracker.fail(apiErr)
+					cancel()
+					continue
+				}
+				if part.Checksum != "" && part.Checksum != job.part.Checksum {
+					tracker.fail(ErrPartChecksumMismatch)
+					cancel()
+					continue
+				}
+
+				tracker.complete(job.part, false)
+			}
+		}()
+	}
+
+	contentHash := sha256.New()
+	var readErr error
+	for partNumber := 1; ctx.Err() == nil; partNumber++ {
+
+		data := make([]byte, partSize)
+		n, err := io.ReadFull(reader, data)
+		if err == io.EOF && partNumber > 1 {
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			readErr = err
+			break
+		}
+
+		data = data[:n]
+		contentHash.Write(data)
+		part := Part{
+			PartNumber: partNumber,
+			Size:       int64(n),
+			Checksum:   checksum(data),
+		}
+
+		if existing, ok := uploaded[partNumber]; ok &&
+			existing.Checksum == part.Checksum && existing.Size == part.Size {
+			tracker.complete(part, true)
+		} else {
+			select {
+			case jobs <- job{part: part, data: data}:
+			case <-ctx.Done():
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if readErr != nil {
+		return "", readErr
+	}
+	if tracker.err != nil {
+		return "", tracker.err
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	completeRequest := CompleteMultipartRequest{
+		UploadID:    uploadRequest.UploadID,
+		Parts:       tracker.sortedParts(),
+		ContentHash: hex.EncodeToString(contentHash.Sum(nil)),
+	}
+	objectID, apiErr := completeRequest.Complete()
+	if apiErr != nil {
+		return "", apiErr
+	}
+
+	return objectID, nil
+}
+
+// Download writes the requested byte range of the object to writer, fetching it in chunks,
+// and returns the number of bytes written
+func (downloadRequest *DownloadRequest) Download(ctx context.Context, writer io.Writer) (written int64, err error) {
+
+	if downloadRequest.Offset < 0 || downloadRequest.Length < 0 {
+		return 0, ErrInvalidRange
+	}
+
+	chunkSize := downloadRequest.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	tracker := transferTracker{
+		progress: Progress{TotalBytes: -1},
+		callback: downloadRequest.Progress,
+	}
+	if downloadRequest.Length > 0 {
+		tracker.progress.TotalBytes = downloadRequest.Length
+	}
+
+	offset := downloadRequest.Offset
+	for downloadRequest.Length == 0 || written < downloadRequest.Length {
+
+		if ctx.Err() != nil {
+			return written, ctx.Err()
+		}
+
+		length := chunkSize
+		if downloadRequest.Length > 0 && downloadRequest.Length-written < length {
+			length = downloadRequest.Length - written
+		}
+
+		readRangeRequest := ReadRangeRequest{
+			DeploymentID: downloadRequest.DeploymentID,
+			ObjectID:     downloadRequest.ObjectID,
+			FullName:     downloadRequest.FullName,
+			VersionID:    downloadRequest.VersionID,
+			Offset:       offset,
+			Length:       length,
+		}
+		data, size, apiErr := readRangeRequest.ReadRange()
+		if apiErr != nil {
+			return written, apiErr
+		}
+
+		if tracker.progress.TotalBytes < 0 {
+			tracker.progress.TotalBytes = int64(size) - downloadRequest.Offset
+		}
+
+		n, err := writer.Write(data)
+		written += int64(n)
+		offset += int64(n)
+		if err != nil {
+			return written, err
+		}
+
+		tracker.transferred(int64(n))
+
+		if int64(len(data)) < length || uint64(offset) >= size {
+			break
+		}
+	}
+
+	if downloadRequest.Length > 0 && written < downloadRequest.Length {
+		return written, io.ErrUnexpectedEOF
+	}
+
+	return written, nil
+}
+
+// transferTracker collects progress and the first error across concurrent transfers
+type transferTracker struct {
+	mutex    sync.Mutex
+	progress Progress
+	callback func(Progress)
+	parts    []Part
+	err      error
+}
+
+// complete records an uploaded or skipped part
+func (tracker *transferTracker) complete(part Part, skipped bool) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.parts = append(tracker.parts, part)
+	tracker.progress.BytesTransferred += part.Size
+	if skipped {
+		tracker.progress.PartsSkipped++
+	} else {
+		tracker.progress.PartsCompleted++
+	}
+
+	if tracker.callback != nil {
+		tracker.callback(tracker.progress)
+	}
+}
+
+// transferred records downloaded bytes
+func (tracker *transferTracker) transferred(n int64) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.progress.BytesTransferred += n
+	if tracker.callback != nil {
+		tracker.callback(tracker.progress)
+	}
+}
+
+// fail records the first error of a transfer
+func (tracker *transferTracker) fail(err error) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.err == nil {
+		tracker.err = err
+	}
+}
+
+// sortedParts returns recorded parts ordered by part number
+func (tracker *transferTracker) sortedParts() []Part {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	parts := append([]Part(nil), tracker.parts...)
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	return parts
+}
+
+// checksum is the hex encoded SHA-256 of data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,323 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+// fakeMultipart is a platform stand-in for multipart uploads and ranged reads
+type fakeMultipart struct {
+	mutex sync.Mutex
+	// parts by upload ID and part number
+	uploads map[string]map[int][]byte
+	// failPart rejects uploads of the part number with status code 500
+	failPart int
+	// inFlight and maxInFlight count concurrent part uploads
+	inFlight, maxInFlight int
+	// release blocks part uploads until closed, when set
+	release chan struct{}
+	// objects assembled by Complete, by object ID
+	objects  map[string][]byte
+	uploadID int
+}
+
+func startFakeMultipart(t *testing.T) (*fakeMultipart, *platformtest.Server) {
+
+	fake := &fakeMultipart{uploads: map[string]map[int][]byte{}, objects: map[string][]byte{}}
+	server := platformtest.Start(t)
+
+	server.Handle("ObjectStoreMultipartInitiate", func(json.RawMessage) interface{} {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		fake.uploadID++
+		uploadID := "upload-" + strconv.Itoa(fake.uploadID)
+		fake.uploads[uploadID] = map[int][]byte{}
+		return InitiateMultipartResponse{APIResponse: platformtest.OK(), UploadID: uploadID}
+	})
+
+	server.Handle("ObjectStoreMultipartUploadPart", func(body json.RawMessage) interface{} {
+		request := UploadPartRequest{}
+		json.Unmarshal(body, &request)
+
+		fake.mutex.Lock()
+		fake.inFlight++
+		if fake.inFlight > fake.maxInFlight {
+			fake.maxInFlight = fake.inFlight
+		}
+		release := fake.release
+		fake.mutex.Unlock()
+		if release != nil {
+			<-release
+		}
+
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		fake.inFlight--
+		if request.PartNumber == fake.failPart {
+			return platformtest.Fail("500", "part upload failed")
+		}
+		if checksum(request.Data) != request.Checksum {
+			return platformtest.Fail("400", "checksum mismatch")
+		}
+		fake.uploads[request.UploadID][request.PartNumber] = request.Data
+		return UploadPartResponse{APIResponse: platformtest.OK(), Part: Part{
+			PartNumber: request.PartNumber, Size: int64(len(request.Data)), Checksum: request.Checksum,
+		}}
+	})
+
+	server.Handle("ObjectStoreMultipartListParts", func(body json.RawMessage) interface{} {
+		request := ListPartsRequest{}
+		json.Unmarshal(body, &request)
+
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		response := ListPartsResponse{APIResponse: platformtest.OK()}
+		for partNumber, data := range fake.uploads[request.UploadID] {
+			response.Parts = append(response.Parts, Part{
+				PartNumber: partNumber, Size: int64(len(data)), Checksum: checksum(data),
+			})
+		}
+		return response
+	})
+
+	server.Handle("ObjectStoreMultipartComplete", func(body json.RawMessage) interface{} {
+		request := CompleteMultipartRequest{}
+		json.Unmarshal(body, &request)
+
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		object := []byte{}
+		for i, part := range request.Parts {
+			data, ok := fake.uploads[request.UploadID][part.PartNumber]
+			if !ok || part.PartNumber != i+1 || checksum(data) != part.Checksum {
+				return platformtest.Fail("400", "invalid part list")
+			}
+			object = append(object, data...)
+		}
+		if checksum(object) != request.ContentHash {
+			return platformtest.Fail("400", "content hash mismatch")
+		}
+		fake.objects["object-"+request.UploadID] = object
+		return CompleteMultipartResponse{APIResponse: platformtest.OK(), ObjectID: "object-" + request.UploadID}
+	})
+
+	server.Handle("ObjectStoreReadRange", func(body json.RawMessage) interface{} {
+		request := ReadRangeRequest{}
+		json.Unmarshal(body, &request)
+
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		object, ok := fake.objects[request.ObjectID]
+		if !ok {
+			return platformtest.Fail("404", "object not found")
+		}
+		start, end := request.Offset, request.Offset+request.Length
+		if start > int64(len(object)) {
+			start = int64(len(object))
+		}
+		if end > int64(len(object)) {
+			end = int64(len(object))
+		}
+		return ReadRangeResponse{APIResponse: platformtest.OK(), Data: object[start:end], Size: uint64(len(object))}
+	})
+
+	return fake, server
+}
+
+// testContent returns content of the given size with a repeating pattern, so that misplaced
+// parts are detected
+func testContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func TestUploadSplitsParts(t *testing.T) {
+
+	fake, server := startFakeMultipart(t)
+	content := testContent(int(2*MinPartSize + MinPartSize/2))
+
+	var progress []Progress
+	uploadRequest := UploadRequest{
+		FullName:    "data.bin",
+		PartSize:    MinPartSize,
+		Concurrency: 2,
+		Progress:    func(p Progress) { progress = append(progress, p) },
+	}
+	objectID, err := uploadRequest.Upload(context.Background(), bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if uploadRequest.UploadID != "upload-1" {
+		t.Fatalf("UploadID = %q, want it set from the initiated upload", uploadRequest.UploadID)
+	}
+	if !bytes.Equal(fake.objects[objectID], content) {
+		t.Fatal("assembled object does not match the uploaded content")
+	}
+
+	sizes := map[int]int{}
+	for _, body := range server.Requests("ObjectStoreMultipartUploadPart") {
+		request := UploadPartRequest{}
+		json.Unmarshal(body, &request)
+		sizes[request.PartNumber] = len(request.Data)
+	}
+	if len(sizes) != 3 || sizes[1] != int(MinPartSize) || sizes[2] != int(MinPartSize) || sizes[3] != int(MinPartSize/2) {
+		t.Fatalf("part sizes %v, want two full parts and a half part", sizes)
+	}
+
+	last := progress[len(progress)-1]
+	if len(progress) != 3 || last.PartsCompleted != 3 || last.BytesTransferred != int64(len(content)) {
+		t.Fatalf("progress %+v, want 3 updates ending with every byte transferred", progress)
+	}
+}
+
+func TestUploadEmptyContent(t *testing.T) {
+
+	fake, _ := startFakeMultipart(t)
+	uploadRequest := UploadRequest{FullName: "empty.bin"}
+	objectID, err := uploadRequest.Upload(context.Background(), bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if object, ok := fake.objects[objectID]; !ok || len(object) != 0 {
+		t.Fatalf("object = %v, want an empty object", object)
+	}
+}
+
+func TestUploadResumesAfterFailedPart(t *testing.T) {
+
+	fake, server := startFakeMultipart(t)
+	content := testContent(int(3 * MinPartSize))
+
+	fake.failPart = 2
+	uploadRequest := UploadRequest{FullName: "data.bin", PartSize: MinPartSize, Concurrency: 1}
+	_, err := uploadRequest.Upload(context.Background(), bytes.NewReader(content))
+	apiErr := &common.Error{}
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != "500" {
+		t.Fatalf("Upload error = %v, want the failed part error", err)
+	}
+	if uploadRequest.UploadID == "" {
+		t.Fatal("UploadID not kept for a resume")
+	}
+
+	fake.mutex.Lock()
+	fake.failPart = 0
+	fake.mutex.Unlock()
+	uploadsBefore := len(server.Requests("ObjectStoreMultipartUploadPart"))
+
+	var last Progress
+	uploadRequest.Progress = func(p Progress) { last = p }
+	objectID, err := uploadRequest.Upload(context.Background(), bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("resumed Upload: %v", err)
+	}
+	if !bytes.Equal(fake.objects[objectID], content) {
+		t.Fatal("assembled object does not match the uploaded content")
+	}
+	if len(server.Requests("ObjectStoreMultipartInitiate")) != 1 {
+		t.Fatal("resume initiated a new upload")
+	}
+
+	resent := server.Requests("ObjectStoreMultipartUploadPart")[uploadsBefore:]
+	for _, body := range resent {
+		request := UploadPartRequest{}
+		json.Unmarshal(body, &request)
+		if request.PartNumber == 1 {
+			t.Fatal("resume uploaded part 1 again")
+		}
+	}
+	if last.PartsSkipped != 1 || last.PartsCompleted != 2 {
+		t.Fatalf("progress %+v, want part 1 skipped and the others uploaded", last)
+	}
+}
+
+func TestUploadConcurrency(t *testing.T) {
+
+	fake, _ := startFakeMultipart(t)
+	release := make(chan struct{})
+	fake.release = release
+	content := testContent(int(4 * MinPartSize))
+
+	done := make(chan error)
+	go func() {
+		uploadRequest := UploadRequest{FullName: "data.bin", PartSize: MinPartSize, Concurrency: 2}
+		_, err := uploadRequest.Upload(context.Background(), bytes.NewReader(content))
+		done <- err
+	}()
+
+	for {
+		fake.mutex.Lock()
+		inFlight := fake.inFlight
+		fake.mutex.Unlock()
+		if inFlight == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	err := <-done
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if fake.maxInFlight != 2 {
+		t.Fatalf("%d parts uploaded in parallel, want 2", fake.maxInFlight)
+	}
+}
+
+func TestUploadPartSizeTooSmall(t *testing.T) {
+
+	uploadRequest := UploadRequest{FullName: "data.bin", PartSize: MinPartSize - 1}
+	_, err := uploadRequest.Upload(context.Background(), bytes.NewReader(nil))
+	if !errors.Is(err, ErrPartSizeTooSmall) {
+		t.Fatalf("Upload error = %v, want ErrPartSizeTooSmall", err)
+	}
+}
+
+func TestDownloadRanges(t *testing.T) {
+
+	fake, _ := startFakeMultipart(t)
+	content := testContent(1000)
+	fake.objects["object"] = content
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           []byte
+		err            error
+	}{
+		{"whole object", 0, 0, content, nil},
+		{"from offset", 990, 0, content[990:], nil},
+		{"middle range", 100, 250, content[100:350], nil},
+		{"last partial range", 900, 100, content[900:], nil},
+		{"past the end", 950, 100, content[950:], io.ErrUnexpectedEOF},
+		{"negative offset", -1, 0, nil, ErrInvalidRange},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := bytes.Buffer{}
+			downloadRequest := DownloadRequest{ObjectID: "object", Offset: test.offset, Length: test.length, ChunkSize: 64}
+			written, err := downloadRequest.Download(context.Background(), &buffer)
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Download error = %v, want %v", err, test.err)
+			}
+			if !bytes.Equal(buffer.Bytes(), test.want) || written != int64(len(test.want)) {
+				t.Fatalf("downloaded %d bytes, want %d", written, len(test.want))
+			}
+		})
+	}
+}
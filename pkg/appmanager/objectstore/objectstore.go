@@ -26,6 +26,24 @@ type Object struct {
 	ModifiedAt time.Time `json:"modified_at,omitempty"`
 	// If object is encrypted or not
 	Encrypted bool `json:"encrypted"`
+	// Key identifier needed to decrypt the downloaded content of an encrypted object
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier,omitempty"`
+	// MIME type of the object eg. `application/gzip`
+	ContentType string `json:"content_type,omitempty"`
+	// Hex encoded SHA-256 hash of the object content
+	ContentHash string `json:"content_hash,omitempty"`
+	// User defined metadata of the object
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// User defined tags of the object, used to filter listings
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID of this version of the object
+	VersionID string `json:"version_id,omitempty"`
+	// If this is the current version of the object
+	IsLatest bool `json:"is_latest,omitempty"`
+	// Time at which object was soft deleted, zero if the object is not deleted
+	DeletedAt time.Time `json:"deleted_at,omitempty"`
+	// Time after which a soft deleted object is purged permanently
+	PurgeAt time.Time `json:"purge_at,omitempty"`
 }
 
 // ListRequest list all objects where path or name is or begins with Name
@@ -34,6 +52,10 @@ type ListRequest struct {
 	DeploymentID string `json:"deployment_id"`
 	// Find objects where path or name begins with or is equal to
 	Pattern string `json:"pattern,omitempty"`
+	// Find objects having all of these tags with the same values
+	Tags map[string]string `json:"tags,omitempty"`
+	// Include soft deleted objects in the listing
+	IncludeDeleted bool `json:"include_deleted,omitempty"`
 }
 
 // ListResponse list of all objects
@@ -50,6 +72,8 @@ type DescribeRequest struct {
 	ObjectID string `json:"object_id,omitempty"`
 	// Name of the object including its path
 	FullName string `json:"full_name,omitempty"`
+	// ID of the version to describe. Omit to describe the latest version
+	VersionID string `json:"version_id,omitempty"`
 }
 
 // DescribeResponse is object meta returned from Platform API
@@ -70,11 +94,46 @@ type ObjectRequest struct {
 	DestinationName string `json:"destination_name"`
 	// Encrypted whether object will be stored with inbuilt encryption or not
 	Encrypted bool `json:"encrypted"`
+	// MIME type of the object, detected from the content if omitted
+	ContentType string `json:"content_type,omitempty"`
+	// User defined metadata of the object
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// User defined tags of the object
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// UpdateMetadataRequest is used to change the content type, metadata or tags of an
+// object without uploading it again
+type UpdateMetadataRequest struct {
+	DeploymentID string `json:"deployment_id"`
+	// ID of the object
+	ObjectID string `json:"object_id,omitempty"`
+	// Name of the object including its path
+	FullName string `json:"full_name,omitempty"`
+	// New MIME type of the object. Omit to keep the existing content type
+	ContentType string `json:"content_type,omitempty"`
+	// Metadata entries to add or replace
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Keys of metadata entries to remove
+	RemoveMetadata []string `json:"remove_metadata,omitempty"`
+	// Tags to add or replace
+	Tags map[string]string `json:"tags,omitempty"`
+	// Keys of tags to remove
+	RemoveTags []string `json:"remove_tags,omitempty"`
+}
+
+// UpdateMetadataResponse is the object returned after its metadata is updated
+type UpdateMetadataResponse struct {
+	common.APIResponse
+	Object
 }
 
 // DeleteRequest is used to request deletion of an object
 type DeleteRequest struct {
 	ObjectID string `json:"object_id"`
+	// RetentionPeriod when set, soft deletes the object so that it can be restored
+	// until the period expires. Omit to delete the object and all its versions permanently
+	RetentionPeriod time.Duration `json:"retention_period,omitempty"`
 }
 
 // DeleteResponse response to object deletion request
@@ -129,6 +188,7 @@ func (describeRequest *DescribeRequest) Describe() (object Object, apiErr *commo
 }
 
 // Delete is used to delete an already stored object, this action is not reverseable
+// unless RetentionPeriod is set, in which case the object can be restored with RestoreRequest
 func (deleteRequest *DeleteRequest) Delete() (success bool, apiErr *common.Error) {
 
 	deleteResponse := DeleteResponse{}
@@ -150,3 +210,27 @@ func (deleteRequest *DeleteRequest) Delete() (success bool, apiErr *common.Error
 
 	return true, nil
 }
+
+// UpdateMetadata is used to update the content type, metadata and tags of an existing object,
+// returning the updated object
+func (updateRequest *UpdateMetadataRequest) UpdateMetadata() (object Object, apiErr *common.Error) {
+
+	updateResponse := UpdateMetadataResponse{}
+	err := common.Execute("ObjectStoreUpdateMetadata", updateRequest, &updateResponse)
+
+	if err != nil {
+		return Object{}, err
+	}
+
+	if updateResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        updateResponse.StatusCode,
+			ErrorDescription: updateResponse.Description,
+		}
+
+		return Object{}, apiErr
+	}
+
+	return updateResponse.Object, nil
+}
@@ -0,0 +1,169 @@
+package presign
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/appmanager/objectstore"
+)
+
+// Handler is an http.Handler serving pre-signed URLs created by a Signer. GET requests
+// stream the object from the object store and PUT requests upload the request body.
+// The object store has no public URL to redirect to, so every byte of a download or upload
+// passes through the application manager serving the handler. Size its bandwidth, and the
+// expiry and content length limits of the URLs it signs, accordingly.
+type Handler struct {
+	// Keys maps key identifiers to HMAC keys accepted by the handler
+	Keys map[string][]byte
+	// Now returns the current time, defaults to time.Now
+	Now func() time.Time
+}
+
+// uploadResult is the JSON body returned after a successful PUT
+type uploadResult struct {
+	ObjectID string `json:"object_id"`
+}
+
+// NewHandler is used to create a Handler accepting URLs signed with key, identified by keyID
+func NewHandler(keyID string, key []byte) *Handler {
+	return &Handler{Keys: map[string][]byte{keyID: key}}
+}
+
+// ServeHTTP verifies the pre-signed URL and serves the object
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	now := time.Now
+	if handler.Now != nil {
+		now = handler.Now
+	}
+
+	grant, err := Verify(r.URL.Query(), handler.Keys, now())
+	if err != nil {
+		status := http.StatusForbidden
+		if errors.Is(err, ErrExpired) {
+			status = http.StatusGone
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if r.Method != grant.Method {
+		http.Error(w, ErrMethodNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch grant.Method {
+	case "GET":
+		handler.serveGet(w, r, grant)
+	case "PUT":
+		handler.servePut(w, r, grant)
+	}
+}
+
+// serveGet streams the object to the response through the application manager
+func (handler *Handler) serveGet(w http.ResponseWriter, r *http.Request, grant Grant) {
+
+	contentType := grant.ContentType
+	if contentType == "" {
+		describeRequest := objectstore.DescribeRequest{
+			DeploymentID: grant.DeploymentID,
+			ObjectID:     grant.ObjectID,
+			FullName:     grant.FullName,
+		}
+		object, apiErr := describeRequest.Describe()
+		if apiErr != nil {
+			http.Error(w, apiErr.Error(), http.StatusNotFound)
+			return
+		}
+		contentType = object.ContentType
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	downloadRequest := objectstore.DownloadRequest{
+		DeploymentID: grant.DeploymentID,
+		ObjectID:     grant.ObjectID,
+		FullName:     grant.FullName,
+	}
+
+	// the status is already written once the first chunk is streamed,
+	// so a failure can only be signalled by aborting the response
+	written, err := downloadRequest.Download(r.Context(), w)
+	if err != nil && written == 0 {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	} else if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// servePut uploads the request body within the limits of the grant
+func (handler *Handler) servePut(w http.ResponseWriter, r *http.Request, grant Grant) {
+
+	if grant.ContentType != "" {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != grant.ContentType {
+			http.Error(w, ErrContentTypeNotAllowed.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	var body io.Reader = r.Body
+	if grant.MaxContentLength > 0 {
+		if r.ContentLength > grant.MaxContentLength {
+			http.Error(w, ErrContentTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		body = &limitedReader{reader: r.Body, remaining: grant.MaxContentLength}
+	}
+
+	uploadRequest := objectstore.UploadRequest{
+		DeploymentID: grant.DeploymentID,
+		FullName:     grant.FullName,
+		Encrypted:    grant.Encrypted,
+		ContentType:  grant.ContentType,
+	}
+
+	objectID, err := uploadRequest.Upload(r.Context(), body)
+	if err != nil {
+		if uploadRequest.UploadID != "" {
+			abortRequest := objectstore.AbortMultipartRequest{UploadID: uploadRequest.UploadID}
+			abortRequest.Abort()
+		}
+		if errors.Is(err, ErrContentTooLarge) {
+			http.Error(w, ErrContentTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(uploadResult{ObjectID: objectID})
+}
+
+// limitedReader fails with ErrContentTooLarge once more than remaining bytes are read
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+// Read reads from the underlying reader, allowing at most one byte past the limit to detect overflow
+func (limited *limitedReader) Read(p []byte) (int, error) {
+	if limited.remaining < 0 {
+		return 0, ErrContentTooLarge
+	}
+	if int64(len(p)) > limited.remaining+1 {
+		p = p[:limited.remaining+1]
+	}
+	n, err := limited.reader.Read(p)
+	limited.remaining -= int64(n)
+	if limited.remaining < 0 {
+		return n, ErrContentTooLarge
+	}
+	return n, err
+}
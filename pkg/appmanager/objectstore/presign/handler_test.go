@@ -0,0 +1,75 @@
+package presign
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/appmanager/objectstore"
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+func TestHandler(t *testing.T) {
+
+	server := platformtest.Start(t)
+	server.Handle("ObjectStoreDescribe", func(json.RawMessage) interface{} {
+		return objectstore.DescribeResponse{
+			APIResponse: platformtest.OK(),
+			Object:      objectstore.Object{ObjectID: "object", ContentType: "text/plain"},
+		}
+	})
+	server.Handle("ObjectStoreReadRange", func(json.RawMessage) interface{} {
+		return objectstore.ReadRangeResponse{APIResponse: platformtest.OK(), Data: []byte("content"), Size: 7}
+	})
+
+	handler := &Handler{Keys: testKeys, Now: func() time.Time { return testNow }}
+	signURL := func(grant Grant) string {
+		signedURL, err := testSigner().Sign(grant)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return signedURL
+	}
+	getURL := signURL(Grant{Method: "GET", ObjectID: "object", Expires: testNow.Add(time.Minute)})
+	putURL := signURL(Grant{
+		Method: "PUT", FullName: "uploads/a.txt", Expires: testNow.Add(time.Minute),
+		MaxContentLength: 4, ContentType: "text/plain",
+	})
+
+	tests := []struct {
+		name        string
+		method, url string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"get", "GET", getURL, "", "", http.StatusOK},
+		{"get with put URL", "GET", putURL, "", "", http.StatusMethodNotAllowed},
+		{"put with get URL", "PUT", getURL, "text/plain", "data", http.StatusMethodNotAllowed},
+		{"tampered", "GET", strings.Replace(getURL, "object_id=object", "object_id=other", 1), "", "", http.StatusForbidden},
+		{"expired", "GET", signURL(Grant{Method: "GET", ObjectID: "object", Expires: testNow}), "", "", http.StatusGone},
+		{"put too large", "PUT", putURL, "text/plain", "too large", http.StatusRequestEntityTooLarge},
+		{"put content type", "PUT", putURL, "text/html", "data", http.StatusUnsupportedMediaType},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			request.Header.Set("Content-Type", test.contentType)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status {
+			t.Errorf("%s: status %d, want %d: %s", test.name, recorder.Code, test.status, recorder.Body)
+		}
+		if test.status == http.StatusOK &&
+			(recorder.Body.String() != "content" || recorder.Header().Get("Content-Type") != "text/plain") {
+			t.Errorf("%s: served %q as %q, want the object content", test.name, recorder.Body,
+				recorder.Header().Get("Content-Type"))
+		}
+	}
+}
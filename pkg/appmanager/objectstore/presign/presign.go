@@ -0,0 +1,220 @@
+package presign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters carried by a pre-signed URL
+const (
+	ParamMethod           = "method"
+	ParamDeploymentID     = "deployment_id"
+	ParamObjectID         = "object_id"
+	ParamFullName         = "full_name"
+	ParamExpires          = "expires"
+	ParamMaxContentLength = "max_content_length"
+	ParamContentType      = "content_type"
+	ParamEncrypted        = "encrypted"
+	ParamKeyID            = "key_id"
+	ParamSignature        = "signature"
+)
+
+// DefaultExpiry is the validity of a pre-signed URL when Grant leaves it unset
+const DefaultExpiry = 15 * time.Minute
+
+// Err* is used to throw errors while signing or verifying pre-signed URLs
+var (
+	ErrMissingKey            = errors.New("no signing key for key identifier")
+	ErrMissingObject         = errors.New("either object ID or full name is required")
+	ErrInvalidMethod         = errors.New("pre-signed URLs only support GET and PUT")
+	ErrInvalidSignature      = errors.New("invalid pre-signed URL signature")
+	ErrExpired               = errors.New("pre-signed URL has expired")
+	ErrMethodNotAllowed      = errors.New("request method does not match pre-signed URL")
+	ErrContentTooLarge       = errors.New("content length exceeds pre-signed URL limit")
+	ErrContentTypeNotAllowed = errors.New("content type does not match pre-signed URL")
+)
+
+// Grant describes what a pre-signed URL allows its holder to do
+type Grant struct {
+	// Method is either `GET` to download or `PUT` to upload the object
+	Method string
+	// ID of the deployment owning the object
+	DeploymentID string
+	// ID of the object, only valid for GET
+	ObjectID string
+	// Name of the object including its path
+	FullName string
+	// Expires is the time after which the URL is rejected
+	Expires time.Time
+	// MaxContentLength is the largest body accepted by a PUT URL, zero means no limit
+	MaxContentLength int64
+	// ContentType is the content type a PUT body must be sent with, or is served with for GET
+	ContentType string
+	// Encrypted whether an uploaded object will be stored with inbuilt encryption or not
+	Encrypted bool
+}
+
+// Signer is used to create pre-signed URLs that are verified by a Handler sharing its key
+type Signer struct {
+	// BaseURL is the public URL at which the Handler is served
+	BaseURL string
+	// KeyID identifies Key, allowing the Handler to accept several keys during rotation
+	KeyID string
+	// Key is the HMAC-SHA256 secret key
+	Key []byte
+}
+
+// Sign returns a URL granting access described by grant. If grant.Expires is zero,
+// the URL expires after DefaultExpiry.
+func (signer *Signer) Sign(grant Grant) (string, error) {
+
+	if len(signer.Key) == 0 {
+		return "", ErrMissingKey
+	}
+
+	base, err := url.Parse(signer.BaseURL)
+	if err != nil {
+		return "", err
+	}
+
+	if grant.Expires.IsZero() {
+		grant.Expires = time.Now().Add(DefaultExpiry)
+	}
+
+	err = grant.validate()
+	if err != nil {
+		return "", err
+	}
+
+	query := grant.query()
+	if signer.KeyID != "" {
+		query.Set(ParamKeyID, signer.KeyID)
+	}
+	query.Set(ParamSignature, sign(signer.Key, signer.KeyID, grant))
+
+	base.RawQuery = query.Encode()
+	return base.String(), nil
+}
+
+// Verify checks the signature and expiry of a pre-signed URL query and returns its grant.
+// keys maps key identifiers to HMAC keys, the empty identifier is used for unnamed keys.
+func Verify(query url.Values, keys map[string][]byte, now time.Time) (Grant, error) {
+
+	keyID := query.Get(ParamKeyID)
+	key, ok := keys[keyID]
+	if !ok || len(key) == 0 {
+		return Grant{}, ErrMissingKey
+	}
+
+	grant, err := parseGrant(query)
+	if err != nil {
+		return Grant{}, ErrInvalidSignature
+	}
+
+	signature, err := hex.DecodeString(query.Get(ParamSignature))
+	if err != nil {
+		return Grant{}, ErrInvalidSignature
+	}
+	expected, _ := hex.DecodeString(sign(key, keyID, grant))
+	if !hmac.Equal(signature, expected) {
+		return Grant{}, ErrInvalidSignature
+	}
+
+	if !now.Before(grant.Expires) {
+		return Grant{}, ErrExpired
+	}
+
+	return grant, nil
+}
+
+// validate checks that the grant can be signed
+func (grant Grant) validate() error {
+	if grant.Method != "GET" && grant.Method != "PUT" {
+		return ErrInvalidMethod
+	}
+	if grant.ObjectID == "" && grant.FullName == "" {
+		return ErrMissingObject
+	}
+	if grant.Method == "PUT" && grant.FullName == "" {
+		return ErrMissingObject
+	}
+	return nil
+}
+
+// query encodes the grant as URL query parameters
+func (grant Grant) query() url.Values {
+	query := url.Values{}
+	query.Set(ParamMethod, grant.Method)
+	query.Set(ParamDeploymentID, grant.DeploymentID)
+	query.Set(ParamExpires, strconv.FormatInt(grant.Expires.Unix(), 10))
+	if grant.ObjectID != "" {
+		query.Set(ParamObjectID, grant.ObjectID)
+	}
+	if grant.FullName != "" {
+		query.Set(ParamFullName, grant.FullName)
+	}
+	if grant.MaxContentLength > 0 {
+		query.Set(ParamMaxContentLength, strconv.FormatInt(grant.MaxContentLength, 10))
+	}
+	if grant.ContentType != "" {
+		query.Set(ParamContentType, grant.ContentType)
+	}
+	if grant.Encrypted {
+		query.Set(ParamEncrypted, "true")
+	}
+	return query
+}
+
+// parseGrant decodes a grant from URL query parameters
+func parseGrant(query url.Values) (grant Grant, err error) {
+
+	grant = Grant{
+		Method:       query.Get(ParamMethod),
+		DeploymentID: query.Get(ParamDeploymentID),
+		ObjectID:     query.Get(ParamObjectID),
+		FullName:     query.Get(ParamFullName),
+		ContentType:  query.Get(ParamContentType),
+		Encrypted:    query.Get(ParamEncrypted) == "true",
+	}
+
+	expires, err := strconv.ParseInt(query.Get(ParamExpires), 10, 64)
+	if err != nil {
+		return Grant{}, err
+	}
+	grant.Expires = time.Unix(expires, 0)
+
+	if value := query.Get(ParamMaxContentLength); value != "" {
+		grant.MaxContentLength, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return Grant{}, err
+		}
+	}
+
+	return grant, grant.validate()
+}
+
+// sign computes the hex encoded HMAC-SHA256 of the canonical form of the grant
+func sign(key []byte, keyID string, grant Grant) string {
+
+	canonical := strings.Join([]string{
+		grant.Method,
+		grant.DeploymentID,
+		grant.ObjectID,
+		grant.FullName,
+		strconv.FormatInt(grant.Expires.Unix(), 10),
+		strconv.FormatInt(grant.MaxContentLength, 10),
+		grant.ContentType,
+		strconv.FormatBool(grant.Encrypted),
+		keyID,
+	}, "\n")
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,146 @@
+package presign
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var (
+	testNow  = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	testKeys = map[string][]byte{"key-1": []byte("first secret"), "key-2": []byte("second secret")}
+)
+
+func testSigner() *Signer {
+	return &Signer{BaseURL: "https://files.example.com/presigned", KeyID: "key-1", Key: testKeys["key-1"]}
+}
+
+// signedQuery signs grant with the test signer and returns the query of the URL
+func signedQuery(t *testing.T, grant Grant) url.Values {
+
+	signedURL, err := testSigner().Sign(grant)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	parsed, err := url.Parse(signedURL)
+	if err != nil {
+		t.Fatalf("signed URL %q: %v", signedURL, err)
+	}
+	if parsed.Host != "files.example.com" || parsed.Path != "/presigned" {
+		t.Fatalf("signed URL %q is not below the base URL", signedURL)
+	}
+	return parsed.Query()
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+
+	grants := []Grant{
+		{Method: "GET", DeploymentID: "deployment", ObjectID: "object", Expires: testNow.Add(time.Minute)},
+		{Method: "GET", FullName: "reports/q1.pdf", ContentType: "application/pdf", Expires: testNow.Add(time.Minute)},
+		{
+			Method: "PUT", DeploymentID: "deployment", FullName: "uploads/a b&c.bin", Expires: testNow.Add(time.Hour),
+			MaxContentLength: 1 << 20, ContentType: "application/octet-stream", Encrypted: true,
+		},
+	}
+
+	for _, grant := range grants {
+		verified, err := Verify(signedQuery(t, grant), testKeys, testNow)
+		if err != nil {
+			t.Fatalf("Verify %+v: %v", grant, err)
+		}
+		if verified != (Grant{
+			Method: grant.Method, DeploymentID: grant.DeploymentID, ObjectID: grant.ObjectID,
+			FullName: grant.FullName, Expires: time.Unix(grant.Expires.Unix(), 0),
+			MaxContentLength: grant.MaxContentLength, ContentType: grant.ContentType, Encrypted: grant.Encrypted,
+		}) {
+			t.Fatalf("verified %+v, want %+v", verified, grant)
+		}
+	}
+}
+
+func TestSignDefaultExpiry(t *testing.T) {
+
+	query := signedQuery(t, Grant{Method: "GET", ObjectID: "object"})
+	grant, err := Verify(query, testKeys, time.Now())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if remaining := time.Until(grant.Expires); remaining <= DefaultExpiry-time.Minute || remaining > DefaultExpiry {
+		t.Fatalf("URL expires in %v, want DefaultExpiry", remaining)
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		signer Signer
+		grant  Grant
+		want   error
+	}{
+		{"no key", Signer{KeyID: "key-1"}, Grant{Method: "GET", ObjectID: "object"}, ErrMissingKey},
+		{"method", *testSigner(), Grant{Method: "DELETE", ObjectID: "object"}, ErrInvalidMethod},
+		{"no object", *testSigner(), Grant{Method: "GET"}, ErrMissingObject},
+		{"put by object ID", *testSigner(), Grant{Method: "PUT", ObjectID: "object"}, ErrMissingObject},
+	}
+
+	for _, test := range tests {
+		_, err := test.signer.Sign(test.grant)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: Sign error = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+
+	grant := Grant{
+		Method: "PUT", DeploymentID: "deployment", FullName: "uploads/a.bin", Expires: testNow.Add(time.Minute),
+		MaxContentLength: 100, ContentType: "text/plain",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(query url.Values)
+		now    time.Time
+		want   error
+	}{
+		{"method", func(query url.Values) { query.Set(ParamMethod, "GET") }, testNow, ErrInvalidSignature},
+		{"deployment", func(query url.Values) { query.Set(ParamDeploymentID, "other") }, testNow, ErrInvalidSignature},
+		{"name", func(query url.Values) { query.Set(ParamFullName, "uploads/b.bin") }, testNow, ErrInvalidSignature},
+		{"object ID added", func(query url.Values) { query.Set(ParamObjectID, "object") }, testNow, ErrInvalidSignature},
+		{"expiry", func(query url.Values) {
+			query.Set(ParamExpires, "9999999999")
+		}, testNow, ErrInvalidSignature},
+		{"content length", func(query url.Values) { query.Set(ParamMaxContentLength, "1000") }, testNow, ErrInvalidSignature},
+		{"content length removed", func(query url.Values) { query.Del(ParamMaxContentLength) }, testNow, ErrInvalidSignature},
+		{"content type", func(query url.Values) { query.Set(ParamContentType, "text/html") }, testNow, ErrInvalidSignature},
+		{"encrypted", func(query url.Values) { query.Set(ParamEncrypted, "true") }, testNow, ErrInvalidSignature},
+		{"signature", func(query url.Values) {
+			signature := []byte(query.Get(ParamSignature))
+			signature[0] ^= 1
+			query.Set(ParamSignature, string(signature))
+		}, testNow, ErrInvalidSignature},
+		{"signature not hex", func(query url.Values) { query.Set(ParamSignature, "zz") }, testNow, ErrInvalidSignature},
+		{"key ID of another key", func(query url.Values) { query.Set(ParamKeyID, "key-2") }, testNow, ErrInvalidSignature},
+		{"unknown key ID", func(query url.Values) { query.Set(ParamKeyID, "key-3") }, testNow, ErrMissingKey},
+		{"key ID removed", func(query url.Values) { query.Del(ParamKeyID) }, testNow, ErrMissingKey},
+		{"signature removed", func(query url.Values) { query.Del(ParamSignature) }, testNow, ErrInvalidSignature},
+		{"expiry removed", func(query url.Values) { query.Del(ParamExpires) }, testNow, ErrInvalidSignature},
+		{"method removed", func(query url.Values) { query.Del(ParamMethod) }, testNow, ErrInvalidSignature},
+		{"name removed", func(query url.Values) { query.Del(ParamFullName) }, testNow, ErrInvalidSignature},
+		{"expired", func(query url.Values) {}, testNow.Add(time.Minute), ErrExpired},
+		{"long expired", func(query url.Values) {}, testNow.Add(time.Hour), ErrExpired},
+	}
+
+	for _, test := range tests {
+		query := signedQuery(t, grant)
+		test.modify(query)
+
+		_, err := Verify(query, testKeys, test.now)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: Verify error = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
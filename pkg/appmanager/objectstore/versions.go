@@ -0,0 +1,137 @@
+package objectstore
+
+import (
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// ListVersionsRequest is used to list all versions of an object, newest first
+type ListVersionsRequest struct {
+	DeploymentID string `json:"deployment_id"`
+	// ID of the object
+	ObjectID string `json:"object_id,omitempty"`
+	// Name of the object including its path
+	FullName string `json:"full_name,omitempty"`
+}
+
+// ListVersionsResponse is the list of versions of an object
+type ListVersionsResponse struct {
+	common.APIResponse
+	// Versions of the object, newest first
+	Versions []Object `json:"versions,omitempty"`
+}
+
+// RestoreRequest is used to restore a previous version of an object, or a soft deleted object.
+// The restored content becomes the new latest version of the object.
+type RestoreRequest struct {
+	DeploymentID string `json:"deployment_id"`
+	// ID of the object
+	ObjectID string `json:"object_id,omitempty"`
+	// Name of the object including its path
+	FullName string `json:"full_name,omitempty"`
+	// ID of the version to restore. Omit to undelete a soft deleted object at its latest version
+	VersionID string `json:"version_id,omitempty"`
+}
+
+// RestoreResponse is the restored object
+type RestoreResponse struct {
+	common.APIResponse
+	Object
+}
+
+// PurgeRequest is used to permanently remove soft deleted objects or old versions. If neither
+// ObjectID nor FullName is set, every soft deleted object of the deployment matching the
+// other fields is purged. Soft deleted objects are only purged once the retention period they
+// were deleted with has expired, unless IgnoreRetention is set.
+type PurgeRequest struct {
+	DeploymentID string `json:"deployment_id"`
+	// ID of the object
+	ObjectID string `json:"object_id,omitempty"`
+	// Name of the object including its path
+	FullName string `json:"full_name,omitempty"`
+	// ID of a single non-latest version to purge. Omit to purge the whole soft deleted object
+	VersionID string `json:"version_id,omitempty"`
+	// Only purge objects deleted before this time. Omit to purge objects regardless of when
+	// they were deleted
+	DeletedBefore *time.Time `json:"deleted_before,omitempty"`
+	// IgnoreRetention purges soft deleted objects whose retention period has not yet expired
+	IgnoreRetention bool `json:"ignore_retention,omitempty"`
+}
+
+// PurgeResponse is the result of the purge request
+type PurgeResponse struct {
+	common.APIResponse
+	// Number of object versions permanently removed
+	Purged int `json:"purged"`
+}
+
+// ListVersions returns all versions of an object matching either the ObjectID or the full name
+func (listVersionsRequest *ListVersionsRequest) ListVersions() (versions []Object, apiErr *common.Error) {
+
+	listVersionsResponse := ListVersionsResponse{}
+	err := common.Execute("ObjectStoreListVersions", listVersionsRequest, &listVersionsResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if listVersionsResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        listVersionsResponse.StatusCode,
+			ErrorDescription: listVersionsResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return listVersionsResponse.Versions, nil
+}
+
+// Restore restores a version of an object, or undeletes a soft deleted object, and returns
+// the new latest version
+func (restoreRequest *RestoreRequest) Restore() (object Object, apiErr *common.Error) {
+
+	restoreResponse := RestoreResponse{}
+	err := common.Execute("ObjectStoreRestore", restoreRequest, &restoreResponse)
+
+	if err != nil {
+		return Object{}, err
+	}
+
+	if restoreResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        restoreResponse.StatusCode,
+			ErrorDescription: restoreResponse.Description,
+		}
+
+		return Object{}, apiErr
+	}
+
+	return restoreResponse.Object, nil
+}
+
+// Purge permanently removes soft deleted objects or versions, this action is not reverseable
+func (purgeRequest *PurgeRequest) Purge() (purged int, apiErr *common.Error) {
+
+	purgeResponse := PurgeResponse{}
+	err := common.Execute("ObjectStorePurge", purgeRequest, &purgeResponse)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if purgeResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        purgeResponse.StatusCode,
+			ErrorDescription: purgeResponse.Description,
+		}
+
+		return 0, apiErr
+	}
+
+	return purgeResponse.Purged, nil
+}
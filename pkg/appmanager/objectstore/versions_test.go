@@ -0,0 +1,41 @@
+package objectstore
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+func TestPurgeRequestRetention(t *testing.T) {
+
+	server := platformtest.Start(t)
+	server.Handle("ObjectStorePurge", func(json.RawMessage) interface{} {
+		return PurgeResponse{APIResponse: platformtest.OK()}
+	})
+
+	deletedBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	requests := []PurgeRequest{
+		{DeploymentID: "deployment"},
+		{DeploymentID: "deployment", DeletedBefore: &deletedBefore},
+		{DeploymentID: "deployment", IgnoreRetention: true},
+	}
+	for _, purgeRequest := range requests {
+		_, apiErr := purgeRequest.Purge()
+		if apiErr != nil {
+			t.Fatalf("Purge: %v", apiErr)
+		}
+	}
+
+	want := []string{
+		`{"deployment_id":"deployment"}`,
+		`{"deployment_id":"deployment","deleted_before":"2024-01-01T00:00:00Z"}`,
+		`{"deployment_id":"deployment","ignore_retention":true}`,
+	}
+	for i, body := range server.Requests("ObjectStorePurge") {
+		if string(body) != want[i] {
+			t.Errorf("request %d = %s, want %s", i, body, want[i])
+		}
+	}
+}
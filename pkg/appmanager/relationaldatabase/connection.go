@@ -0,0 +1,275 @@
+package relationaldatabase
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Environment variables used to describe the application database
+const (
+	EnvUsername     = "ADB_USERNAME"
+	EnvPassword     = "ADB_PASSWORD"
+	EnvDatabaseName = "ADB_DB_NAME"
+	EnvHost         = "ADB_HOST"
+	EnvPort         = "ADB_PORT"
+)
+
+// tlsConfigPrefix prefixes the names under which custom TLS configurations are registered with
+// the driver. Each configuration gets its own name so that pools opened with different
+// configurations do not overwrite each other's
+const tlsConfigPrefix = "semut-adb-"
+
+// tlsConfigNames maps each custom TLS configuration to the name it is registered under, so that
+// a configuration is only registered with the driver once however often it is used
+var (
+	tlsConfigMutex sync.Mutex
+	tlsConfigNames = map[*tls.Config]string{}
+)
+
+// Default connection pool and timeout settings used when Options leaves them unset
+const (
+	DefaultMaxOpenConns    = 10
+	DefaultMaxIdleConns    = 5
+	DefaultConnMaxLifetime = 5 * time.Minute
+	DefaultDialTimeout     = 10 * time.Second
+	DefaultPingTimeout     = 10 * time.Second
+)
+
+// Err* is used to throw errors while connecting to the application database
+var (
+	ErrInvalidPort      = errors.New("invalid application database port in " + EnvPort)
+	ErrDatabaseNotReady = errors.New("application database did not respond to ping")
+)
+
+// MissingConfigError is returned when required ADB_* environment variables are not set
+type MissingConfigError struct {
+	// Variables are the names of the environment variables that are missing
+	Variables []string
+}
+
+// Error is used to stringify the error into it's message
+func (err *MissingConfigError) Error() string {
+	return fmt.Sprintf("missing application database configuration: %s",
+		strings.Join(err.Variables, ", "))
+}
+
+// Options configures the connection pool, TLS and timeouts used by Open
+type Options struct {
+	// MaxOpenConns is the maximum number of open connections, defaults to DefaultMaxOpenConns
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections, defaults to DefaultMaxIdleConns
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum time a connection may be reused, defaults to DefaultConnMaxLifetime
+	ConnMaxLifetime time.Duration
+	// TLS is a driver TLS mode: `true`, `false`, `skip-verify` or `preferred`. Ignored if TLSConfig is set
+	TLS string
+	// TLSConfig is a custom TLS configuration used to connect to the database. It is registered with
+	// the driver the first time it is used and must not be modified afterwards
+	TLSConfig *tls.Config
+	// DialTimeout is the timeout for establishing connections, defaults to DefaultDialTimeout
+	DialTimeout time.Duration
+	// ReadTimeout is the I/O read timeout, zero means no timeout
+	ReadTimeout time.Duration
+	// WriteTimeout is the I/O write timeout, zero means no timeout
+	WriteTimeout time.Duration
+	// PingTimeout is the time given to the database to respond to the readiness ping,
+	// defaults to DefaultPingTimeout
+	PingTimeout time.Duration
+	// Params are additional connection parameters passed to the driver eg. `charset`
+	Params map[string]string
+	// Credentials resolves the username and password for each new connection, allowing
+	// rotated credentials to be picked up. If nil, ADB_USERNAME and ADB_PASSWORD are read once
+	Credentials CredentialProvider
+}
+
+// GetConfig is used to build a driver configuration from the ADB_* environment variables.
+// Unlike GetDatabaseDetails, all variables including the port are required. ADB_USERNAME and
+// ADB_PASSWORD are not required when options.Credentials is set.
+func GetConfig(options *Options) (*mysql.Config, error) {
+
+	if options == nil {
+		options = &Options{}
+	}
+
+	missing := []string{}
+	lookup := func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	}
+
+	username, password := "", ""
+	if options.Credentials == nil {
+		username = lookup(EnvUsername)
+		password = lookup(EnvPassword)
+	}
+	databaseName := lookup(EnvDatabaseName)
+	host := lookup(EnvHost)
+	portValue := lookup(EnvPort)
+
+	if len(missing) != 0 {
+		return nil, &MissingConfigError{Variables: missing}
+	}
+
+	port, err := strconv.Atoi(portValue)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, ErrInvalidPort
+	}
+
+	config := mysql.NewConfig()
+	config.User = username
+	config.Passwd = password
+	config.Net = "tcp"
+	config.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	config.DBName = databaseName
+	config.ParseTime = true
+	config.Timeout = options.DialTimeout
+	config.ReadTimeout = options.ReadTimeout
+	config.WriteTimeout = options.WriteTimeout
+
+	if config.Timeout == 0 {
+		config.Timeout = DefaultDialTimeout
+	}
+
+	if options.TLSConfig != nil {
+		config.TLSConfig, err = registerTLSConfig(options.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		config.TLSConfig = options.TLS
+	}
+
+	if len(options.Params) != 0 {
+		config.Params = make(map[string]string, len(options.Params))
+		for key, value := range options.Params {
+			config.Params[key] = value
+		}
+	}
+
+	return config, nil
+}
+
+// registerTLSConfig registers a custom TLS configuration with the driver, unless it already is,
+// and returns its name
+func registerTLSConfig(tlsConfig *tls.Config) (string, error) {
+
+	tlsConfigMutex.Lock()
+	defer tlsConfigMutex.Unlock()
+
+	tlsConfigName, ok := tlsConfigNames[tlsConfig]
+	if ok {
+		return tlsConfigName, nil
+	}
+
+	tlsConfigName = tlsConfigPrefix + strconv.Itoa(len(tlsConfigNames)+1)
+	err := mysql.RegisterTLSConfig(tlsConfigName, tlsConfig)
+	if err != nil {
+		return "", err
+	}
+	tlsConfigNames[tlsConfig] = tlsConfigName
+
+	return tlsConfigName, nil
+}
+
+// GetDriverDSN is used to get a DSN that can be passed directly to
+// `sql.Open("mysql", dsn)`, unlike the URL returned by GetDSN
+func GetDriverDSN(options *Options) (string, error) {
+	config, err := GetConfig(options)
+	if err != nil {
+		return "", err
+	}
+	return config.FormatDSN(), nil
+}
+
+// Open is used to open a connection pool to the application database, configured from the
+// ADB_* environment variables. The pool is pinged before being returned so that the database
+// is known to be ready for use.
+// NOTE: only application managers will be able to access this database!
+func Open(options *Options) (*sql.DB, error) {
+
+	if options == nil {
+		options = &Options{}
+	}
+
+	config, err := GetConfig(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var connector driver.Connector
+	if options.Credentials != nil {
+		connector = NewConnector(config, options.Credentials)
+	} else {
+		connector, err = mysql.NewConnector(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db := sql.OpenDB(connector)
+	configurePool(db, options)
+
+	err = ping(db, options.PingTimeout)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// configurePool applies pool limits from options, falling back to the defaults
+func configurePool(db *sql.DB, options *Options) {
+
+	maxOpenConns := options.MaxOpenConns
+	if maxOpenConns == 0 {
+		maxOpenConns = DefaultMaxOpenConns
+	}
+
+	maxIdleConns := options.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = DefaultMaxIdleConns
+	}
+
+	connMaxLifetime := options.ConnMaxLifetime
+	if connMaxLifetime == 0 {
+		connMaxLifetime = DefaultConnMaxLifetime
+	}
+
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+}
+
+// ping checks that the database is ready within the given timeout
+func ping(db *sql.DB, timeout time.Duration) error {
+
+	if timeout == 0 {
+		timeout = DefaultPingTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseNotReady, err)
+	}
+
+	return nil
+}
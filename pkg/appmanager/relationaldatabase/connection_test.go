@@ -0,0 +1,152 @@
+package relationaldatabase
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var testEnv = map[string]string{
+	EnvUsername:     "user",
+	EnvPassword:     "secret",
+	EnvDatabaseName: "app",
+	EnvHost:         "db.internal",
+	EnvPort:         "3306",
+}
+
+// setEnv sets the ADB_* environment variables to testEnv for the duration of the test, with
+// overrides applied and the variables listed in unset removed
+func setEnv(t *testing.T, overrides map[string]string, unset ...string) {
+
+	for name, value := range testEnv {
+		previous, ok := os.LookupEnv(name)
+		name := name
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+
+		if override, ok := overrides[name]; ok {
+			value = override
+		}
+		os.Setenv(name, value)
+	}
+	for _, name := range unset {
+		os.Unsetenv(name)
+	}
+}
+
+type staticProvider struct{}
+
+func (staticProvider) Credentials(ctx context.Context) (Credentials, error) {
+	return Credentials{}, nil
+}
+
+func TestGetConfig(t *testing.T) {
+
+	setEnv(t, nil)
+
+	config, err := GetConfig(&Options{
+		ReadTimeout: time.Second,
+		TLS:         "skip-verify",
+		Params:      map[string]string{"charset": "utf8mb4"},
+	})
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if config.User != "user" || config.Passwd != "secret" || config.DBName != "app" ||
+		config.Addr != "db.internal:3306" || config.Net != "tcp" || !config.ParseTime {
+		t.Fatalf("config = %+v, want it built from the environment", config)
+	}
+	if config.Timeout != DefaultDialTimeout || config.ReadTimeout != time.Second {
+		t.Fatalf("timeouts %v and %v, want the default dial timeout and 1s", config.Timeout, config.ReadTimeout)
+	}
+	if config.TLSConfig != "skip-verify" || config.Params["charset"] != "utf8mb4" {
+		t.Fatalf("config = %+v, want the TLS mode and params from options", config)
+	}
+
+	dsn, err := GetDriverDSN(nil)
+	if err != nil {
+		t.Fatalf("GetDriverDSN: %v", err)
+	}
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("ParseDSN(%q): %v", dsn, err)
+	}
+	if parsed.User != "user" || parsed.Passwd != "secret" || parsed.Addr != "db.internal:3306" || parsed.DBName != "app" {
+		t.Fatalf("DSN %q does not describe the environment", dsn)
+	}
+}
+
+func TestGetConfigMissing(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		unset   []string
+		options *Options
+		want    []string
+	}{
+		{"all", []string{EnvUsername, EnvPassword, EnvDatabaseName, EnvHost, EnvPort}, nil,
+			[]string{EnvUsername, EnvPassword, EnvDatabaseName, EnvHost, EnvPort}},
+		{"port", []string{EnvPort}, nil, []string{EnvPort}},
+		{"credentials from provider", []string{EnvUsername, EnvPassword, EnvHost},
+			&Options{Credentials: staticProvider{}}, []string{EnvHost}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setEnv(t, nil, test.unset...)
+
+			_, err := GetDriverDSN(test.options)
+			missingErr := &MissingConfigError{}
+			if !errors.As(err, &missingErr) {
+				t.Fatalf("GetDriverDSN error = %v, want MissingConfigError", err)
+			}
+			if !reflect.DeepEqual(missingErr.Variables, test.want) {
+				t.Fatalf("missing %v, want %v", missingErr.Variables, test.want)
+			}
+		})
+	}
+}
+
+func TestGetConfigInvalidPort(t *testing.T) {
+
+	for _, port := range []string{"", "db", "0", "65536"} {
+		setEnv(t, map[string]string{EnvPort: port})
+
+		_, err := GetConfig(nil)
+		if !errors.Is(err, ErrInvalidPort) {
+			t.Errorf("port %q: GetConfig error = %v, want ErrInvalidPort", port, err)
+		}
+	}
+}
+
+func TestGetConfigRegistersTLSConfigOnce(t *testing.T) {
+
+	setEnv(t, nil)
+	first, second := &tls.Config{ServerName: "first"}, &tls.Config{ServerName: "second"}
+
+	names := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		for _, tlsConfig := range []*tls.Config{first, second} {
+			config, err := GetConfig(&Options{TLSConfig: tlsConfig})
+			if err != nil {
+				t.Fatalf("GetConfig: %v", err)
+			}
+			names[config.TLSConfig] = true
+		}
+	}
+
+	if len(names) != 2 {
+		t.Fatalf("registered TLS configurations %v, want one per distinct configuration", names)
+	}
+}
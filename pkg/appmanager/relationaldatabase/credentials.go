@@ -0,0 +1,280 @@
+package relationaldatabase
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"go.semut.io/sdk/go-sdk/pkg/appmanager/secrets"
+)
+
+// MySQL error numbers that indicate the credentials used are no longer valid
+const (
+	errAccessDenied        = 1045
+	errPasswordExpired     = 1820
+	errPasswordExpiredAuth = 1862
+)
+
+// ErrEmptyCredentials is returned when a provider resolves an empty username
+var ErrEmptyCredentials = errors.New("credential provider returned an empty username")
+
+// Credentials are the username and password used to connect to the application database
+type Credentials struct {
+	// Username of the database user
+	Username string
+	// Password of the database user
+	Password string
+}
+
+// CredentialProvider is used to resolve the current database credentials, it is called
+// whenever new credentials are needed eg. after the platform rotates the password
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// EnvCredentialProvider re-reads ADB_USERNAME and ADB_PASSWORD from the environment
+type EnvCredentialProvider struct{}
+
+// FileCredentialProvider reads credentials from mounted files, the contents of
+// each file are trimmed of surrounding whitespace
+type FileCredentialProvider struct {
+	// UsernameFile is the path of the file containing the username, if empty ADB_USERNAME is used
+	UsernameFile string
+	// PasswordFile is the path of the file containing the password
+	PasswordFile string
+}
+
+// SecretsCredentialProvider reads credentials from the secrets store
+type SecretsCredentialProvider struct {
+	// Optional ID of the deployment
+	DeploymentID string
+	// UsernameSecretKey is the key of the secret containing the username, if empty ADB_USERNAME is used
+	UsernameSecretKey string
+	// PasswordSecretKey is the key of the secret containing the password
+	PasswordSecretKey string
+}
+
+// Credentials returns credentials from the environment
+func (provider EnvCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+	return Credentials{
+		Username: os.Getenv(EnvUsername),
+		Password: os.Getenv(EnvPassword),
+	}, nil
+}
+
+// Credentials returns credentials read from the mounted files
+func (provider FileCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+
+	credentials := Credentials{Username: os.Getenv(EnvUsername)}
+
+	if provider.UsernameFile != "" {
+		username, err := ioutil.ReadFile(provider.UsernameFile)
+		if err != nil {
+			return Credentials{}, err
+		}
+		credentials.Username = strings.TrimSpace(string(username))
+	}
+
+	password, err := ioutil.ReadFile(provider.PasswordFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+	credentials.Password = strings.TrimSpace(string(password))
+
+	return credentials, nil
+}
+
+// Credentials returns credentials retrieved from the secrets store
+func (provider SecretsCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+
+	credentials := Credentials{Username: os.Getenv(EnvUsername)}
+
+	if provider.UsernameSecretKey != "" {
+		username, apiErr := provider.retrieve(provider.UsernameSecretKey)
+		if apiErr != nil {
+			return Credentials{}, apiErr
+		}
+		credentials.Username = username
+	}
+
+	password, apiErr := provider.retrieve(provider.PasswordSecretKey)
+	if apiErr != nil {
+		return Credentials{}, apiErr
+	}
+	credentials.Password = password
+
+	return credentials, nil
+}
+
+// retrieve fetches a single secret value
+func (provider SecretsCredentialProvider) retrieve(secretKey string) (string, error) {
+
+	retrieveRequest := secrets.RetrieveSecretRequest{
+		SecretSpec: secrets.SecretSpec{
+			DeploymentID: provider.DeploymentID,
+			SecretKey:    secretKey,
+		},
+	}
+
+	secret, apiErr := retrieveRequest.RetrieveSecret()
+	if apiErr != nil {
+		return "", apiErr
+	}
+
+	return secret, nil
+}
+
+// Connector is a driver.Connector that resolves credentials through a CredentialProvider.
+// When a new connection fails authentication, credentials are fetched again and the
+// connection is retried once. Connections failing together share a single fetch, and
+// connections are not held up by a fetch while the cached credentials still work.
+// Connections already open are left untouched, so queries in flight are not interrupted
+// by a rotation.
+type Connector struct {
+	config   *mysql.Config
+	provider CredentialProvider
+	// dial opens a connection with the given credentials, replaced in tests
+	dial func(ctx context.Context, credentials Credentials) (driver.Conn, error)
+
+	mutex       sync.Mutex
+	credentials *Credentials
+	generation  uint64
+	fetch       *credentialFetch
+}
+
+// credentialFetch is a provider call shared by the connections waiting for its result
+type credentialFetch struct {
+	done        chan struct{}
+	credentials Credentials
+	generation  uint64
+	err         error
+}
+
+// NewConnector is used to create a Connector from a base driver configuration, the
+// username and password of config are replaced by those returned from provider
+func NewConnector(config *mysql.Config, provider CredentialProvider) *Connector {
+	connector := &Connector{
+		config:   config.Clone(),
+		provider: provider,
+	}
+	connector.dial = connector.connect
+	return connector
+}
+
+// Connect opens a new connection using the current credentials, refreshing them
+// once if the database rejects them
+func (connector *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+
+	credentials, generation, err := connector.current(ctx, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := connector.dial(ctx, credentials)
+	if err == nil || !IsAuthenticationError(err) {
+		return conn, err
+	}
+
+	credentials, _, err = connector.current(ctx, true, generation)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.dial(ctx, credentials)
+}
+
+// Driver returns the underlying MySQL driver
+func (connector *Connector) Driver() driver.Driver {
+	return mysql.MySQLDriver{}
+}
+
+// Refresh discards cached credentials, the next connection will fetch them again
+func (connector *Connector) Refresh() {
+	connector.mutex.Lock()
+	connector.credentials = nil
+	connector.mutex.Unlock()
+}
+
+// current returns the cached credentials and their generation, fetching them from the provider
+// if there are none. With refresh set, credentials of the stale generation are fetched again
+// unless another connection already replaced them. The provider is called without holding the
+// mutex, and concurrent callers wait for the fetch in progress instead of starting their own.
+func (connector *Connector) current(ctx context.Context, refresh bool, stale uint64) (Credentials, uint64, error) {
+
+	connector.mutex.Lock()
+
+	if connector.credentials != nil && (!refresh || connector.generation != stale) {
+		defer connector.mutex.Unlock()
+		return *connector.credentials, connector.generation, nil
+	}
+
+	fetch := connector.fetch
+	if fetch != nil {
+		connector.mutex.Unlock()
+
+		select {
+		case <-fetch.done:
+			return fetch.credentials, fetch.generation, fetch.err
+		case <-ctx.Done():
+			return Credentials{}, 0, ctx.Err()
+		}
+	}
+
+	fetch = &credentialFetch{done: make(chan struct{})}
+	connector.fetch = fetch
+	connector.mutex.Unlock()
+
+	credentials, err := connector.provider.Credentials(ctx)
+	if err == nil && credentials.Username == "" {
+		err = ErrEmptyCredentials
+	}
+
+	connector.mutex.Lock()
+	if err == nil {
+		connector.generation++
+		connector.credentials = &credentials
+		fetch.credentials, fetch.generation = credentials, connector.generation
+	}
+	fetch.err = err
+	connector.fetch = nil
+	connector.mutex.Unlock()
+	close(fetch.done)
+
+	return fetch.credentials, fetch.generation, fetch.err
+}
+
+// connect opens a single connection with the given credentials
+func (connector *Connector) connect(ctx context.Context, credentials Credentials) (driver.Conn, error) {
+
+	config := connector.config.Clone()
+	config.User = credentials.Username
+	config.Passwd = credentials.Password
+
+	mysqlConnector, err := mysql.NewConnector(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return mysqlConnector.Connect(ctx)
+}
+
+// IsAuthenticationError reports whether err is a MySQL error caused by invalid or expired credentials
+func IsAuthenticationError(err error) bool {
+
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+
+	switch mysqlErr.Number {
+	case errAccessDenied, errPasswordExpired, errPasswordExpiredAuth:
+		return true
+	}
+
+	return false
+}
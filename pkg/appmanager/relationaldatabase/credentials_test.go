@@ -0,0 +1,214 @@
+package relationaldatabase
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// fakeProvider returns the current password, counting calls and optionally blocking them
+// until release is closed
+type fakeProvider struct {
+	mutex    sync.Mutex
+	password string
+	err      error
+	calls    int
+	release  chan struct{}
+}
+
+func (provider *fakeProvider) Credentials(ctx context.Context) (Credentials, error) {
+
+	provider.mutex.Lock()
+	provider.calls++
+	release := provider.release
+	provider.mutex.Unlock()
+
+	if release != nil {
+		<-release
+	}
+
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	return Credentials{Username: "app", Password: provider.password}, provider.err
+}
+
+func (provider *fakeProvider) set(password string, release chan struct{}) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	provider.password, provider.release = password, release
+}
+
+func (provider *fakeProvider) callCount() int {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	return provider.calls
+}
+
+// fakeConn is the connection returned by fakeDatabase
+type fakeConn struct {
+	driver.Conn
+	password string
+}
+
+// fakeDatabase accepts connections with its current password and rejects others with an
+// access denied error
+type fakeDatabase struct {
+	mutex    sync.Mutex
+	password string
+	err      error
+}
+
+func (database *fakeDatabase) dial(ctx context.Context, credentials Credentials) (driver.Conn, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if database.err != nil {
+		return nil, database.err
+	}
+	if credentials.Password != database.password {
+		return nil, &mysql.MySQLError{Number: errAccessDenied, Message: "access denied"}
+	}
+	return &fakeConn{password: credentials.Password}, nil
+}
+
+func (database *fakeDatabase) rotate(password string) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	database.password = password
+}
+
+func newTestConnector(provider CredentialProvider, database *fakeDatabase) *Connector {
+	connector := NewConnector(mysql.NewConfig(), provider)
+	connector.dial = database.dial
+	return connector
+}
+
+func TestConnectorRefreshesRotatedCredentials(t *testing.T) {
+
+	provider := &fakeProvider{password: "old"}
+	database := &fakeDatabase{password: "old"}
+	connector := newTestConnector(provider, database)
+
+	conn, err := connector.Connect(context.Background())
+	if err != nil || conn.(*fakeConn).password != "old" {
+		t.Fatalf("Connect = %v, %v, want a connection with the old password", conn, err)
+	}
+	_, err = connector.Connect(context.Background())
+	if err != nil || provider.callCount() != 1 {
+		t.Fatalf("second Connect: %v with %d provider calls, want the cached credentials", err, provider.callCount())
+	}
+
+	database.rotate("new")
+	provider.set("new", nil)
+
+	conn, err = connector.Connect(context.Background())
+	if err != nil || conn.(*fakeConn).password != "new" {
+		t.Fatalf("Connect after rotation = %v, %v, want a connection with the new password", conn, err)
+	}
+	if provider.callCount() != 2 {
+		t.Fatalf("%d provider calls, want one refresh", provider.callCount())
+	}
+}
+
+func TestConnectorSharesConcurrentRefresh(t *testing.T) {
+
+	provider := &fakeProvider{password: "old"}
+	database := &fakeDatabase{password: "old"}
+	connector := newTestConnector(provider, database)
+
+	_, err := connector.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	database.rotate("new")
+	release := make(chan struct{})
+	provider.set("new", release)
+
+	const connections = 8
+	errs := make(chan error, connections)
+	for i := 0; i < connections; i++ {
+		go func() {
+			_, err := connector.Connect(context.Background())
+			errs <- err
+		}()
+	}
+
+	// let every connection fail authentication and wait on the blocked fetch
+	deadline := time.Now().Add(5 * time.Second)
+	for provider.callCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < connections; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+	}
+	if provider.callCount() != 2 {
+		t.Fatalf("%d provider calls, want the initial fetch and a single shared refresh", provider.callCount())
+	}
+}
+
+func TestConnectorFetchDoesNotHoldLock(t *testing.T) {
+
+	provider := &fakeProvider{password: "old"}
+	database := &fakeDatabase{password: "old"}
+	connector := newTestConnector(provider, database)
+
+	_, err := connector.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a refresh of the current generation blocks in the provider
+	release := make(chan struct{})
+	defer close(release)
+	provider.set("old", release)
+	go connector.current(context.Background(), true, 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for provider.callCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = connector.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect during a slow fetch: %v, want the cached credentials used", err)
+	}
+}
+
+func TestConnectorErrors(t *testing.T) {
+
+	providerErr := errors.New("secrets unavailable")
+	connector := newTestConnector(&fakeProvider{err: providerErr}, &fakeDatabase{})
+	_, err := connector.Connect(context.Background())
+	if !errors.Is(err, providerErr) {
+		t.Fatalf("Connect error = %v, want the provider error", err)
+	}
+
+	dialErr := errors.New("connection refused")
+	provider := &fakeProvider{password: "old"}
+	connector = newTestConnector(provider, &fakeDatabase{password: "old", err: dialErr})
+	_, err = connector.Connect(context.Background())
+	if !errors.Is(err, dialErr) || provider.callCount() != 1 {
+		t.Fatalf("Connect = %v with %d provider calls, want the dial error without a refresh", err,
+			provider.callCount())
+	}
+
+	connector = newTestConnector(&fakeProvider{password: "stale"}, &fakeDatabase{password: "new"})
+	_, err = connector.Connect(context.Background())
+	if !IsAuthenticationError(err) {
+		t.Fatalf("Connect error = %v, want an authentication error once refreshed credentials fail", err)
+	}
+}
@@ -0,0 +1,468 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Default table and lock names used when Options leaves them unset
+const (
+	DefaultTableName   = "schema_migrations"
+	DefaultLockName    = "schema_migrations_lock"
+	DefaultLockTimeout = time.Minute
+)
+
+// Err* is used to throw errors while running migrations
+var (
+	ErrInvalidFileName   = errors.New("invalid migration file name, expected <version>_<name>.(up|down).sql")
+	ErrDuplicateVersion  = errors.New("duplicate migration version")
+	ErrMissingUp         = errors.New("migration has no up file")
+	ErrMissingDown       = errors.New("migration has no down file")
+	ErrChecksumMismatch  = errors.New("applied migration checksum does not match migration file")
+	ErrUnknownVersion    = errors.New("applied migration version not found in migration files")
+	ErrLockNotAcquired   = errors.New("timed out waiting for the migration lock")
+	ErrInvalidTableName  = errors.New("invalid migrations table name")
+	ErrNothingToRollback = errors.New("no applied migrations to roll back")
+)
+
+// appliedAtLayout is the format of DATETIME columns read from a connection without parseTime
+const appliedAtLayout = "2006-01-02 15:04:05.999999"
+
+// fileNamePattern matches migration file names eg. `0001_create_users.up.sql`
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_\-]+)\.(up|down)\.sql$`)
+
+// tableNamePattern restricts table names since they cannot be passed as query parameters
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	// Version of the migration, taken from the file name prefix
+	Version int64
+	// Name of the migration, taken from the file name
+	Name string
+	// Up is the SQL applying the migration
+	Up string
+	// Down is the SQL reverting the migration
+	Down string
+	// Checksum is the SHA-256 of the up SQL, used to detect edits to applied migrations
+	Checksum string
+}
+
+// Status describes whether a migration has been applied
+type Status struct {
+	Migration
+	// Applied is true if the migration is recorded in the migrations table
+	Applied bool
+	// AppliedAt is the time at which the migration was applied
+	AppliedAt time.Time
+}
+
+// Options configures a Migrator
+type Options struct {
+	// Dir is the directory inside the file system containing migrations, defaults to `.`
+	Dir string
+	// TableName is the table used to record applied migrations, defaults to DefaultTableName
+	TableName string
+	// LockName is the name of the MySQL advisory lock held while migrating, defaults to DefaultLockName
+	LockName string
+	// LockTimeout is the time to wait for the advisory lock, defaults to DefaultLockTimeout
+	LockTimeout time.Duration
+	// AllowMissingDown allows migrations without a down file, Down will fail on them
+	AllowMissingDown bool
+}
+
+// Migrator applies and reverts migrations on the application database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	options    Options
+}
+
+// appliedMigration is a row in the migrations table
+type appliedMigration struct {
+	version   int64
+	checksum  string
+	appliedAt time.Time
+}
+
+// New is used to create a Migrator using migrations read from fsys, typically an `embed.FS`.
+// Migration files are named `<version>_<name>.up.sql` and `<version>_<name>.down.sql`.
+func New(db *sql.DB, fsys fs.FS, options *Options) (*Migrator, error) {
+
+	migrator := &Migrator{db: db}
+	if options != nil {
+		migrator.options = *options
+	}
+
+	if migrator.options.Dir == "" {
+		migrator.options.Dir = "."
+	}
+	if migrator.options.TableName == "" {
+		migrator.options.TableName = DefaultTableName
+	}
+	if migrator.options.LockName == "" {
+		migrator.options.LockName = DefaultLockName
+	}
+	if migrator.options.LockTimeout == 0 {
+		migrator.options.LockTimeout = DefaultLockTimeout
+	}
+
+	if !tableNamePattern.MatchString(migrator.options.TableName) {
+		return nil, ErrInvalidTableName
+	}
+
+	migrations, err := Load(fsys, migrator.options.Dir, migrator.options.AllowMissingDown)
+	if err != nil {
+		return nil, err
+	}
+	migrator.migrations = migrations
+
+	return migrator, nil
+}
+
+// Load reads and validates migrations present in dir of fsys, sorted by version
+func Load(fsys fs.FS, dir string, allowMissingDown bool) ([]Migration, error) {
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			migration.Checksum = checksum(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("%w: %d", ErrMissingUp, migration.Version)
+		}
+		if migration.Down == "" && !allowMissingDown {
+			return nil, fmt.Errorf("%w: %d", ErrMissingDown, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrations returns the migrations known to the migrator, sorted by version
+func (migrator *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), migrator.migrations...)
+}
+
+// Up applies all pending migrations in order and returns the versions that were applied
+func (migrator *Migrator) Up(ctx context.Context) (applied []int64, err error) {
+	return migrator.UpTo(ctx, -1)
+}
+
+// UpTo applies pending migrations up to and including version, a negative version applies all
+func (migrator *Migrator) UpTo(ctx context.Context, version int64) (applied []int64, err error) {
+
+	err = migrator.withLock(ctx, func(conn *sql.Conn) error {
+
+		appliedMigrations, err := migrator.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrator.migrations {
+			if version >= 0 && migration.Version > version {
+				break
+			}
+			if _, ok := appliedMigrations[migration.Version]; ok {
+				continue
+			}
+
+			err = execute(ctx, conn, migration.Up)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+
+			_, err = conn.ExecContext(ctx, fmt.Sprintf(
+				"INSERT INTO `%s` (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+				migrator.options.TableName),
+				migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+
+			applied = append(applied, migration.Version)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations in reverse order and returns the versions reverted
+func (migrator *Migrator) Down(ctx context.Context, steps int) (reverted []int64, err error) {
+
+	err = migrator.withLock(ctx, func(conn *sql.Conn) error {
+
+		appliedMigrations, err := migrator.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if len(appliedMigrations) == 0 {
+			return ErrNothingToRollback
+		}
+
+		for i := len(migrator.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := migrator.migrations[i]
+			if _, ok := appliedMigrations[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %d", ErrMissingDown, migration.Version)
+			}
+
+			err = execute(ctx, conn, migration.Down)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+
+			_, err = conn.ExecContext(ctx, fmt.Sprintf(
+				"DELETE FROM `%s` WHERE version = ?", migrator.options.TableName),
+				migration.Version)
+			if err != nil {
+				return err
+			}
+
+			reverted = append(reverted, migration.Version)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns every known migration along with whether it has been applied
+func (migrator *Migrator) Status(ctx context.Context) (statuses []Status, err error) {
+
+	conn, err := migrator.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	err = migrator.ensureTable(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedMigrations, err := migrator.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range migrator.migrations {
+		status := Status{Migration: migration}
+		if row, ok := appliedMigrations[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Verify checks that every applied migration still exists with an unchanged checksum
+func (migrator *Migrator) Verify(ctx context.Context) error {
+	return migrator.withLock(ctx, func(conn *sql.Conn) error {
+		_, err := migrator.verify(ctx, conn)
+		return err
+	})
+}
+
+// verify compares applied migrations against migration files and returns the applied ones
+func (migrator *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+
+	appliedMigrations, err := migrator.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]Migration, len(migrator.migrations))
+	for _, migration := range migrator.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, row := range appliedMigrations {
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+		if migration.Checksum != row.checksum {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+
+	return appliedMigrations, nil
+}
+
+// applied reads the migrations table
+func (migrator *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(
+		"SELECT version, checksum, applied_at FROM `%s`", migrator.options.TableName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedMigrations := map[int64]appliedMigration{}
+	for rows.Next() {
+		row := appliedMigration{}
+		var appliedAt interface{}
+		err = rows.Scan(&row.version, &row.checksum, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		row.appliedAt, err = parseAppliedAt(appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		appliedMigrations[row.version] = row
+	}
+
+	return appliedMigrations, rows.Err()
+}
+
+// parseAppliedAt converts an applied_at value, which the driver returns as time.Time only when
+// the connection was opened with parseTime, eg. by relationaldatabase.Open
+func parseAppliedAt(value interface{}) (time.Time, error) {
+	switch value := value.(type) {
+	case time.Time:
+		return value, nil
+	case []byte:
+		return time.ParseInLocation(appliedAtLayout, string(value), time.UTC)
+	case string:
+		return time.ParseInLocation(appliedAtLayout, value, time.UTC)
+	case nil:
+		return time.Time{}, nil
+	default:
+		return time.Time{}, fmt.Errorf("unexpected applied_at value of type %T", value)
+	}
+}
+
+// ensureTable creates the migrations table if it does not exist
+func (migrator *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS `%s` ("+
+			"version BIGINT NOT NULL PRIMARY KEY, "+
+			"name VARCHAR(255) NOT NULL, "+
+			"checksum CHAR(64) NOT NULL, "+
+			"applied_at DATETIME NOT NULL)",
+		migrator.options.TableName))
+	return err
+}
+
+// withLock runs fn on a single connection holding the MySQL advisory lock, so that
+// concurrent app manager replicas do not migrate at the same time
+func (migrator *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+
+	conn, err := migrator.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)",
+		migrator.options.LockName, int(migrator.options.LockTimeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return ErrLockNotAcquired
+	}
+
+	defer func() {
+		_, releaseErr := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)",
+			migrator.options.LockName)
+		if releaseErr != nil {
+			// closing conn only returns it to the pool, where its session would keep holding the
+			// lock. Marking it bad makes the pool close it, which ends the session and the lock
+			conn.Raw(func(driverConn interface{}) error {
+				return driver.ErrBadConn
+			})
+		}
+	}()
+
+	err = migrator.ensureTable(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// execute runs each statement of a migration file in order
+func execute(ctx context.Context, conn *sql.Conn, content string) error {
+	for _, statement := range SplitStatements(content) {
+		_, err := conn.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checksum is the hex encoded SHA-256 of content
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
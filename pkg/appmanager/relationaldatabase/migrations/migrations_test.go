@@ -0,0 +1,400 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeServer is a MySQL stand-in understanding the statements issued by Migrator. Like the
+// driver without parseTime, it returns DATETIME values as bytes.
+type fakeServer struct {
+	mu          sync.Mutex
+	rows        map[int64]fakeRow
+	lockOwner   *fakeConn
+	statements  []string
+	failRelease bool
+	closed      int
+}
+
+type fakeRow struct {
+	name, checksum string
+	appliedAt      time.Time
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{rows: map[int64]fakeRow{}}
+}
+
+func (server *fakeServer) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{server: server}, nil
+}
+
+func (server *fakeServer) Driver() driver.Driver {
+	return nil
+}
+
+func (server *fakeServer) open(t *testing.T) *sql.DB {
+	db := sql.OpenDB(server)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fakeConn struct {
+	server *fakeServer
+}
+
+func (conn *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (conn *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (conn *fakeConn) Close() error {
+	server := conn.server
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.closed++
+	if server.lockOwner == conn {
+		server.lockOwner = nil
+	}
+	return nil
+}
+
+func (conn *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+
+	server := conn.server
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SELECT RELEASE_LOCK"):
+		if server.failRelease {
+			return nil, errors.New("connection reset")
+		}
+		if server.lockOwner == conn {
+			server.lockOwner = nil
+		}
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS"):
+	case strings.HasPrefix(query, "INSERT INTO"):
+		server.rows[args[0].Value.(int64)] = fakeRow{
+			name: args[1].Value.(string), checksum: args[2].Value.(string), appliedAt: args[3].Value.(time.Time),
+		}
+	case strings.HasPrefix(query, "DELETE FROM"):
+		delete(server.rows, args[0].Value.(int64))
+	default:
+		if strings.Contains(query, "FAIL") {
+			return nil, errors.New("syntax error")
+		}
+		server.statements = append(server.statements, query)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (conn *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+
+	server := conn.server
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SELECT GET_LOCK"):
+		acquired := int64(0)
+		if server.lockOwner == nil || server.lockOwner == conn {
+			server.lockOwner = conn
+			acquired = 1
+		}
+		return &fakeRows{columns: []string{"acquired"}, values: [][]driver.Value{{acquired}}}, nil
+	case strings.HasPrefix(query, "SELECT version, checksum, applied_at"):
+		rows := &fakeRows{columns: []string{"version", "checksum", "applied_at"}}
+		for version, row := range server.rows {
+			rows.values = append(rows.values, []driver.Value{
+				version, row.checksum, []byte(row.appliedAt.Format("2006-01-02 15:04:05")),
+			})
+		}
+		return rows, nil
+	}
+
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (rows *fakeRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *fakeRows) Close() error {
+	return nil
+}
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if len(rows.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.values[0])
+	rows.values = rows.values[1:]
+	return nil
+}
+
+var testMigrations = fstest.MapFS{
+	"0001_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id INT);\n-- comment; not a statement\n")},
+	"0001_create_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+	"0002_add_email.up.sql":       {Data: []byte("ALTER TABLE users ADD email TEXT; CREATE INDEX email ON users (email);")},
+	"0002_add_email.down.sql":     {Data: []byte("ALTER TABLE users DROP email;")},
+	"0003_seed_defaults.up.sql":   {Data: []byte("INSERT_DEFAULTS;")},
+	"0003_seed_defaults.down.sql": {Data: []byte("DELETE_DEFAULTS;")},
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *Migrator {
+	migrator, err := New(db, fsys, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return migrator
+}
+
+func TestUpDownAndStatus(t *testing.T) {
+
+	server := newFakeServer()
+	migrator := newTestMigrator(t, server.open(t), testMigrations)
+	ctx := context.Background()
+
+	applied, err := migrator.UpTo(ctx, 2)
+	if err != nil {
+		t.Fatalf("UpTo: %v", err)
+	}
+	if !reflect.DeepEqual(applied, []int64{1, 2}) {
+		t.Fatalf("UpTo applied %v, want [1 2]", applied)
+	}
+
+	applied, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if !reflect.DeepEqual(applied, []int64{3}) {
+		t.Fatalf("Up applied %v, want [3]", applied)
+	}
+
+	wantStatements := []string{
+		"CREATE TABLE users (id INT)",
+		"ALTER TABLE users ADD email TEXT",
+		"CREATE INDEX email ON users (email)",
+		"INSERT_DEFAULTS",
+	}
+	if !reflect.DeepEqual(server.statements, wantStatements) {
+		t.Fatalf("executed %q, want %q", server.statements, wantStatements)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt.IsZero() {
+			t.Errorf("migration %d: applied %v at %v, want applied with a time", status.Version,
+				status.Applied, status.AppliedAt)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, 2)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if !reflect.DeepEqual(reverted, []int64{3, 2}) {
+		t.Fatalf("Down reverted %v, want [3 2]", reverted)
+	}
+
+	versions := []int64{}
+	for version := range server.rows {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	if !reflect.DeepEqual(versions, []int64{1}) {
+		t.Fatalf("recorded versions %v, want [1]", versions)
+	}
+	if server.lockOwner != nil {
+		t.Fatal("lock still held after migrating")
+	}
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"0001_ok.up.sql":    {Data: []byte("CREATE TABLE ok (id INT);")},
+		"0001_ok.down.sql":  {Data: []byte("DROP TABLE ok;")},
+		"0002_bad.up.sql":   {Data: []byte("FAIL;")},
+		"0002_bad.down.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	server := newFakeServer()
+	migrator := newTestMigrator(t, server.open(t), fsys)
+
+	applied, err := migrator.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "2_bad up") {
+		t.Fatalf("Up error = %v, want failure of 0002_bad", err)
+	}
+	if !reflect.DeepEqual(applied, []int64{1}) {
+		t.Fatalf("Up applied %v, want [1]", applied)
+	}
+	if _, ok := server.rows[2]; ok {
+		t.Fatal("failed migration was recorded")
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+
+	server := newFakeServer()
+	db := server.open(t)
+	ctx := context.Background()
+
+	_, err := newTestMigrator(t, db, testMigrations).Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	edited := fstest.MapFS{}
+	for name, file := range testMigrations {
+		edited[name] = file
+	}
+	edited["0002_add_email.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD mail TEXT;")}
+
+	err = newTestMigrator(t, db, edited).Verify(ctx)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Verify error = %v, want ErrChecksumMismatch", err)
+	}
+
+	delete(edited, "0003_seed_defaults.up.sql")
+	delete(edited, "0003_seed_defaults.down.sql")
+	edited["0002_add_email.up.sql"] = testMigrations["0002_add_email.up.sql"]
+
+	err = newTestMigrator(t, db, edited).Verify(ctx)
+	if !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("Verify error = %v, want ErrUnknownVersion", err)
+	}
+}
+
+func TestLockHeldByAnotherSession(t *testing.T) {
+
+	server := newFakeServer()
+	server.lockOwner = &fakeConn{server: server}
+	migrator := newTestMigrator(t, server.open(t), testMigrations)
+
+	_, err := migrator.Up(context.Background())
+	if !errors.Is(err, ErrLockNotAcquired) {
+		t.Fatalf("Up error = %v, want ErrLockNotAcquired", err)
+	}
+	if len(server.statements) != 0 {
+		t.Fatalf("executed %q without the lock", server.statements)
+	}
+}
+
+func TestFailedReleaseDiscardsConnection(t *testing.T) {
+
+	server := newFakeServer()
+	server.failRelease = true
+	db := server.open(t)
+	migrator := newTestMigrator(t, db, testMigrations)
+
+	_, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	server.mu.Lock()
+	closed, lockOwner := server.closed, server.lockOwner
+	server.mu.Unlock()
+
+	if closed != 1 {
+		t.Fatalf("closed %d connections, want the lock holding connection closed", closed)
+	}
+	if lockOwner != nil {
+		t.Fatal("lock still held after its connection was discarded")
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		fsys             fstest.MapFS
+		allowMissingDown bool
+		want             error
+	}{
+		{
+			name: "invalid file name",
+			fsys: fstest.MapFS{"create_users.sql": {Data: []byte("SELECT 1;")}},
+			want: ErrInvalidFileName,
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{"0001_users.down.sql": {Data: []byte("SELECT 1;")}},
+			want: ErrMissingUp,
+		},
+		{
+			name: "missing down",
+			fsys: fstest.MapFS{"0001_users.up.sql": {Data: []byte("SELECT 1;")}},
+			want: ErrMissingDown,
+		},
+		{
+			name:             "missing down allowed",
+			fsys:             fstest.MapFS{"0001_users.up.sql": {Data: []byte("SELECT 1;")}},
+			allowMissingDown: true,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_users.up.sql":  {Data: []byte("SELECT 1;")},
+				"0001_orders.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			allowMissingDown: true,
+			want:             ErrDuplicateVersion,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(test.fsys, ".", test.allowMissingDown)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("Load error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"INSERT INTO t VALUES ('a;b', \"c;d\");", []string{"INSERT INTO t VALUES ('a;b', \"c;d\")"}},
+		{"INSERT INTO t VALUES ('it''s; ok', 'x\\';y');", []string{"INSERT INTO t VALUES ('it''s; ok', 'x\\';y')"}},
+		{"SELECT `a;b` FROM t", []string{"SELECT `a;b` FROM t"}},
+		{"-- only; a comment\n# another; one\n/* block; comment */;", []string{}},
+		{"SELECT 1 /* inline; */ + 1;", []string{"SELECT 1  + 1"}},
+	}
+
+	for _, test := range tests {
+		got := SplitStatements(test.content)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitStatements(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import "strings"
+
+// SplitStatements splits SQL content into individual statements on `;`, ignoring
+// semicolons inside quoted strings, quoted identifiers and comments. Empty statements
+// and statements consisting only of comments are dropped.
+func SplitStatements(content string) []string {
+
+	statements := []string{}
+	current := strings.Builder{}
+	hasCode := false
+
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		if hasCode && statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+		hasCode = false
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := closingQuote(content, i+1, c)
+			current.WriteString(content[i:end])
+			hasCode = true
+			i = end - 1
+
+		case c == '-' && i+1 < len(content) && content[i+1] == '-', c == '#':
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content) - i
+			}
+			i += end - 1
+
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = len(content)
+			} else {
+				i += end + 3
+			}
+
+		case c == ';':
+			flush()
+
+		default:
+			current.WriteByte(c)
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				hasCode = true
+			}
+		}
+	}
+
+	flush()
+
+	return statements
+}
+
+// closingQuote returns the index just past the quote closing a literal started at start,
+// honouring backslash escapes and doubled quotes
+func closingQuote(content string, start int, quote byte) int {
+	for i := start; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(content) && content[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(content)
+}
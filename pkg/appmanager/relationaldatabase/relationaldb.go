@@ -8,6 +8,7 @@ import (
 // GetDSN is used to get a MySQL-compatible DSN URL to the
 // application database available for use by the application manager,
 // DSN string is of the form `mysql://<DB_USERNAME>:<DB_PASSWORD@<DB_HOST>:<DB_PORT>/<DB_NAME>`,
+// this URL cannot be passed directly to the MySQL driver, use GetDriverDSN or Open instead,
 // NOTE: only application managers will be able to access this database!
 func GetDSN() string {
 	// MySQL DSN (data source name) for ADB
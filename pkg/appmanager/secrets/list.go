@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// SecretMetadata describes a stored secret, its value is never returned
+type SecretMetadata struct {
+	SecretSpec
+	// Time at which the first version of the secret was stored
+	CreatedAt time.Time `json:"created_at"`
+	// Time at which the latest version of the secret was stored
+	UpdatedAt time.Time `json:"updated_at"`
+	// Latest enabled version of the secret
+	Version int64 `json:"version"`
+	// GeneratorType is the credential type used when the secret was created by
+	// GenerateStoreCredentials, empty for secrets stored with StoreSecret
+	GeneratorType common.CredentialType `json:"generator_type,omitempty"`
+}
+
+// ListSecretsRequest is used to list the secrets of a deployment
+type ListSecretsRequest struct {
+	// Optional ID of the deployment
+	DeploymentID string `json:"deployment_id,omitempty"`
+	// Only list secrets whose key begins with Prefix. Omit to list all secrets
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// ListSecretsResponse is the list of secret metadata
+type ListSecretsResponse struct {
+	common.APIResponse
+	// Metadata of the matching secrets
+	Secrets []SecretMetadata `json:"secrets,omitempty"`
+}
+
+// List returns the metadata of the secrets matching the request
+func (listRequest *ListSecretsRequest) List() (secrets []SecretMetadata, apiErr *common.Error) {
+
+	listResponse := ListSecretsResponse{}
+	err := common.Execute("SecretsList", listRequest, &listResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if listResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        listResponse.StatusCode,
+			ErrorDescription: listResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return listResponse.Secrets, nil
+}
@@ -0,0 +1,481 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Default rotation schedule settings used when Rotator leaves them unset
+const (
+	// DefaultCheckInterval is how often Rotator.Run looks for secrets due for rotation
+	DefaultCheckInterval = time.Minute
+	// DefaultRetryBackoff is the delay before retrying the first failed rotation of a secret
+	DefaultRetryBackoff = time.Minute
+	// DefaultMaxRetryBackoff caps the delay between retries of failed rotations
+	DefaultMaxRetryBackoff = time.Hour
+)
+
+// Err* is used to throw errors during secret rotation
+var (
+	ErrInvalidRotationConfig = errors.New("rotation requires a secret key, a positive interval and an apply hook")
+	ErrAlreadyRegistered     = errors.New("secret is already registered for rotation")
+	ErrNotRegistered         = errors.New("secret is not registered for rotation")
+	ErrRotationInProgress    = errors.New("secret is already being rotated")
+)
+
+// RotationOutcome is the result of a rotation attempt
+type RotationOutcome string
+
+const (
+	// RotationSucceeded means the pending version was applied and became current
+	RotationSucceeded RotationOutcome = "Succeeded"
+	// RotationRolledBack means the rotation failed and the current version was left in place
+	RotationRolledBack RotationOutcome = "RolledBack"
+	// RotationFailed means the rotation failed and could not be fully rolled back, the secret
+	// or the system it protects may need attention
+	RotationFailed RotationOutcome = "Failed"
+)
+
+// RotationEvent is passed to rotation hooks
+type RotationEvent struct {
+	SecretSpec
+	// Version currently retrieved by consumers, zero if the secret did not exist
+	CurrentVersion int64
+	// Value of the current version
+	CurrentValue string
+	// Version holding the new value, disabled until the rotation succeeds
+	PendingVersion int64
+	// New value to apply
+	PendingValue string
+}
+
+// RotationHook applies a rotation to the system using the secret, eg. by updating a database user
+type RotationHook func(ctx context.Context, event RotationEvent) error
+
+// RotationConfig registers a secret for automatic rotation
+type RotationConfig struct {
+	SecretSpec
+	// CredentialFormat is the format of the generated values
+	CredentialFormat
+	// Interval between rotations
+	Interval time.Duration
+	// Apply makes the system using the secret accept the pending value
+	Apply RotationHook
+	// Revert is optional and undoes Apply, it is called when the pending version cannot be
+	// made current after Apply succeeded
+	Revert RotationHook
+}
+
+// AuditRecord describes a rotation attempt
+type AuditRecord struct {
+	SecretSpec
+	// Time at which the rotation started
+	StartedAt time.Time
+	// Time at which the rotation finished
+	FinishedAt time.Time
+	// Version current before the rotation
+	PreviousVersion int64
+	// Version created by the rotation, zero if none was stored
+	NewVersion int64
+	// Outcome of the rotation
+	Outcome RotationOutcome
+	// Err describes the failure, empty on success
+	Err string
+}
+
+// AuditLog records every rotation attempt
+type AuditLog interface {
+	Record(record AuditRecord) error
+}
+
+// AuditFunc adapts a function to an AuditLog
+type AuditFunc func(record AuditRecord) error
+
+// Record calls the function
+func (audit AuditFunc) Record(record AuditRecord) error {
+	return audit(record)
+}
+
+// RotationLock serialises rotations of a secret across processes
+type RotationLock interface {
+	// TryLock takes the lock of the secret without waiting, reporting false if another process
+	// holds it. unlock releases the lock once the rotation is over
+	TryLock(ctx context.Context, spec SecretSpec) (unlock func(), locked bool, err error)
+}
+
+// rotationEntry is a registered secret and its schedule
+type rotationEntry struct {
+	config   RotationConfig
+	next     time.Time
+	failures int
+	rotating bool
+}
+
+// Rotator rotates registered secrets on schedule. Each rotation happens in two phases: a new
+// value is generated and stored as a disabled pending version, then the Apply hook runs and the
+// pending version is enabled to become current. If the hook fails, the pending version is
+// destroyed and the current version stays in place, and the rotation is retried after an
+// exponential backoff.
+//
+// A Rotator only prevents concurrent rotations of a secret within the process. When several
+// replicas run a Rotator for the same secrets, they must share a Lock so that a single replica
+// rotates each secret.
+type Rotator struct {
+	// Audit receives a record of every rotation attempt. Optional
+	Audit AuditLog
+	// Lock is taken around each rotation, and due secrets are checked again once it is held so
+	// that a rotation made by another replica is not repeated. Required when several processes
+	// rotate the same secrets
+	Lock RotationLock
+	// CheckInterval is how often Run looks for due secrets, defaults to DefaultCheckInterval
+	CheckInterval time.Duration
+	// RetryBackoff is the delay before retrying a failed rotation, doubling with each consecutive
+	// failure. Defaults to DefaultRetryBackoff
+	RetryBackoff time.Duration
+	// MaxRetryBackoff caps the delay between retries, defaults to DefaultMaxRetryBackoff
+	MaxRetryBackoff time.Duration
+	// Now returns the current time, defaults to time.Now
+	Now func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]*rotationEntry
+}
+
+// NewRotator is used to create a Rotator recording rotations to audit
+func NewRotator(audit AuditLog) *Rotator {
+	return &Rotator{Audit: audit, entries: map[string]*rotationEntry{}}
+}
+
+// Register schedules a secret for rotation. The first rotation is due one interval after the
+// latest version of the secret was stored, or immediately if it has no version yet.
+func (rotator *Rotator) Register(config RotationConfig) error {
+
+	if config.SecretKey == "" || config.Interval <= 0 || config.Apply == nil {
+		return ErrInvalidRotationConfig
+	}
+
+	rotator.mutex.Lock()
+	defer rotator.mutex.Unlock()
+
+	if rotator.entries == nil {
+		rotator.entries = map[string]*rotationEntry{}
+	}
+	id := specID(config.SecretSpec)
+	if _, ok := rotator.entries[id]; ok {
+		return ErrAlreadyRegistered
+	}
+	rotator.entries[id] = &rotationEntry{config: config}
+
+	return nil
+}
+
+// Unregister stops rotating a secret
+func (rotator *Rotator) Unregister(spec SecretSpec) {
+	rotator.mutex.Lock()
+	defer rotator.mutex.Unlock()
+	delete(rotator.entries, specID(spec))
+}
+
+// NextRotation returns when a registered secret is next due, zero until Run or Rotate has
+// looked the secret up
+func (rotator *Rotator) NextRotation(spec SecretSpec) (time.Time, error) {
+	rotator.mutex.Lock()
+	defer rotator.mutex.Unlock()
+
+	entry, ok := rotator.entries[specID(spec)]
+	if !ok {
+		return time.Time{}, ErrNotRegistered
+	}
+	return entry.next, nil
+}
+
+// Run rotates due secrets until ctx is cancelled, then returns the context error. Failed
+// rotations are audited and retried with a capped exponential backoff.
+func (rotator *Rotator) Run(ctx context.Context) error {
+
+	checkInterval := rotator.CheckInterval
+	if checkInterval <= 0 {
+		checkInterval = DefaultCheckInterval
+	}
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		rotator.rotateDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rotateDue rotates every registered secret whose rotation is due
+func (rotator *Rotator) rotateDue(ctx context.Context) {
+
+	rotator.mutex.Lock()
+	specs := make([]SecretSpec, 0, len(rotator.entries))
+	for _, entry := range rotator.entries {
+		specs = append(specs, entry.config.SecretSpec)
+	}
+	rotator.mutex.Unlock()
+
+	for _, spec := range specs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		due, err := rotator.due(spec)
+		if err != nil || !due {
+			continue
+		}
+		rotator.rotate(ctx, spec, true)
+	}
+}
+
+// due reports whether a secret should be rotated, looking up its latest version on first use
+func (rotator *Rotator) due(spec SecretSpec) (bool, error) {
+
+	next, err := rotator.NextRotation(spec)
+	if err != nil {
+		return false, err
+	}
+
+	if next.IsZero() {
+		latest, err := latestVersion(spec)
+		if err != nil {
+			return false, err
+		}
+
+		rotator.mutex.Lock()
+		entry, ok := rotator.entries[specID(spec)]
+		if ok && entry.next.IsZero() && latest.Version != 0 {
+			entry.next = latest.CreatedAt.Add(entry.config.Interval)
+		}
+		rotator.mutex.Unlock()
+
+		if latest.Version == 0 {
+			return true, nil
+		}
+		next, _ = rotator.NextRotation(spec)
+	}
+
+	return !rotator.now().Before(next), nil
+}
+
+// Rotate rotates a registered secret immediately and returns the audit record of the attempt.
+// It fails with ErrRotationInProgress if the secret is being rotated, by this process or by
+// another holding the Lock.
+func (rotator *Rotator) Rotate(ctx context.Context, spec SecretSpec) (record AuditRecord, err error) {
+	return rotator.rotate(ctx, spec, false)
+}
+
+// rotate rotates a registered secret. With onlyDue set and a Lock configured, the secret is
+// only rotated if no other process rotated it since it became due.
+func (rotator *Rotator) rotate(ctx context.Context, spec SecretSpec, onlyDue bool) (record AuditRecord, err error) {
+
+	rotator.mutex.Lock()
+	entry, ok := rotator.entries[specID(spec)]
+	if !ok {
+		rotator.mutex.Unlock()
+		return AuditRecord{}, ErrNotRegistered
+	}
+	if entry.rotating {
+		rotator.mutex.Unlock()
+		return AuditRecord{}, ErrRotationInProgress
+	}
+	entry.rotating = true
+	config := entry.config
+	rotator.mutex.Unlock()
+
+	defer func() {
+		rotator.mutex.Lock()
+		entry.rotating = false
+		rotator.mutex.Unlock()
+	}()
+
+	if rotator.Lock != nil {
+		unlock, locked, err := rotator.Lock.TryLock(ctx, spec)
+		if err != nil {
+			rotator.schedule(entry, false, rotator.now())
+			return AuditRecord{}, err
+		}
+		if !locked {
+			return AuditRecord{}, ErrRotationInProgress
+		}
+		defer unlock()
+
+		if onlyDue {
+			latest, err := latestVersion(spec)
+			if err != nil {
+				rotator.schedule(entry, false, rotator.now())
+				return AuditRecord{}, err
+			}
+			if latest.Version != 0 && rotator.now().Before(latest.CreatedAt.Add(config.Interval)) {
+				rotator.schedule(entry, true, latest.CreatedAt)
+				return AuditRecord{}, nil
+			}
+		}
+	}
+
+	record = AuditRecord{SecretSpec: spec, StartedAt: rotator.now()}
+	record.PreviousVersion, record.NewVersion, record.Outcome, err = rotate(ctx, config)
+	record.FinishedAt = rotator.now()
+	if err != nil {
+		record.Err = err.Error()
+	}
+
+	rotator.schedule(entry, record.Outcome == RotationSucceeded, record.FinishedAt)
+
+	if rotator.Audit != nil {
+		auditErr := rotator.Audit.Record(record)
+		if err == nil {
+			err = auditErr
+		}
+	}
+
+	return record, err
+}
+
+// schedule sets when a secret is next due after a rotation finished at the given time, one
+// interval later on success or after the retry backoff on failure
+func (rotator *Rotator) schedule(entry *rotationEntry, succeeded bool, finishedAt time.Time) {
+
+	rotator.mutex.Lock()
+	defer rotator.mutex.Unlock()
+
+	if succeeded {
+		entry.failures = 0
+		entry.next = finishedAt.Add(entry.config.Interval)
+		return
+	}
+
+	entry.failures++
+	entry.next = finishedAt.Add(rotator.retryBackoff(entry.failures))
+}
+
+// retryBackoff returns the delay before retrying a rotation after consecutive failures
+func (rotator *Rotator) retryBackoff(failures int) time.Duration {
+
+	backoff, maximum := rotator.RetryBackoff, rotator.MaxRetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	if maximum <= 0 {
+		maximum = DefaultMaxRetryBackoff
+		if backoff > maximum {
+			maximum = backoff
+		}
+	}
+
+	for i := 1; i < failures && backoff < maximum; i++ {
+		backoff *= 2
+	}
+	if backoff > maximum {
+		return maximum
+	}
+	return backoff
+}
+
+// now returns the current time
+func (rotator *Rotator) now() time.Time {
+	if rotator.Now != nil {
+		return rotator.Now()
+	}
+	return time.Now()
+}
+
+// rotate performs the two phases of a rotation
+func rotate(ctx context.Context, config RotationConfig) (previousVersion, newVersion int64,
+	outcome RotationOutcome, err error) {
+
+	event := RotationEvent{SecretSpec: config.SecretSpec}
+
+	latest, err := latestVersion(config.SecretSpec)
+	if err != nil {
+		return 0, 0, RotationRolledBack, err
+	}
+	if latest.Version != 0 {
+		retrieveRequest := RetrieveSecretRequest{SecretSpec: config.SecretSpec}
+		value, version, apiErr := retrieveRequest.RetrieveSecretVersion()
+		if apiErr != nil {
+			return 0, 0, RotationRolledBack, apiErr
+		}
+		event.CurrentValue, event.CurrentVersion = value, version
+	}
+
+	generateRequest := GenerateCredentialsRequest{
+		DeploymentID:     config.DeploymentID,
+		CredentialFormat: config.CredentialFormat,
+	}
+	value, apiErr := generateRequest.GenerateCredentials()
+	if apiErr != nil {
+		return event.CurrentVersion, 0, RotationRolledBack, apiErr
+	}
+	event.PendingValue = value
+
+	storeRequest := StoreSecretRequest{
+		SecretSpec:  config.SecretSpec,
+		SecretValue: value,
+		Overwrite:   true,
+		Disabled:    true,
+	}
+	event.PendingVersion, apiErr = storeRequest.StoreSecretVersion()
+	if apiErr != nil {
+		return event.CurrentVersion, 0, RotationRolledBack, apiErr
+	}
+
+	discard := func(cause error) (int64, int64, RotationOutcome, error) {
+		destroyRequest := DestroySecretVersionRequest{SecretSpec: config.SecretSpec, Version: event.PendingVersion}
+		_, apiErr := destroyRequest.DestroyVersion()
+		if apiErr != nil {
+			return event.CurrentVersion, event.PendingVersion, RotationFailed, cause
+		}
+		return event.CurrentVersion, event.PendingVersion, RotationRolledBack, cause
+	}
+
+	err = config.Apply(ctx, event)
+	if err != nil {
+		return discard(err)
+	}
+
+	enableRequest := EnableSecretVersionRequest{SecretSpec: config.SecretSpec, Version: event.PendingVersion}
+	_, apiErr = enableRequest.EnableVersion()
+	if apiErr != nil {
+		if config.Revert == nil || config.Revert(ctx, event) != nil {
+			return event.CurrentVersion, event.PendingVersion, RotationFailed, apiErr
+		}
+		return discard(apiErr)
+	}
+
+	return event.CurrentVersion, event.PendingVersion, RotationSucceeded, nil
+}
+
+// latestVersion returns the newest enabled version of a secret, or a zero version if the secret
+// has none
+func latestVersion(spec SecretSpec) (SecretVersion, error) {
+
+	listRequest := ListSecretVersionsRequest{SecretSpec: spec}
+	versions, apiErr := listRequest.List()
+	if apiErr != nil {
+		return SecretVersion{}, apiErr
+	}
+
+	latest := SecretVersion{}
+	for _, version := range versions {
+		if version.State == VersionEnabled && version.Version > latest.Version {
+			latest = version
+		}
+	}
+
+	return latest, nil
+}
+
+// specID identifies a secret within a Rotator
+func specID(spec SecretSpec) string {
+	return spec.DeploymentID + "/" + spec.SecretKey
+}
@@ -0,0 +1,411 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+var rotationStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeVersion is a version of the secret held by fakeSecrets
+type fakeVersion struct {
+	SecretVersion
+	value string
+}
+
+// fakeSecrets is a platform stand-in holding the versions of a single secret
+type fakeSecrets struct {
+	mutex    sync.Mutex
+	now      time.Time
+	versions []fakeVersion
+	// failEnable rejects enabling versions with status code 500
+	failEnable bool
+	generated  int
+}
+
+func startFakeSecrets(t *testing.T) *fakeSecrets {
+
+	fake := &fakeSecrets{now: rotationStart}
+	server := platformtest.Start(t)
+
+	server.Handle("SecretsListVersions", func(json.RawMessage) interface{} {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		response := ListSecretVersionsResponse{APIResponse: platformtest.OK()}
+		for _, version := range fake.versions {
+			response.Versions = append(response.Versions, version.SecretVersion)
+		}
+		return response
+	})
+
+	server.Handle("SecretsRetrieve", func(json.RawMessage) interface{} {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		response := RetrieveSecretResponse{APIResponse: platformtest.OK()}
+		for _, version := range fake.versions {
+			if version.State == VersionEnabled && version.Version > response.Version {
+				response.Secret, response.Version = version.value, version.Version
+			}
+		}
+		return response
+	})
+
+	server.Handle("SecretsGenerateCredentials", func(json.RawMessage) interface{} {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		fake.generated++
+		return GenerateCredentialsResponse{
+			APIResponse: platformtest.OK(), Secret: "generated-" + strconv.Itoa(fake.generated),
+		}
+	})
+
+	server.Handle("SecretsStore", func(body json.RawMessage) interface{} {
+		request := StoreSecretRequest{}
+		json.Unmarshal(body, &request)
+
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		state := VersionEnabled
+		if request.Disabled {
+			state = VersionDisabled
+		}
+		version := int64(len(fake.versions) + 1)
+		fake.versions = append(fake.versions, fakeVersion{
+			SecretVersion: SecretVersion{Version: version, State: state, CreatedAt: fake.now},
+			value:         request.SecretValue,
+		})
+		return StoreSecretResponse{APIResponse: platformtest.OK(), Version: version}
+	})
+
+	changeState := func(state VersionState) platformtest.HandlerFunc {
+		return func(body json.RawMessage) interface{} {
+			request := SecretVersionRequest{}
+			json.Unmarshal(body, &request)
+
+			fake.mutex.Lock()
+			defer fake.mutex.Unlock()
+			if state == VersionEnabled && fake.failEnable {
+				return platformtest.Fail("500", "enable failed")
+			}
+			if request.Version < 1 || request.Version > int64(len(fake.versions)) {
+				return platformtest.Fail("404", "version not found")
+			}
+			fake.versions[request.Version-1].State = state
+			return SecretVersionResponse{APIResponse: platformtest.OK()}
+		}
+	}
+	server.Handle("SecretsEnableVersion", changeState(VersionEnabled))
+	server.Handle("SecretsDisableVersion", changeState(VersionDisabled))
+	server.Handle("SecretsDestroyVersion", changeState(VersionDestroyed))
+
+	return fake
+}
+
+// store adds an enabled version created at the given time
+func (fake *fakeSecrets) store(value string, createdAt time.Time) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.versions = append(fake.versions, fakeVersion{
+		SecretVersion: SecretVersion{Version: int64(len(fake.versions) + 1), State: VersionEnabled, CreatedAt: createdAt},
+		value:         value,
+	})
+}
+
+// states returns the state of every version, oldest first
+func (fake *fakeSecrets) states() []VersionState {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	states := []VersionState{}
+	for _, version := range fake.versions {
+		states = append(states, version.State)
+	}
+	return states
+}
+
+func (fake *fakeSecrets) setNow(now time.Time) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.now = now
+}
+
+// fakeHook records the events it is called with and returns err
+type fakeHook struct {
+	events []RotationEvent
+	err    error
+}
+
+func (hook *fakeHook) apply(ctx context.Context, event RotationEvent) error {
+	hook.events = append(hook.events, event)
+	return hook.err
+}
+
+// newTestRotator returns a rotator with a clock at rotationStart and the secret registered
+func newTestRotator(t *testing.T, apply, revert RotationHook, audit *[]AuditRecord) (*Rotator, *time.Time) {
+
+	now := rotationStart
+	rotator := NewRotator(AuditFunc(func(record AuditRecord) error {
+		*audit = append(*audit, record)
+		return nil
+	}))
+	rotator.Now = func() time.Time { return now }
+
+	err := rotator.Register(RotationConfig{
+		SecretSpec: SecretSpec{SecretKey: "database"},
+		Interval:   24 * time.Hour,
+		Apply:      apply,
+		Revert:     revert,
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return rotator, &now
+}
+
+func TestRotatorRotate(t *testing.T) {
+
+	fake := startFakeSecrets(t)
+	fake.store("initial", rotationStart.Add(-48*time.Hour))
+	apply := &fakeHook{}
+	audit := []AuditRecord{}
+	rotator, _ := newTestRotator(t, apply.apply, nil, &audit)
+
+	record, err := rotator.Rotate(context.Background(), SecretSpec{SecretKey: "database"})
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if record.Outcome != RotationSucceeded || record.PreviousVersion != 1 || record.NewVersion != 2 {
+		t.Fatalf("record %+v, want version 2 replacing version 1", record)
+	}
+	if len(audit) != 1 || audit[0] != record {
+		t.Fatalf("audited %+v, want the returned record", audit)
+	}
+
+	want := RotationEvent{
+		SecretSpec:     SecretSpec{SecretKey: "database"},
+		CurrentVersion: 1, CurrentValue: "initial",
+		PendingVersion: 2, PendingValue: "generated-1",
+	}
+	if len(apply.events) != 1 || apply.events[0] != want {
+		t.Fatalf("Apply called with %+v, want %+v", apply.events, want)
+	}
+
+	states := fake.states()
+	if len(states) != 2 || states[0] != VersionEnabled || states[1] != VersionEnabled {
+		t.Fatalf("version states %v, want the new version enabled", states)
+	}
+
+	next, _ := rotator.NextRotation(SecretSpec{SecretKey: "database"})
+	if !next.Equal(rotationStart.Add(24 * time.Hour)) {
+		t.Fatalf("next rotation at %v, want one interval later", next)
+	}
+}
+
+func TestRotatorRollsBackFailedApply(t *testing.T) {
+
+	fake := startFakeSecrets(t)
+	fake.store("initial", rotationStart.Add(-48*time.Hour))
+	applyErr := errors.New("database unavailable")
+	apply := &fakeHook{err: applyErr}
+	audit := []AuditRecord{}
+	rotator, _ := newTestRotator(t, apply.apply, nil, &audit)
+
+	record, err := rotator.Rotate(context.Background(), SecretSpec{SecretKey: "database"})
+	if !errors.Is(err, applyErr) {
+		t.Fatalf("Rotate error = %v, want the Apply error", err)
+	}
+	if record.Outcome != RotationRolledBack || record.Err != applyErr.Error() || len(audit) != 1 {
+		t.Fatalf("record %+v, want a rolled back rotation audited", record)
+	}
+
+	states := fake.states()
+	if len(states) != 2 || states[0] != VersionEnabled || states[1] != VersionDestroyed {
+		t.Fatalf("version states %v, want the pending version destroyed and the current one kept", states)
+	}
+}
+
+func TestRotatorRevertsFailedEnable(t *testing.T) {
+
+	revertErr := errors.New("revert failed")
+
+	tests := []struct {
+		name      string
+		revert    *fakeHook
+		outcome   RotationOutcome
+		destroyed bool
+	}{
+		{"no revert", nil, RotationFailed, false},
+		{"reverted", &fakeHook{}, RotationRolledBack, true},
+		{"revert failed", &fakeHook{err: revertErr}, RotationFailed, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			fake := startFakeSecrets(t)
+			fake.store("initial", rotationStart.Add(-48*time.Hour))
+			fake.failEnable = true
+
+			var revert RotationHook
+			if test.revert != nil {
+				revert = test.revert.apply
+			}
+			apply := &fakeHook{}
+			audit := []AuditRecord{}
+			rotator, _ := newTestRotator(t, apply.apply, revert, &audit)
+
+			record, err := rotator.Rotate(context.Background(), SecretSpec{SecretKey: "database"})
+			if err == nil || record.Outcome != test.outcome {
+				t.Fatalf("Rotate = %+v, %v, want outcome %s", record, err, test.outcome)
+			}
+			if test.revert != nil && (len(test.revert.events) != 1 || test.revert.events[0] != apply.events[0]) {
+				t.Fatalf("Revert called with %+v, want the applied event", test.revert.events)
+			}
+
+			states := fake.states()
+			if destroyed := states[1] == VersionDestroyed; destroyed != test.destroyed || states[0] != VersionEnabled {
+				t.Fatalf("version states %v, want the pending version destroyed: %v", states, test.destroyed)
+			}
+		})
+	}
+}
+
+func TestRotatorBacksOffFailedRotations(t *testing.T) {
+
+	fake := startFakeSecrets(t)
+	fake.store("initial", rotationStart.Add(-48*time.Hour))
+	apply := &fakeHook{err: errors.New("database unavailable")}
+	audit := []AuditRecord{}
+	rotator, now := newTestRotator(t, apply.apply, nil, &audit)
+	rotator.RetryBackoff = time.Minute
+	rotator.MaxRetryBackoff = 5 * time.Minute
+	spec := SecretSpec{SecretKey: "database"}
+
+	for i, backoff := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		rotator.rotateDue(context.Background())
+		if len(apply.events) != i+1 {
+			t.Fatalf("attempt %d: Apply called %d times, want %d", i+1, len(apply.events), i+1)
+		}
+		next, _ := rotator.NextRotation(spec)
+		if want := now.Add(backoff); !next.Equal(want) {
+			t.Fatalf("attempt %d: retry at %v, want after %v", i+1, next, backoff)
+		}
+
+		// not retried before the backoff elapses
+		*now = next.Add(-time.Second)
+		rotator.rotateDue(context.Background())
+		if len(apply.events) != i+1 {
+			t.Fatalf("attempt %d: retried before the backoff elapsed", i+1)
+		}
+		*now = next
+	}
+
+	apply.err = nil
+	rotator.rotateDue(context.Background())
+	next, _ := rotator.NextRotation(spec)
+	if len(audit) != 6 || audit[5].Outcome != RotationSucceeded || !next.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("next rotation at %v after %d attempts, want one interval after the success", next, len(audit))
+	}
+
+	// the backoff starts over after a success
+	*now = next
+	apply.err = errors.New("database unavailable")
+	rotator.rotateDue(context.Background())
+	next, _ = rotator.NextRotation(spec)
+	if !next.Equal(now.Add(time.Minute)) {
+		t.Fatalf("retry at %v, want the first backoff after a success", next)
+	}
+}
+
+func TestRotatorRetryBackoffDefaults(t *testing.T) {
+
+	rotator := &Rotator{}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, DefaultRetryBackoff},
+		{2, 2 * DefaultRetryBackoff},
+		{7, DefaultMaxRetryBackoff},
+		{1000, DefaultMaxRetryBackoff},
+	}
+
+	for _, test := range tests {
+		if got := rotator.retryBackoff(test.failures); got != test.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", test.failures, got, test.want)
+		}
+	}
+}
+
+// fakeLock is a RotationLock shared by rotators
+type fakeLock struct {
+	mutex sync.Mutex
+	held  bool
+	locks int
+}
+
+func (lock *fakeLock) TryLock(ctx context.Context, spec SecretSpec) (func(), bool, error) {
+
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+
+	if lock.held {
+		return nil, false, nil
+	}
+	lock.held = true
+	lock.locks++
+	return func() {
+		lock.mutex.Lock()
+		defer lock.mutex.Unlock()
+		lock.held = false
+	}, true, nil
+}
+
+func TestRotatorLock(t *testing.T) {
+
+	fake := startFakeSecrets(t)
+	fake.store("initial", rotationStart.Add(-48*time.Hour))
+	apply := &fakeHook{}
+	audit := []AuditRecord{}
+	rotator, now := newTestRotator(t, apply.apply, nil, &audit)
+	lock := &fakeLock{}
+	rotator.Lock = lock
+	spec := SecretSpec{SecretKey: "database"}
+
+	// another replica holds the lock
+	lock.held = true
+	_, err := rotator.Rotate(context.Background(), spec)
+	if !errors.Is(err, ErrRotationInProgress) || len(apply.events) != 0 {
+		t.Fatalf("Rotate error = %v, want ErrRotationInProgress without applying", err)
+	}
+	lock.held = false
+
+	// another replica rotated after this one found the secret due
+	due, err := rotator.due(spec)
+	if err != nil || !due {
+		t.Fatalf("due = %v, %v, want the secret due", due, err)
+	}
+	fake.store("rotated elsewhere", *now)
+	rotator.rotateDue(context.Background())
+	if len(apply.events) != 0 || len(audit) != 0 {
+		t.Fatal("rotated a secret another replica had just rotated")
+	}
+	next, _ := rotator.NextRotation(spec)
+	if !next.Equal(now.Add(24 * time.Hour)) {
+		t.Fatalf("next rotation at %v, want one interval after the other rotation", next)
+	}
+
+	*now = next
+	fake.setNow(next)
+	rotator.rotateDue(context.Background())
+	if len(apply.events) != 1 || lock.held || lock.locks != 2 {
+		t.Fatalf("Apply called %d times with the lock held: %v, want one rotation under the lock",
+			len(apply.events), lock.held)
+	}
+}
@@ -29,19 +29,27 @@ type CredentialFormat struct {
 type StoreSecretRequest struct {
 	SecretSpec
 	// Secret is the conent to store.
-	SecretValue string `json:"secret_key"`
-	// Overwrite when set to true, will replace any existing secret
+	SecretValue string `json:"secret_value"`
+	// Overwrite when set to true, will store a new version over any existing secret.
+	// Previous versions are kept and can be retrieved or restored
 	Overwrite bool `json:"overwrite"`
+	// Disabled stores the new version disabled, so that it is not retrieved as the latest
+	// version until enabled
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 // StoreSecretResponse is the response from the store request
 type StoreSecretResponse struct {
 	common.APIResponse
+	// Version created by the store request
+	Version int64 `json:"version,omitempty"`
 }
 
 // RetrieveSecretRequest retrive stored secret
 type RetrieveSecretRequest struct {
 	SecretSpec
+	// Version to retrieve. Omit to retrieve the latest enabled version
+	Version int64 `json:"version,omitempty"`
 }
 
 // RetrieveSecretResponse is the response from the retrieval request
@@ -49,6 +57,8 @@ type RetrieveSecretResponse struct {
 	common.APIResponse
 	// Secret is the conent to store.
 	Secret string `json:"secret_value,omitempty"`
+	// Version of the retrieved secret
+	Version int64 `json:"version,omitempty"`
 }
 
 // DeleteSecretRequest retrive stored secret
@@ -111,6 +121,29 @@ func (storeSecretRequest *StoreSecretRequest) StoreSecret() (success bool, apiEr
 	return true, nil
 }
 
+// StoreSecretVersion stores secret in the secrets store and returns the version created
+func (storeSecretRequest *StoreSecretRequest) StoreSecretVersion() (version int64, apiErr *common.Error) {
+
+	storeSecretResponse := StoreSecretResponse{}
+	err := common.Execute("SecretsStore", storeSecretRequest, &storeSecretResponse)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if storeSecretResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        storeSecretResponse.StatusCode,
+			ErrorDescription: storeSecretResponse.Description,
+		}
+
+		return 0, apiErr
+	}
+
+	return storeSecretResponse.Version, nil
+}
+
 // RetrieveSecret retrieves secret that was previously stored in the secrets store
 func (retrieveSecretRequest *RetrieveSecretRequest) RetrieveSecret() (secret string, apiErr *common.Error) {
 
@@ -134,6 +167,29 @@ func (retrieveSecretRequest *RetrieveSecretRequest) RetrieveSecret() (secret str
 	return retrieveSecretResponse.Secret, nil
 }
 
+// RetrieveSecretVersion retrieves a stored secret along with its version
+func (retrieveSecretRequest *RetrieveSecretRequest) RetrieveSecretVersion() (secret string, version int64, apiErr *common.Error) {
+
+	retrieveSecretResponse := RetrieveSecretResponse{}
+	err := common.Execute("SecretsRetrieve", retrieveSecretRequest, &retrieveSecretResponse)
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	if retrieveSecretResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        retrieveSecretResponse.StatusCode,
+			ErrorDescription: retrieveSecretResponse.Description,
+		}
+
+		return "", 0, apiErr
+	}
+
+	return retrieveSecretResponse.Secret, retrieveSecretResponse.Version, nil
+}
+
 // DeleteSecret deletes stored secret
 func (deleteSecretRequest *DeleteSecretRequest) DeleteSecret() (success bool, apiErr *common.Error) {
 
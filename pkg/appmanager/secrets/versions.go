@@ -0,0 +1,129 @@
+package secrets
+
+import (
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// VersionState describes whether a secret version can be retrieved
+type VersionState string
+
+const (
+	// VersionEnabled versions can be retrieved
+	VersionEnabled VersionState = "Enabled"
+	// VersionDisabled versions cannot be retrieved until enabled again
+	VersionDisabled VersionState = "Disabled"
+	// VersionDestroyed versions have had their value erased permanently
+	VersionDestroyed VersionState = "Destroyed"
+)
+
+// SecretVersion is the metadata of a version of a secret, the value is never returned
+type SecretVersion struct {
+	// Version number, increasing with each store
+	Version int64 `json:"version"`
+	// State of the version
+	State VersionState `json:"state"`
+	// Time at which the version was stored
+	CreatedAt time.Time `json:"created_at"`
+	// Time at which the version was last disabled, enabled or destroyed
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	// Latest is true for the version retrieved when no version is given
+	Latest bool `json:"latest,omitempty"`
+}
+
+// ListSecretVersionsRequest is used to list the versions of a secret
+type ListSecretVersionsRequest struct {
+	SecretSpec
+}
+
+// ListSecretVersionsResponse is the list of versions of a secret
+type ListSecretVersionsResponse struct {
+	common.APIResponse
+	// Versions of the secret, newest first
+	Versions []SecretVersion `json:"versions,omitempty"`
+}
+
+// SecretVersionRequest identifies a single version of a secret
+type SecretVersionRequest struct {
+	SecretSpec
+	// Version of the secret
+	Version int64 `json:"version"`
+}
+
+// SecretVersionResponse is the response of a version state change
+type SecretVersionResponse struct {
+	common.APIResponse
+}
+
+// DisableSecretVersionRequest is used to stop a version from being retrieved. If the latest
+// version is disabled, the newest enabled version becomes the latest
+type DisableSecretVersionRequest SecretVersionRequest
+
+// EnableSecretVersionRequest is used to allow a disabled version to be retrieved again
+type EnableSecretVersionRequest SecretVersionRequest
+
+// DestroySecretVersionRequest is used to permanently erase the value of a version. The version
+// metadata is kept for auditing
+type DestroySecretVersionRequest SecretVersionRequest
+
+// List returns the versions of a secret
+func (listRequest *ListSecretVersionsRequest) List() (versions []SecretVersion, apiErr *common.Error) {
+
+	listResponse := ListSecretVersionsResponse{}
+	err := common.Execute("SecretsListVersions", listRequest, &listResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if listResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        listResponse.StatusCode,
+			ErrorDescription: listResponse.Description,
+		}
+
+		return nil, apiErr
+	}
+
+	return listResponse.Versions, nil
+}
+
+// DisableVersion disables a version of a secret
+func (disableRequest *DisableSecretVersionRequest) DisableVersion() (success bool, apiErr *common.Error) {
+	return changeVersion("SecretsDisableVersion", disableRequest)
+}
+
+// EnableVersion enables a disabled version of a secret
+func (enableRequest *EnableSecretVersionRequest) EnableVersion() (success bool, apiErr *common.Error) {
+	return changeVersion("SecretsEnableVersion", enableRequest)
+}
+
+// DestroyVersion destroys a version of a secret
+func (destroyRequest *DestroySecretVersionRequest) DestroyVersion() (success bool, apiErr *common.Error) {
+	return changeVersion("SecretsDestroyVersion", destroyRequest)
+}
+
+// changeVersion executes a version state change request
+func changeVersion(endpoint string, request interface{}) (success bool, apiErr *common.Error) {
+
+	versionResponse := SecretVersionResponse{}
+	err := common.Execute(endpoint, request, &versionResponse)
+
+	if err != nil {
+		return false, err
+	}
+
+	if versionResponse.StatusCode != "200" {
+
+		apiErr = &common.Error{
+			ErrorCode:        versionResponse.StatusCode,
+			ErrorDescription: versionResponse.Description,
+		}
+
+		return false, apiErr
+	}
+
+	return true, nil
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// Err* is used to throw errors while validating secret references
+var (
+	ErrMissingSecretKey        = errors.New("secret_ref is required")
+	ErrInvalidProjection       = errors.New("projection must be Env or File")
+	ErrMissingEnvVariable      = errors.New("env_variable is required for Env projections")
+	ErrDuplicateEnvVariable    = errors.New("env_variable is already set by another secret or env_variables")
+	ErrInvalidSecretPath       = errors.New("path must be absolute for File projections")
+	ErrDuplicateSecretPath     = errors.New("path is already used by another secret")
+	ErrInvalidRefreshPolicy    = errors.New("refresh_policy must be None, Reload or Restart")
+	ErrReloadRequiresFile      = errors.New("refresh_policy Reload is only valid for File projections")
+	ErrProjectionFieldMismatch = errors.New("env_variable and path cannot both be set")
+)
+
+// ValidateSecrets checks the secret references of the launch spec before it is sent, returning
+// an error naming the first invalid reference
+func (launchSpec *WorkerLaunchSpec) ValidateSecrets() error {
+	return ValidateSecretRefs(launchSpec.Secrets, launchSpec.EnvVariables)
+}
+
+// ValidateSecretRefs checks that secret references are complete and do not collide with each
+// other or with plain environment variables
+func ValidateSecretRefs(secrets []common.SecretRef, envVariables map[string]string) error {
+
+	envNames := map[string]bool{}
+	for name := range envVariables {
+		envNames[name] = true
+	}
+	paths := map[string]bool{}
+
+	for i, secret := range secrets {
+		err := validateSecretRef(secret, envNames, paths)
+		if err != nil {
+			return fmt.Errorf("secrets[%d] %q: %w", i, secret.SecretKey, err)
+		}
+	}
+
+	return nil
+}
+
+// validateSecretRef checks a single reference and records the names it uses
+func validateSecretRef(secret common.SecretRef, envNames, paths map[string]bool) error {
+
+	if secret.SecretKey == "" {
+		return ErrMissingSecretKey
+	}
+	if secret.EnvVariable != "" && secret.Path != "" {
+		return ErrProjectionFieldMismatch
+	}
+
+	switch secret.RefreshPolicy {
+	case "", common.SecretRefreshNone, common.SecretRefreshRestart:
+	case common.SecretRefreshReload:
+		if secret.Projection != common.SecretAsFile {
+			return ErrReloadRequiresFile
+		}
+	default:
+		return ErrInvalidRefreshPolicy
+	}
+
+	switch secret.Projection {
+	case common.SecretAsEnv:
+		if secret.EnvVariable == "" {
+			return ErrMissingEnvVariable
+		}
+		if envNames[secret.EnvVariable] {
+			return ErrDuplicateEnvVariable
+		}
+		envNames[secret.EnvVariable] = true
+	case common.SecretAsFile:
+		if !path.IsAbs(secret.Path) {
+			return ErrInvalidSecretPath
+		}
+		cleaned := path.Clean(secret.Path)
+		if paths[cleaned] {
+			return ErrDuplicateSecretPath
+		}
+		paths[cleaned] = true
+	default:
+		return ErrInvalidProjection
+	}
+
+	return nil
+}
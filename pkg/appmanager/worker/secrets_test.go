@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+func TestValidateSecretRefs(t *testing.T) {
+
+	env := func(key, variable string) common.SecretRef {
+		return common.SecretRef{SecretKey: key, Projection: common.SecretAsEnv, EnvVariable: variable}
+	}
+	file := func(key, path string) common.SecretRef {
+		return common.SecretRef{SecretKey: key, Projection: common.SecretAsFile, Path: path}
+	}
+	withPolicy := func(secret common.SecretRef, policy common.SecretRefreshPolicy) common.SecretRef {
+		secret.RefreshPolicy = policy
+		return secret
+	}
+
+	tests := []struct {
+		name         string
+		secrets      []common.SecretRef
+		envVariables map[string]string
+		want         error
+	}{
+		{"none", nil, nil, nil},
+		{"valid", []common.SecretRef{
+			env("database", "DATABASE_PASSWORD"),
+			withPolicy(env("token", "API_TOKEN"), common.SecretRefreshRestart),
+			withPolicy(file("tls", "/etc/tls/key.pem"), common.SecretRefreshReload),
+			withPolicy(file("ca", "/etc/tls/ca.pem"), common.SecretRefreshNone),
+		}, map[string]string{"LOG_LEVEL": "debug"}, nil},
+		{"missing key", []common.SecretRef{env("", "PASSWORD")}, nil, ErrMissingSecretKey},
+		{"missing projection", []common.SecretRef{{SecretKey: "database"}}, nil, ErrInvalidProjection},
+		{"unknown projection", []common.SecretRef{{SecretKey: "database", Projection: "Volume"}}, nil,
+			ErrInvalidProjection},
+		{"missing env variable", []common.SecretRef{env("database", "")}, nil, ErrMissingEnvVariable},
+		{"env variable used twice", []common.SecretRef{env("database", "PASSWORD"), env("token", "PASSWORD")}, nil,
+			ErrDuplicateEnvVariable},
+		{"env variable set in env_variables", []common.SecretRef{env("database", "PASSWORD")},
+			map[string]string{"PASSWORD": "plain"}, ErrDuplicateEnvVariable},
+		{"relative path", []common.SecretRef{file("tls", "tls/key.pem")}, nil, ErrInvalidSecretPath},
+		{"missing path", []common.SecretRef{file("tls", "")}, nil, ErrInvalidSecretPath},
+		{"path used twice", []common.SecretRef{file("tls", "/etc/tls/key.pem"), file("other", "/etc/tls/../tls/key.pem")},
+			nil, ErrDuplicateSecretPath},
+		{"env variable and path", []common.SecretRef{{
+			SecretKey: "database", Projection: common.SecretAsEnv, EnvVariable: "PASSWORD", Path: "/etc/password",
+		}}, nil, ErrProjectionFieldMismatch},
+		{"unknown refresh policy", []common.SecretRef{withPolicy(env("database", "PASSWORD"), "Rotate")}, nil,
+			ErrInvalidRefreshPolicy},
+		{"reload env variable", []common.SecretRef{withPolicy(env("database", "PASSWORD"), common.SecretRefreshReload)},
+			nil, ErrReloadRequiresFile},
+	}
+
+	for _, test := range tests {
+		err := ValidateSecretRefs(test.secrets, test.envVariables)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: ValidateSecretRefs error = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestValidateSecretRefsNamesReference(t *testing.T) {
+
+	secrets := []common.SecretRef{
+		{SecretKey: "database", Projection: common.SecretAsEnv, EnvVariable: "PASSWORD"},
+		{SecretKey: "tls", Projection: common.SecretAsFile, Path: "key.pem"},
+	}
+	err := ValidateSecretRefs(secrets, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), `secrets[1] "tls": `) {
+		t.Fatalf("ValidateSecretRefs error = %v, want it to name the second reference", err)
+	}
+
+	launchSpec := WorkerLaunchSpec{Secrets: secrets[:1], EnvVariables: map[string]string{"PASSWORD": "plain"}}
+	if err := launchSpec.ValidateSecrets(); !errors.Is(err, ErrDuplicateEnvVariable) {
+		t.Fatalf("ValidateSecrets error = %v, want ErrDuplicateEnvVariable", err)
+	}
+}
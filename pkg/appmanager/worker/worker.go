@@ -77,6 +77,9 @@ type WorkerLaunchSpec struct {
 	// EnvVariables is the list of environment variables set in the Worker. It is an optional field and the
 	// value is inherited from the parent WorkerGroup if none is specified.
 	EnvVariables map[string]string `json:"env_variables,omitempty"`
+	// Secrets are references to stored secrets projected into the Worker as environment variables or
+	// files. It is an optional field and the value is inherited from the parent WorkerGroup if none is specified.
+	Secrets []common.SecretRef `json:"secrets,omitempty"`
 	// Tags are the set of labels that are added to the Worker. It is an optional field and the value is
 	// inherited from the parent WorkerGroup if none is specified.
 	Tags map[string]string `json:"tags,omitempty"`
@@ -189,6 +192,9 @@ type UpdateRequest struct {
 	// EnvVariables is the list of environment variables set in the Worker. It is an optional field and the
 	// value is inherited from the parent WorkerGroup if none is specified.
 	EnvVariables map[string]string `json:"env_variables,omitempty"`
+	// Secrets are references to stored secrets projected into the Worker as environment variables or
+	// files. It is an optional field and the value is inherited from the parent WorkerGroup if none is specified.
+	Secrets []common.SecretRef `json:"secrets,omitempty"`
 	// Tags are the set of labels that are added to the Worker. It is an optional field and the value is
 	// inherited from the parent WorkerGroup if none is specified.
 	Tags map[string]string `json:"tags,omitempty"`
@@ -304,6 +310,10 @@ func (describeRequest *DescribeRequest) Describe() (workers []Worker, apiErr *co
 // Launch new worker group
 func (launchRequest *LaunchRequest) Launch() (workerIDs []string, requestID string, apiErr *common.Error) {
 
+	if err := launchRequest.ValidateSecrets(); err != nil {
+		return []string{}, "", &common.Error{ErrorCode: "400", ErrorDescription: err.Error()}
+	}
+
 	launchResponse := LaunchResponse{}
 	err := common.Execute("WorkerLaunch", launchRequest, &launchResponse)
 
@@ -488,6 +498,10 @@ func (updateResourcesRequest *UpdateResourcesRequest) UpdateResourceLimits() (re
 // Update worker specification
 func (updateRequest *UpdateRequest) Update() (requestID string, apiErr *common.Error) {
 
+	if err := ValidateSecretRefs(updateRequest.Secrets, updateRequest.EnvVariables); err != nil {
+		return "", &common.Error{ErrorCode: "400", ErrorDescription: err.Error()}
+	}
+
 	updateResponse := UpdateResponse{}
 	err := common.Execute("WorkerUpdate", updateRequest, &updateResponse)
 
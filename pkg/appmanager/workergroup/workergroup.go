@@ -283,6 +283,10 @@ type ScaleCallback struct {
 // Launch new worker group
 func (launchRequest *LaunchRequest) Launch() (workerGroupID, requestID string, apiErr *common.Error) {
 
+	if err := launchRequest.ValidateSecrets(); err != nil {
+		return "", "", &common.Error{ErrorCode: "400", ErrorDescription: err.Error()}
+	}
+
 	launchResponse := LaunchResponse{}
 	err := common.Execute("WorkerGroupLaunch", launchRequest, &launchResponse)
 
@@ -330,6 +334,10 @@ func (describeRequest *DescribeRequest) Describe() (workerGroups []WorkerGroup,
 // Update one or more properties of worker group
 func (updateRequest *UpdateRequest) Update() (requestID string, apiErr *common.Error) {
 
+	if err := updateRequest.ValidateSecrets(); err != nil {
+		return "", &common.Error{ErrorCode: "400", ErrorDescription: err.Error()}
+	}
+
 	updateResponse := UpdateResponse{}
 	err := common.Execute("WorkerGroupUpdate", updateRequest, &updateResponse)
 
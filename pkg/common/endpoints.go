@@ -41,10 +41,14 @@ func GetEndpoints(version Version) (Endpoint, error) {
 		"ApplicationInvoke": "/v1/application/invoke",
 
 		// Cron
-		"CronCreate": "/v1/cron/create",
-		"CronDelete": "/v1/cron/delete",
-		"CronList":   "/v1/cron/list",
-		"CronUpdate": "/v1/cron/update",
+		"CronCreate":   "/v1/cron/create",
+		"CronDelete":   "/v1/cron/delete",
+		"CronList":     "/v1/cron/list",
+		"CronListRuns": "/v1/cron/listruns",
+		"CronPause":    "/v1/cron/pause",
+		"CronResume":   "/v1/cron/resume",
+		"CronTrigger":  "/v1/cron/trigger",
+		"CronUpdate":   "/v1/cron/update",
 
 		// Database (ADB KV)
 		"DatabaseDelete": "/v1/database/delete",
@@ -61,11 +65,19 @@ func GetEndpoints(version Version) (Endpoint, error) {
 		"DeploymentTerminate": "/v1/deployment/terminate",
 
 		// Encryption
-		"EncryptionDecryptContent": "/v1/encryption/decryptcontent",
-		"EncryptionDecryptFile":    "/v1/encryption/decrypt",
-		"EncryptionDeleteKey":      "/v1/encryption/deleteencryptionkey",
-		"EncryptionEncryptContent": "/v1/encryption/encryptcontent",
-		"EncryptionEncryptFile":    "/v1/encryption/encrypt",
+		"EncryptionDecryptContent":   "/v1/encryption/decryptcontent",
+		"EncryptionDecryptData":      "/v1/encryption/decryptdata",
+		"EncryptionDecryptFile":      "/v1/encryption/decrypt",
+		"EncryptionDeleteKey":        "/v1/encryption/deleteencryptionkey",
+		"EncryptionDescribeKey":      "/v1/encryption/describekey",
+		"EncryptionEncryptContent":   "/v1/encryption/encryptcontent",
+		"EncryptionEncryptData":      "/v1/encryption/encryptdata",
+		"EncryptionEncryptFile":      "/v1/encryption/encrypt",
+		"EncryptionGenerateDataKey":  "/v1/encryption/generatedatakey",
+		"EncryptionListKeys":         "/v1/encryption/listkeys",
+		"EncryptionRotateKey":        "/v1/encryption/rotatekey",
+		"EncryptionScheduleRotation": "/v1/encryption/schedulerotation",
+		"EncryptionUnwrapDataKey":    "/v1/encryption/unwrapdatakey",
 
 		// Events
 		"EventSubscriptionsDelete": "/v1/subscriptions/delete",
@@ -106,16 +118,42 @@ func GetEndpoints(version Version) (Endpoint, error) {
 		"NotificationVerifyEmailID":        "/v1/notifications/verifyemailid",
 
 		// Object Store
-		"ObjectStoreDelete":   "/v1/objectstore/delete",
-		"ObjectStoreDescribe": "/v1/objectstore/describe",
-		"ObjectStoreFetch":    "/v1/objectstore/fetch",
-		"ObjectStoreList":     "/v1/objectstore/list",
-		"ObjectStorePost":     "/v1/objectstore/post",
+		"ObjectStoreCopy":           "/v1/objectstore/copy",
+		"ObjectStoreDelete":         "/v1/objectstore/delete",
+		"ObjectStoreDescribe":       "/v1/objectstore/describe",
+		"ObjectStoreFetch":          "/v1/objectstore/fetch",
+		"ObjectStoreList":           "/v1/objectstore/list",
+		"ObjectStoreListVersions":   "/v1/objectstore/listversions",
+		"ObjectStoreMove":           "/v1/objectstore/move",
+		"ObjectStorePost":           "/v1/objectstore/post",
+		"ObjectStorePurge":          "/v1/objectstore/purge",
+		"ObjectStoreReadRange":      "/v1/objectstore/readrange",
+		"ObjectStoreRestore":        "/v1/objectstore/restore",
+		"ObjectStoreUpdateMetadata": "/v1/objectstore/updatemetadata",
+
+		// Object Store Lifecycle
+		"ObjectStoreLifecycleCreate": "/v1/objectstore/lifecycle/create",
+		"ObjectStoreLifecycleDelete": "/v1/objectstore/lifecycle/delete",
+		"ObjectStoreLifecycleList":   "/v1/objectstore/lifecycle/list",
+		"ObjectStoreLifecycleUpdate": "/v1/objectstore/lifecycle/update",
+
+		// Object Store Multipart Uploads
+		"ObjectStoreMultipartAbort":       "/v1/objectstore/multipart/abort",
+		"ObjectStoreMultipartComplete":    "/v1/objectstore/multipart/complete",
+		"ObjectStoreMultipartInitiate":    "/v1/objectstore/multipart/initiate",
+		"ObjectStoreMultipartListParts":   "/v1/objectstore/multipart/listparts",
+		"ObjectStoreMultipartListUploads": "/v1/objectstore/multipart/listuploads",
+		"ObjectStoreMultipartUploadPart":  "/v1/objectstore/multipart/uploadpart",
 
 		// Secrets
 		"SecretsDelete":                   "/v1/secrets/delete",
+		"SecretsDestroyVersion":           "/v1/secrets/destroyversion",
+		"SecretsDisableVersion":           "/v1/secrets/disableversion",
+		"SecretsEnableVersion":            "/v1/secrets/enableversion",
 		"SecretsGenerateCredentials":      "/v1/secrets/generatecredentials",
 		"SecretsGenerateStoreCredentials": "/v1/secrets/generateandstorecredentials",
+		"SecretsList":                     "/v1/secrets/list",
+		"SecretsListVersions":             "/v1/secrets/listversions",
 		"SecretsRetrieve":                 "/v1/secrets/retrieve",
 		"SecretsStore":                    "/v1/secrets/store",
 
@@ -204,6 +204,49 @@ const (
 	RegexType CredentialType = "Regex"
 )
 
+// SecretProjection is how a referenced secret is exposed inside a worker
+type SecretProjection string
+
+const (
+	// SecretAsEnv projects the secret as an environment variable
+	SecretAsEnv SecretProjection = "Env"
+	// SecretAsFile projects the secret as a file mounted in the worker
+	SecretAsFile SecretProjection = "File"
+)
+
+// SecretRefreshPolicy is what happens to running workers when a referenced secret changes
+type SecretRefreshPolicy string
+
+const (
+	// SecretRefreshNone keeps the value resolved at worker start
+	SecretRefreshNone SecretRefreshPolicy = "None"
+	// SecretRefreshReload rewrites projected files in place, workers are expected to reload them.
+	// Only valid for file projections
+	SecretRefreshReload SecretRefreshPolicy = "Reload"
+	// SecretRefreshRestart restarts workers using the UpdateStrategy of their worker group
+	SecretRefreshRestart SecretRefreshPolicy = "Restart"
+)
+
+// SecretRef references a stored secret that the platform resolves when a worker starts, so that
+// the value never appears in launch specs or describe responses
+type SecretRef struct {
+	// SecretKey is the key of the secret in the deployment secrets store
+	SecretKey string `json:"secret_ref"`
+	// Version of the secret to project. Omit to project the latest version
+	Version int64 `json:"version,omitempty"`
+	// Projection is how the secret is exposed
+	Projection SecretProjection `json:"projection"`
+	// EnvVariable is the name of the environment variable for SecretAsEnv projections
+	EnvVariable string `json:"env_variable,omitempty"`
+	// Path is the absolute path of the file for SecretAsFile projections
+	Path string `json:"path,omitempty"`
+	// FileMode is the permission of the projected file, the platform uses 0400 if not provided
+	FileMode uint32 `json:"file_mode,omitempty"`
+	// RefreshPolicy applies when a new version becomes latest, defaults to SecretRefreshNone.
+	// Ignored when Version is set
+	RefreshPolicy SecretRefreshPolicy `json:"refresh_policy,omitempty"`
+}
+
 // Error errors returned from API server
 type Error struct {
 	// Error code
@@ -0,0 +1,126 @@
+// Package platformtest provides a stand-in for the platform API server, used to test requests
+// against canned responses without a platform deployment.
+package platformtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.semut.io/sdk/go-sdk/pkg/common"
+)
+
+// listenTimeout is the time Start waits for the platform address to be released by other test
+// binaries, since packages are tested in parallel
+const listenTimeout = 2 * time.Minute
+
+// HandlerFunc handles the JSON body of a request to an endpoint and returns the response to
+// encode, usually a struct embedding common.APIResponse
+type HandlerFunc func(request json.RawMessage) interface{}
+
+// Server is a stand-in platform API server listening on the address of common.GetPlatformURL.
+// Requests to endpoints without a handler are answered with status code 404.
+type Server struct {
+	mutex    sync.Mutex
+	handlers map[string]HandlerFunc
+	requests map[string][]json.RawMessage
+	server   *http.Server
+}
+
+// Start starts a stand-in server that is stopped when the test completes
+func Start(t *testing.T) *Server {
+
+	t.Helper()
+
+	address := strings.TrimPrefix(common.GetPlatformURL(), "http://")
+	deadline := time.Now().Add(listenTimeout)
+
+	var listener net.Listener
+	var err error
+	for {
+		listener, err = net.Listen("tcp", address)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("platformtest: listen on %s: %v", address, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	server := &Server{
+		handlers: map[string]HandlerFunc{},
+		requests: map[string][]json.RawMessage{},
+	}
+	server.server = &http.Server{Handler: http.HandlerFunc(server.serveHTTP)}
+	go server.server.Serve(listener)
+	t.Cleanup(func() { server.server.Close() })
+
+	return server
+}
+
+// Handle registers the handler of an endpoint, by the name used in common.Execute
+func (server *Server) Handle(endpoint string, handler HandlerFunc) {
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.handlers[endpointPath(endpoint)] = handler
+}
+
+// Requests returns the bodies of the requests received by an endpoint, in order
+func (server *Server) Requests(endpoint string) []json.RawMessage {
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return append([]json.RawMessage(nil), server.requests[endpointPath(endpoint)]...)
+}
+
+// OK is a successful API response
+func OK() common.APIResponse {
+	return common.APIResponse{StatusCode: "200", Description: "OK"}
+}
+
+// Fail is a failed API response
+func Fail(statusCode, description string) common.APIResponse {
+	return common.APIResponse{StatusCode: statusCode, Description: description}
+}
+
+// endpointPath returns the path of an endpoint in the version used by common.Execute
+func endpointPath(endpoint string) string {
+
+	endpoints, _ := common.GetEndpoints(common.GetSupportedVersions()[0])
+	path, ok := endpoints[endpoint]
+	if !ok {
+		panic("platformtest: unknown endpoint " + endpoint)
+	}
+
+	return path
+}
+
+// serveHTTP records the request and answers it with the handler of its path
+func (server *Server) serveHTTP(writer http.ResponseWriter, request *http.Request) {
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	server.mutex.Lock()
+	server.requests[request.URL.Path] = append(server.requests[request.URL.Path], body)
+	handler := server.handlers[request.URL.Path]
+	server.mutex.Unlock()
+
+	var response interface{} = Fail("404", "no handler for "+request.URL.Path)
+	if handler != nil {
+		response = handler(body)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(response)
+}
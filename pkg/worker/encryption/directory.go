@@ -0,0 +1,477 @@
+package encryption
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestFileName is the default name of the manifest written to the root of an encrypted directory
+const ManifestFileName = ".semut-encryption-manifest.json"
+
+// tempFilePrefix starts the names of the temporary files this package writes next to the files
+// it replaces. Like ManifestFileName it is reserved, files named with it are not encrypted
+const tempFilePrefix = ".semut-encryption-tmp-"
+
+// ManifestVersion1 is the version of the manifest format written by EncryptDirectory
+const ManifestVersion1 = 1
+
+// Err* is used to throw errors during directory encryption
+var (
+	ErrNotDirectory           = errors.New("path is not a directory")
+	ErrUnsupportedManifest    = errors.New("unsupported encryption manifest version")
+	ErrManifestOutsideRoot    = errors.New("manifest entry points outside the directory")
+	ErrDirectoryEncryptFailed = errors.New("one or more files failed to encrypt or decrypt")
+)
+
+// Replacements recorded in ManifestEntry.Pending
+const (
+	PendingEncryption = "encryption"
+	PendingDecryption = "decryption"
+)
+
+// ManifestEntry records the key a file was encrypted with
+type ManifestEntry struct {
+	// Key identifier the file is encrypted with
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier"`
+	// Time at which the file was encrypted
+	EncryptedAt time.Time `json:"encrypted_at"`
+	// Pending is set to PendingEncryption or PendingDecryption while the encrypted or decrypted
+	// copy of the file is renamed over it, and cleared once the manifest records the outcome
+	Pending string `json:"pending,omitempty"`
+	// PendingHash is the hex encoded SHA-256 of the pending copy. A run interrupted between the
+	// rename and the manifest update is resumed by comparing it with the file.
+	PendingHash string `json:"pending_hash,omitempty"`
+}
+
+// encrypted reports whether the file of the entry is encrypted, settling a pending
+// replacement by comparing the file with the pending copy
+func (entry ManifestEntry) encrypted(path string) (bool, error) {
+
+	if entry.Pending == "" {
+		return true, nil
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return false, err
+	}
+	replaced := hash == entry.PendingHash
+
+	if entry.Pending == PendingEncryption {
+		return replaced, nil
+	}
+	return !replaced, nil
+}
+
+// Manifest lists the encrypted files of a directory, keyed by slash separated path relative
+// to the directory
+type Manifest struct {
+	Version int                      `json:"version"`
+	Files   map[string]ManifestEntry `json:"files"`
+}
+
+// ReadManifest is used to load a manifest, an absent file yields an empty manifest
+func ReadManifest(path string) (*Manifest, error) {
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Manifest{Version: ManifestVersion1, Files: map[string]ManifestEntry{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(content, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Version != ManifestVersion1 {
+		return nil, ErrUnsupportedManifest
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]ManifestEntry{}
+	}
+
+	return manifest, nil
+}
+
+// write saves the manifest through a temporary file renamed over path
+func (manifest *Manifest) write(path string) (err error) {
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp, err := createTempFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(temp.Name())
+		}
+	}()
+
+	_, err = temp.Write(content)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+// settle clears the pending replacement of an entry whose file is known to be encrypted
+func (manifest *Manifest) settle(path, name string, entry ManifestEntry) error {
+
+	if entry.Pending == "" {
+		return nil
+	}
+
+	entry.Pending, entry.PendingHash = "", ""
+	manifest.Files[name] = entry
+	return manifest.write(path)
+}
+
+// DirectoryReport is the outcome of encrypting or decrypting a directory
+type DirectoryReport struct {
+	// Files processed by this run, as slash separated relative paths
+	Processed []string
+	// Files skipped because a previous run already processed them
+	Skipped []string
+	// Errors by relative path
+	Errors map[string]error
+}
+
+// EncryptDirectoryRequest is used to encrypt every file below a directory in place
+type EncryptDirectoryRequest struct {
+	// Directory to encrypt
+	Directory string
+	// Key identifier of an existing key to encrypt with. Omit to generate a new key for the
+	// first file and reuse it for the rest of the directory
+	EncryptionKeyIdentifier string
+	// ManifestPath is where the manifest is written, defaults to ManifestFileName in Directory
+	ManifestPath string
+}
+
+// EncryptDirectory encrypts each file below the directory, replacing it atomically so a file is
+// never left partially encrypted, and records its key in the manifest. The manifest lists a file
+// as pending before the encrypted copy replaces it, so that a file is never encrypted without
+// being recorded. Files already listed in the manifest are skipped, so an interrupted run can be
+// resumed by running it again.
+func (encryptRequest *EncryptDirectoryRequest) EncryptDirectory() (report DirectoryReport, err error) {
+
+	root, manifestPath, err := directoryPaths(encryptRequest.Directory, encryptRequest.ManifestPath)
+	if err != nil {
+		return DirectoryReport{}, err
+	}
+
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		return DirectoryReport{}, err
+	}
+
+	files, err := listFiles(root, manifestPath)
+	if err != nil {
+		return DirectoryReport{}, err
+	}
+
+	encryptionKeyIdentifier := encryptRequest.EncryptionKeyIdentifier
+	report.Errors = map[string]error{}
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+
+		if entry, ok := manifest.Files[name]; ok {
+			encrypted, err := entry.encrypted(path)
+			if err != nil {
+				report.Errors[name] = err
+				continue
+			}
+			if encrypted {
+				err = manifest.settle(manifestPath, name, entry)
+				if err != nil {
+					return report, err
+				}
+				report.Skipped = append(report.Skipped, name)
+				continue
+			}
+		}
+
+		var entry ManifestEntry
+		err := replaceFile(path, func(tempPath string) error {
+
+			usedKey, err := encryptFile(tempPath, encryptionKeyIdentifier)
+			if err != nil {
+				return err
+			}
+
+			hash, err := hashFile(tempPath)
+			if err != nil {
+				return err
+			}
+
+			entry = ManifestEntry{EncryptionKeyIdentifier: usedKey, EncryptedAt: time.Now().UTC()}
+			pending := entry
+			pending.Pending, pending.PendingHash = PendingEncryption, hash
+			manifest.Files[name] = pending
+			return manifest.write(manifestPath)
+		})
+		if err != nil {
+			// a pending entry left behind does not match the file, so the next run encrypts it again
+			report.Errors[name] = err
+			continue
+		}
+		if encryptionKeyIdentifier == "" {
+			encryptionKeyIdentifier = entry.EncryptionKeyIdentifier
+		}
+
+		manifest.Files[name] = entry
+		err = manifest.write(manifestPath)
+		if err != nil {
+			return report, err
+		}
+		report.Processed = append(report.Processed, name)
+	}
+
+	if len(report.Errors) != 0 {
+		return report, ErrDirectoryEncryptFailed
+	}
+
+	return report, nil
+}
+
+// DecryptDirectoryRequest is used to decrypt in place the files listed in a directory manifest
+type DecryptDirectoryRequest struct {
+	// Directory to decrypt
+	Directory string
+	// ManifestPath is where the manifest was written, defaults to ManifestFileName in Directory
+	ManifestPath string
+}
+
+// DecryptDirectory decrypts each file listed in the manifest with the key recorded for it and
+// removes it from the manifest. As with EncryptDirectory, the manifest records the decryption
+// as pending before the decrypted copy replaces the file, so an interrupted run can be resumed.
+// The manifest is deleted once every file is decrypted.
+func (decryptRequest *DecryptDirectoryRequest) DecryptDirectory() (report DirectoryReport, err error) {
+
+	root, manifestPath, err := directoryPaths(decryptRequest.Directory, decryptRequest.ManifestPath)
+	if err != nil {
+		return DirectoryReport{}, err
+	}
+
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		return DirectoryReport{}, err
+	}
+
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report.Errors = map[string]error{}
+	for _, name := range names {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if !withinRoot(root, path) {
+			report.Errors[name] = ErrManifestOutsideRoot
+			continue
+		}
+
+		entry := manifest.Files[name]
+		encrypted, err := entry.encrypted(path)
+		if err != nil {
+			report.Errors[name] = err
+			continue
+		}
+
+		if encrypted {
+			err = replaceFile(path, func(tempPath string) error {
+
+				err := decryptFile(tempPath, entry.EncryptionKeyIdentifier)
+				if err != nil {
+					return err
+				}
+
+				hash, err := hashFile(tempPath)
+				if err != nil {
+					return err
+				}
+
+				pending := entry
+				pending.Pending, pending.PendingHash = PendingDecryption, hash
+				manifest.Files[name] = pending
+				return manifest.write(manifestPath)
+			})
+			if err != nil {
+				report.Errors[name] = err
+				continue
+			}
+		}
+
+		delete(manifest.Files, name)
+		err = manifest.write(manifestPath)
+		if err != nil {
+			return report, err
+		}
+		report.Processed = append(report.Processed, name)
+	}
+
+	if len(report.Errors) != 0 {
+		return report, ErrDirectoryEncryptFailed
+	}
+
+	return report, os.Remove(manifestPath)
+}
+
+// EncryptFileAtomic encrypts a copy of the file and renames it over the original, so readers
+// see either the plaintext or the complete ciphertext
+func EncryptFileAtomic(path, encryptionKeyIdentifier string) (usedKey string, err error) {
+
+	err = replaceFile(path, func(tempPath string) error {
+		key, err := encryptFile(tempPath, encryptionKeyIdentifier)
+		usedKey = key
+		return err
+	})
+
+	return usedKey, err
+}
+
+// DecryptFileAtomic decrypts a copy of the file and renames it over the original, so readers
+// see either the ciphertext or the complete plaintext
+func DecryptFileAtomic(path, encryptionKeyIdentifier string) error {
+
+	return replaceFile(path, func(tempPath string) error {
+		return decryptFile(tempPath, encryptionKeyIdentifier)
+	})
+}
+
+// encryptFile encrypts a file in place through the platform and returns the key used
+func encryptFile(path, encryptionKeyIdentifier string) (string, error) {
+
+	encryptRequest := EncryptFileRequest{
+		FileOrDirectory:         path,
+		EncryptionKeyIdentifier: encryptionKeyIdentifier,
+	}
+	usedKey, apiErr := encryptRequest.EncryptFile()
+	if apiErr != nil {
+		return "", apiErr
+	}
+
+	return usedKey, nil
+}
+
+// decryptFile decrypts a file in place through the platform
+func decryptFile(path, encryptionKeyIdentifier string) error {
+
+	decryptRequest := DecryptFileRequest{
+		EncryptedFile:           path,
+		EncryptionKeyIdentifier: encryptionKeyIdentifier,
+	}
+	_, apiErr := decryptRequest.DecryptFile()
+	if apiErr != nil {
+		return apiErr
+	}
+
+	return nil
+}
+
+// hashFile is the hex encoded SHA-256 of the file content
+func hashFile(path string) (string, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// directoryPaths validates the directory and resolves the manifest path
+func directoryPaths(directory, manifestPath string) (root, manifest string, err error) {
+
+	root, err = filepath.Abs(directory)
+	if err != nil {
+		return "", "", err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", "", err
+	}
+	if !info.IsDir() {
+		return "", "", ErrNotDirectory
+	}
+
+	if manifestPath == "" {
+		return root, filepath.Join(root, ManifestFileName), nil
+	}
+
+	manifest, err = filepath.Abs(manifestPath)
+	return root, manifest, err
+}
+
+// listFiles returns the regular files below root as sorted slash separated relative paths,
+// leaving out the manifest and temporary files left by interrupted replacements
+func listFiles(root, manifestPath string) ([]string, error) {
+
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || path == manifestPath {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), tempFilePrefix) {
+			return nil
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relative))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// createTempFile creates a temporary file next to the file at path, to be renamed over it
+func createTempFile(path string) (*os.File, error) {
+	return ioutil.TempFile(filepath.Dir(path), tempFilePrefix+filepath.Base(path)+"-*")
+}
+
+// withinRoot reports whether path is inside root
+func withinRoot(root, path string) bool {
+	relative, err := filepath.Rel(root, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
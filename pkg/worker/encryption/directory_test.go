@@ -0,0 +1,401 @@
+package encryption
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+)
+
+// generatedKey is the key identifier the stand-in returns when no key is requested
+const generatedKey = "generated-key"
+
+// fakeEncryption is a platform stand-in encrypting worker files in place by prefixing their
+// content with the key identifier, either a single file or every file below a directory
+type fakeEncryption struct {
+	mutex       sync.Mutex
+	fail        map[string]bool
+	encryptions int
+	decryptions int
+}
+
+func startFakeEncryption(t *testing.T) *fakeEncryption {
+
+	fake := &fakeEncryption{fail: map[string]bool{}}
+	server := platformtest.Start(t)
+
+	server.Handle("EncryptionEncryptFile", func(body json.RawMessage) interface{} {
+		request := EncryptFileRequest{}
+		json.Unmarshal(body, &request)
+
+		key := request.EncryptionKeyIdentifier
+		if key == "" {
+			key = generatedKey
+		}
+
+		err := fake.each(request.FileOrDirectory, func(path string, content []byte) ([]byte, error) {
+			fake.encryptions++
+			return append([]byte(sealPrefix(key)), content...), nil
+		})
+		if err != nil {
+			return platformtest.Fail("500", err.Error())
+		}
+		return EncryptFileResponse{APIResponse: platformtest.OK(), EncryptionKeyIdentifier: key}
+	})
+
+	server.Handle("EncryptionDecryptFile", func(body json.RawMessage) interface{} {
+		request := DecryptFileRequest{}
+		json.Unmarshal(body, &request)
+
+		err := fake.each(request.EncryptedFile, func(path string, content []byte) ([]byte, error) {
+			prefix := sealPrefix(request.EncryptionKeyIdentifier)
+			if !strings.HasPrefix(string(content), prefix) {
+				return nil, errors.New("file is not encrypted with " + request.EncryptionKeyIdentifier)
+			}
+			fake.decryptions++
+			return content[len(prefix):], nil
+		})
+		if err != nil {
+			return platformtest.Fail("400", err.Error())
+		}
+		return DecryptFileResponse{APIResponse: platformtest.OK()}
+	})
+
+	return fake
+}
+
+// each transforms the file, or every file below the directory, at path
+func (fake *fakeEncryption) each(path string, transform func(path string, content []byte) ([]byte, error)) error {
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	return filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		// temporary copies are named after the file they replace
+		for name := range fake.fail {
+			if strings.HasPrefix(strings.TrimPrefix(info.Name(), tempFilePrefix), name) {
+				return errors.New("failed to process " + name)
+			}
+		}
+
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		content, err = transform(filePath, content)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filePath, content, info.Mode())
+	})
+}
+
+func (fake *fakeEncryption) setFail(name string, fail bool) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.fail[name] = fail
+	if !fail {
+		delete(fake.fail, name)
+	}
+}
+
+func sealPrefix(key string) string {
+	return "sealed(" + key + "):"
+}
+
+// writeFiles creates files below dir from a map of slash separated relative path to content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(path, []byte(content), 0640)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readFile returns the content of a file below dir
+func readFile(t *testing.T, dir, name string) string {
+	content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+var testFiles = map[string]string{
+	"a.txt":          "alpha",
+	"nested/b.txt":   "bravo",
+	"nested/c/d.txt": "delta",
+}
+
+func TestEncryptFileModes(t *testing.T) {
+
+	startFakeEncryption(t)
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, testFiles)
+
+		encryptRequest := EncryptFileRequest{FileOrDirectory: filepath.Join(dir, "a.txt")}
+		key, apiErr := encryptRequest.EncryptFile()
+		if apiErr != nil {
+			t.Fatalf("EncryptFile: %v", apiErr)
+		}
+		if key != generatedKey {
+			t.Fatalf("key = %q, want %q", key, generatedKey)
+		}
+		if got := readFile(t, dir, "a.txt"); got != sealPrefix(key)+"alpha" {
+			t.Fatalf("a.txt = %q, want it encrypted", got)
+		}
+		if got := readFile(t, dir, "nested/b.txt"); got != "bravo" {
+			t.Fatalf("nested/b.txt = %q, want it untouched", got)
+		}
+
+		decryptRequest := DecryptFileRequest{EncryptedFile: filepath.Join(dir, "a.txt"), EncryptionKeyIdentifier: key}
+		_, apiErr = decryptRequest.DecryptFile()
+		if apiErr != nil {
+			t.Fatalf("DecryptFile: %v", apiErr)
+		}
+		if got := readFile(t, dir, "a.txt"); got != "alpha" {
+			t.Fatalf("a.txt = %q, want it decrypted", got)
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, testFiles)
+
+		encryptRequest := EncryptFileRequest{FileOrDirectory: dir, EncryptionKeyIdentifier: "key-1"}
+		key, apiErr := encryptRequest.EncryptFile()
+		if apiErr != nil {
+			t.Fatalf("EncryptFile: %v", apiErr)
+		}
+		if key != "key-1" {
+			t.Fatalf("key = %q, want key-1", key)
+		}
+
+		for name, content := range testFiles {
+			if got := readFile(t, dir, name); got != sealPrefix(key)+content {
+				t.Fatalf("%s = %q, want it encrypted", name, got)
+			}
+
+			decryptRequest := DecryptFileRequest{
+				EncryptedFile:           filepath.Join(dir, filepath.FromSlash(name)),
+				EncryptionKeyIdentifier: key,
+			}
+			_, apiErr = decryptRequest.DecryptFile()
+			if apiErr != nil {
+				t.Fatalf("DecryptFile %s: %v", name, apiErr)
+			}
+			if got := readFile(t, dir, name); got != content {
+				t.Fatalf("%s = %q, want it decrypted", name, got)
+			}
+		}
+	})
+}
+
+func TestEncryptDirectoryRoundTrip(t *testing.T) {
+
+	fake := startFakeEncryption(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, testFiles)
+
+	encryptRequest := EncryptDirectoryRequest{Directory: dir}
+	report, err := encryptRequest.EncryptDirectory()
+	if err != nil {
+		t.Fatalf("EncryptDirectory: %v", err)
+	}
+	if len(report.Processed) != len(testFiles) {
+		t.Fatalf("processed %v, want every file", report.Processed)
+	}
+
+	manifest, err := ReadManifest(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range testFiles {
+		entry := manifest.Files[name]
+		if entry.EncryptionKeyIdentifier != generatedKey || entry.Pending != "" {
+			t.Fatalf("manifest entry of %s = %+v, want settled with the generated key", name, entry)
+		}
+		if got := readFile(t, dir, name); got != sealPrefix(generatedKey)+content {
+			t.Fatalf("%s = %q, want it encrypted once", name, got)
+		}
+	}
+
+	decryptRequest := DecryptDirectoryRequest{Directory: dir}
+	report, err = decryptRequest.DecryptDirectory()
+	if err != nil {
+		t.Fatalf("DecryptDirectory: %v", err)
+	}
+	for name, content := range testFiles {
+		if got := readFile(t, dir, name); got != content {
+			t.Fatalf("%s = %q, want it decrypted", name, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ManifestFileName)); !os.IsNotExist(err) {
+		t.Fatalf("manifest still present after decryption: %v", err)
+	}
+	if fake.encryptions != len(testFiles) || fake.decryptions != len(testFiles) {
+		t.Fatalf("%d encryptions and %d decryptions, want %d of each", fake.encryptions, fake.decryptions,
+			len(testFiles))
+	}
+}
+
+func TestEncryptDirectoryResume(t *testing.T) {
+
+	fake := startFakeEncryption(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, testFiles)
+
+	fake.setFail("b.txt", true)
+	encryptRequest := EncryptDirectoryRequest{Directory: dir, EncryptionKeyIdentifier: "key-1"}
+	report, err := encryptRequest.EncryptDirectory()
+	if !errors.Is(err, ErrDirectoryEncryptFailed) {
+		t.Fatalf("EncryptDirectory error = %v, want ErrDirectoryEncryptFailed", err)
+	}
+	if report.Errors["nested/b.txt"] == nil || len(report.Processed) != 2 {
+		t.Fatalf("report = %+v, want nested/b.txt failed and the others processed", report)
+	}
+	if got := readFile(t, dir, "nested/b.txt"); got != "bravo" {
+		t.Fatalf("failed file = %q, want it left untouched", got)
+	}
+
+	fake.setFail("b.txt", false)
+	report, err = encryptRequest.EncryptDirectory()
+	if err != nil {
+		t.Fatalf("EncryptDirectory: %v", err)
+	}
+	if strings.Join(report.Processed, ",") != "nested/b.txt" || len(report.Skipped) != 2 {
+		t.Fatalf("report = %+v, want only nested/b.txt processed", report)
+	}
+	for name, content := range testFiles {
+		if got := readFile(t, dir, name); got != sealPrefix("key-1")+content {
+			t.Fatalf("%s = %q, want it encrypted once", name, got)
+		}
+	}
+}
+
+func TestEncryptDirectoryInterruptedReplacement(t *testing.T) {
+
+	startFakeEncryption(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"renamed.txt":     sealPrefix("key-1") + "renamed",
+		"not-renamed.txt": "not renamed",
+	})
+
+	renamedHash, err := hashFile(filepath.Join(dir, "renamed.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	manifest := &Manifest{Version: ManifestVersion1, Files: map[string]ManifestEntry{
+		// interrupted after the encrypted copy was renamed over the file
+		"renamed.txt": {EncryptionKeyIdentifier: "key-1", Pending: PendingEncryption, PendingHash: renamedHash},
+		// interrupted before the encrypted copy was renamed over the file
+		"not-renamed.txt": {EncryptionKeyIdentifier: "key-1", Pending: PendingEncryption, PendingHash: "lost copy"},
+	}}
+	err = manifest.write(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptRequest := EncryptDirectoryRequest{Directory: dir, EncryptionKeyIdentifier: "key-1"}
+	report, err := encryptRequest.EncryptDirectory()
+	if err != nil {
+		t.Fatalf("EncryptDirectory: %v", err)
+	}
+	if strings.Join(report.Processed, ",") != "not-renamed.txt" || strings.Join(report.Skipped, ",") != "renamed.txt" {
+		t.Fatalf("report = %+v, want not-renamed.txt processed and renamed.txt skipped", report)
+	}
+
+	for name, content := range map[string]string{"renamed.txt": "renamed", "not-renamed.txt": "not renamed"} {
+		if got := readFile(t, dir, name); got != sealPrefix("key-1")+content {
+			t.Fatalf("%s = %q, want it encrypted once", name, got)
+		}
+	}
+
+	manifest, err = ReadManifest(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, entry := range manifest.Files {
+		if entry.Pending != "" || entry.PendingHash != "" {
+			t.Fatalf("manifest entry of %s = %+v, want it settled", name, entry)
+		}
+	}
+}
+
+func TestDecryptDirectoryInterruptedReplacement(t *testing.T) {
+
+	fake := startFakeEncryption(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "alpha"})
+
+	plainHash, err := hashFile(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	manifest := &Manifest{Version: ManifestVersion1, Files: map[string]ManifestEntry{
+		// interrupted after the decrypted copy was renamed over the file
+		"a.txt": {EncryptionKeyIdentifier: "key-1", Pending: PendingDecryption, PendingHash: plainHash},
+	}}
+	err = manifest.write(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptRequest := DecryptDirectoryRequest{Directory: dir}
+	_, err = decryptRequest.DecryptDirectory()
+	if err != nil {
+		t.Fatalf("DecryptDirectory: %v", err)
+	}
+	if fake.decryptions != 0 {
+		t.Fatalf("decrypted %d files, want the decrypted file left alone", fake.decryptions)
+	}
+	if got := readFile(t, dir, "a.txt"); got != "alpha" {
+		t.Fatalf("a.txt = %q, want alpha", got)
+	}
+	if _, err := os.Stat(manifestPath); !os.IsNotExist(err) {
+		t.Fatalf("manifest still present after decryption: %v", err)
+	}
+}
+
+func TestEncryptDirectoryTempFileNames(t *testing.T) {
+
+	startFakeEncryption(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".notes.tmp-1":                     "notes",
+		"nested/.cache.tmp-data":           "cache",
+		tempFilePrefix + "a.txt-12345":     "leftover",
+		"nested/" + tempFilePrefix + "b-1": "leftover",
+	})
+
+	encryptRequest := EncryptDirectoryRequest{Directory: dir}
+	report, err := encryptRequest.EncryptDirectory()
+	if err != nil {
+		t.Fatalf("EncryptDirectory: %v", err)
+	}
+	if got := strings.Join(report.Processed, ","); got != ".notes.tmp-1,nested/.cache.tmp-data" {
+		t.Fatalf("processed %s, want the user files and not the leftover temporary files", got)
+	}
+	if got := readFile(t, dir, ".notes.tmp-1"); got != sealPrefix(generatedKey)+"notes" {
+		t.Fatalf(".notes.tmp-1 = %q, want it encrypted", got)
+	}
+}
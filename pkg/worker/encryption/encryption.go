@@ -10,6 +10,8 @@ import (
 type EncryptFileRequest struct {
 	// FileOrDirectory is the path of the file or directory that is to be encrypted
 	FileOrDirectory string `json:"file_or_directory"`
+	// Key identifier of an existing key to encrypt with. Omit to generate a new key
+	EncryptionKeyIdentifier string `json:"encryption_key_identifier,omitempty"`
 }
 
 // EncryptFileResponse is the response containing the encryption key after file is encrypted
@@ -60,7 +62,7 @@ func (encryptFileRequest *EncryptFileRequest) EncryptFile() (encryptionKeyIndent
 func (decryptFileRequest *DecryptFileRequest) DecryptFile() (success bool, apiErr *common.Error) {
 
 	decryptFileResponse := DecryptFileResponse{}
-	err := common.Execute("EncryptionEncryptFile", decryptFileRequest, &decryptFileResponse)
+	err := common.Execute("EncryptionDecryptFile", decryptFileRequest, &decryptFileResponse)
 
 	if err != nil {
 		return false, err
@@ -88,3 +90,23 @@ func (deleteRequest *DeleteEncryptionKeyIdentifierRequest) Delete() (success boo
 	success, apiErr = dr.DeleteEncryptionKeyIdentifier()
 	return
 }
+
+// EncryptDataRequest is used to request encryption of binary data
+type EncryptDataRequest appmgrEncryption.EncryptDataRequest
+
+// EncryptData is an alias of app manager encrypt data
+func (encryptRequest *EncryptDataRequest) EncryptData() (ciphertext []byte, encryptionKeyID string, apiErr *common.Error) {
+	er := (*appmgrEncryption.EncryptDataRequest)(encryptRequest)
+	ciphertext, encryptionKeyID, apiErr = er.EncryptData()
+	return
+}
+
+// DecryptDataRequest is used to request decryption of binary data
+type DecryptDataRequest appmgrEncryption.DecryptDataRequest
+
+// DecryptData is an alias of app manager decrypt data
+func (decryptRequest *DecryptDataRequest) DecryptData() (data []byte, apiErr *common.Error) {
+	dr := (*appmgrEncryption.DecryptDataRequest)(decryptRequest)
+	data, apiErr = dr.DecryptData()
+	return
+}
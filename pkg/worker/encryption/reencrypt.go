@@ -0,0 +1,110 @@
+package encryption
+
+import (
+	"context"
+	"io"
+	"os"
+
+	appmgrEncryption "go.semut.io/sdk/go-sdk/pkg/appmanager/encryption"
+)
+
+// FileTarget is an encrypted file on the worker, migrated to a new key. The file is copied,
+// re-encrypted and renamed over the original, so it is never left decrypted on failure.
+type FileTarget struct {
+	// Path of the encrypted file
+	Path string
+	// Key identifier the file is currently encrypted with
+	EncryptionKeyIdentifier string
+	// Store records the key identifier the file is now encrypted with
+	Store func(ctx context.Context, path, encryptionKeyIdentifier string) error
+}
+
+// ID returns the path of the file
+func (target *FileTarget) ID() string {
+	return target.Path
+}
+
+// ReEncrypt migrates the file to the new key
+func (target *FileTarget) ReEncrypt(ctx context.Context, keys *appmgrEncryption.ReEncryptKeys) error {
+
+	var encryptionKeyIdentifier string
+	err := replaceFile(target.Path, func(tempPath string) error {
+
+		decryptRequest := DecryptFileRequest{
+			EncryptedFile:           tempPath,
+			EncryptionKeyIdentifier: target.EncryptionKeyIdentifier,
+		}
+		_, apiErr := decryptRequest.DecryptFile()
+		if apiErr != nil {
+			return apiErr
+		}
+
+		encryptRequest := EncryptFileRequest{
+			FileOrDirectory:         tempPath,
+			EncryptionKeyIdentifier: keys.NewEncryptionKeyIdentifier,
+		}
+		encryptionKeyIdentifier, apiErr = encryptRequest.EncryptFile()
+		if apiErr != nil {
+			return apiErr
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if target.Store == nil {
+		return nil
+	}
+	return target.Store(ctx, target.Path, encryptionKeyIdentifier)
+}
+
+// replaceFile copies a file to a temporary file in the same directory, lets transform change
+// the copy and renames it over the original once transform succeeds
+func replaceFile(path string, transform func(tempPath string) error) (err error) {
+
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	temp, err := createTempFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(temp.Name())
+		}
+	}()
+
+	_, err = io.Copy(temp, source)
+	if err != nil {
+		temp.Close()
+		return err
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = transform(temp.Name())
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(temp.Name(), info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,128 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	appmgrObjectstore "go.semut.io/sdk/go-sdk/pkg/appmanager/objectstore"
+	"go.semut.io/sdk/go-sdk/pkg/worker/encryption"
+)
+
+// Err* is used to throw errors while downloading objects to the worker
+var (
+	ErrMissingDestination = errors.New("destination path is required")
+	ErrMissingObject      = errors.New("either object ID or full name is required")
+	ErrChecksumMismatch   = errors.New("downloaded content does not match object content hash")
+)
+
+// DownloadFileRequest is used to download an object to a file on the worker. The file is
+// written to a temporary file next to DestinationPath and only renamed into place once the
+// content has been verified, so readers never observe a partial file.
+type DownloadFileRequest struct {
+	// ID of the deployment owning the object
+	DeploymentID string
+	// ID of the object
+	ObjectID string
+	// Name of the object including its path
+	FullName string
+	// ID of the version to download. Omit to download the latest version
+	VersionID string
+	// DestinationPath is the path of the file to write on the worker
+	DestinationPath string
+	// SkipChecksum disables verification of the downloaded content against the object content hash
+	SkipChecksum bool
+	// KeepEncrypted stores encrypted objects as downloaded instead of decrypting them
+	KeepEncrypted bool
+	// Progress is called after each chunk is written
+	Progress func(appmgrObjectstore.Progress)
+}
+
+// DownloadFile downloads the object, decrypting it if it is stored with inbuilt encryption,
+// verifies its SHA-256 content hash and atomically writes it to DestinationPath. The described
+// object is returned.
+func (downloadRequest *DownloadFileRequest) DownloadFile(ctx context.Context) (object appmgrObjectstore.Object, err error) {
+
+	if downloadRequest.DestinationPath == "" {
+		return appmgrObjectstore.Object{}, ErrMissingDestination
+	}
+	if downloadRequest.ObjectID == "" && downloadRequest.FullName == "" {
+		return appmgrObjectstore.Object{}, ErrMissingObject
+	}
+
+	describeRequest := appmgrObjectstore.DescribeRequest{
+		DeploymentID: downloadRequest.DeploymentID,
+		ObjectID:     downloadRequest.ObjectID,
+		FullName:     downloadRequest.FullName,
+		VersionID:    downloadRequest.VersionID,
+	}
+	object, apiErr := describeRequest.Describe()
+	if apiErr != nil {
+		return appmgrObjectstore.Object{}, apiErr
+	}
+
+	decrypt := object.Encrypted && !downloadRequest.KeepEncrypted
+	verify := !downloadRequest.SkipChecksum && object.ContentHash != "" &&
+		(!object.Encrypted || decrypt)
+
+	err = writeFileAtomic(downloadRequest.DestinationPath, func(file *os.File) error {
+
+		hash := sha256.New()
+		var writer io.Writer = file
+		if !decrypt {
+			writer = io.MultiWriter(file, hash)
+		}
+
+		rangeRequest := appmgrObjectstore.DownloadRequest{
+			DeploymentID: downloadRequest.DeploymentID,
+			ObjectID:     object.ObjectID,
+			VersionID:    object.VersionID,
+			Progress:     downloadRequest.Progress,
+		}
+		_, err := rangeRequest.Download(ctx, writer)
+		if err != nil {
+			return err
+		}
+
+		if decrypt {
+			err = file.Sync()
+			if err != nil {
+				return err
+			}
+
+			decryptRequest := encryption.DecryptFileRequest{
+				EncryptedFile:           file.Name(),
+				EncryptionKeyIdentifier: object.EncryptionKeyIdentifier,
+			}
+			_, apiErr := decryptRequest.DecryptFile()
+			if apiErr != nil {
+				return apiErr
+			}
+
+			if verify {
+				sum, err := hashFile(file.Name())
+				if err != nil {
+					return err
+				}
+				if !strings.EqualFold(sum, object.ContentHash) {
+					return ErrChecksumMismatch
+				}
+			}
+			return nil
+		}
+
+		if verify && !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), object.ContentHash) {
+			return ErrChecksumMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		return appmgrObjectstore.Object{}, err
+	}
+
+	return object, nil
+}
@@ -0,0 +1,63 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadFileDecryptsEncryptedObjects(t *testing.T) {
+
+	store := startFakeStore(t)
+	store.put("models/secret.bin", "top secret", true)
+
+	destination := filepath.Join(t.TempDir(), "secret.bin")
+	downloadRequest := DownloadFileRequest{ObjectID: "id-models/secret.bin", DestinationPath: destination}
+	_, err := downloadRequest.DownloadFile(context.Background())
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+
+	content, err := ioutil.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "top secret" {
+		t.Fatalf("downloaded %q, want decrypted content", content)
+	}
+	if len(store.server.Requests("EncryptionDecryptFile")) != 1 || len(store.server.Requests("EncryptionEncryptFile")) != 0 {
+		t.Fatal("decryption did not go through the EncryptionDecryptFile endpoint")
+	}
+
+	keepRequest := DownloadFileRequest{ObjectID: "id-models/secret.bin", DestinationPath: destination, KeepEncrypted: true}
+	_, err = keepRequest.DownloadFile(context.Background())
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	content, _ = ioutil.ReadFile(destination)
+	if string(content) != encryptedPrefix+"top secret" {
+		t.Fatalf("downloaded %q, want the encrypted content kept", content)
+	}
+}
+
+func TestDownloadFileChecksumMismatch(t *testing.T) {
+
+	store := startFakeStore(t)
+	store.put("models/a.bin", "a", false)
+	store.mutex.Lock()
+	store.data["id-models/a.bin"] = []byte("corrupted")
+	store.mutex.Unlock()
+
+	destination := filepath.Join(t.TempDir(), "a.bin")
+	downloadRequest := DownloadFileRequest{ObjectID: "id-models/a.bin", DestinationPath: destination}
+	_, err := downloadRequest.DownloadFile(context.Background())
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("DownloadFile error = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Fatalf("destination written despite the mismatch: %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package objectstore
 
 import (
+	"context"
+	"io"
+
 	appmgrObjectstore "go.semut.io/sdk/go-sdk/pkg/appmanager/objectstore"
 	"go.semut.io/sdk/go-sdk/pkg/common"
 )
@@ -17,6 +20,12 @@ type ObjectRequest struct {
 	DestinationName string `json:"destination_name"`
 	// Encrypted whether object will be stored with inbuilt encryption or not
 	Encrypted bool `json:"encrypted"`
+	// MIME type of the object, detected from the content if omitted
+	ContentType string `json:"content_type,omitempty"`
+	// User defined metadata of the object
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// User defined tags of the object
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 // PostRequest uploads an object prsent in the worker
@@ -34,6 +43,8 @@ type PostResponse struct {
 // GetRequest downloads an object to a given path on the worker
 type GetRequest struct {
 	ObjectRequest
+	// ID of the version to download. Omit to download the latest version
+	VersionID string `json:"version_id,omitempty"`
 }
 
 // GetResponse is the response to get object request
@@ -64,11 +75,12 @@ func (postRequest *PostRequest) Post() (success bool, apiErr *common.Error) {
 	return true, nil
 }
 
-// Get is used to download an object to the worker
+// Get is used to have the platform download an object to the worker, use DownloadFileRequest
+// to download with checksum verification, atomic replacement and decryption
 func (getRequest *GetRequest) Get() (success bool, apiErr *common.Error) {
 
 	getResponse := GetResponse{}
-	err := common.Execute("ObjectStoreGet", getRequest, &getResponse)
+	err := common.Execute("ObjectStoreFetch", getRequest, &getResponse)
 
 	if err != nil {
 		return false, err
@@ -87,6 +99,46 @@ func (getRequest *GetRequest) Get() (success bool, apiErr *common.Error) {
 	return true, nil
 }
 
+// UpdateMetadataRequest is used to update metadata of an existing object from worker end
+type UpdateMetadataRequest appmgrObjectstore.UpdateMetadataRequest
+
+// UpdateMetadata is an alias of app manager update object metadata
+func (updateRequest *UpdateMetadataRequest) UpdateMetadata() (object appmgrObjectstore.Object, apiErr *common.Error) {
+	ur := (*appmgrObjectstore.UpdateMetadataRequest)(updateRequest)
+	object, apiErr = ur.UpdateMetadata()
+	return
+}
+
+// ListVersionsRequest is used to list versions of an object from worker end
+type ListVersionsRequest appmgrObjectstore.ListVersionsRequest
+
+// ListVersions is an alias of app manager list object versions
+func (listVersionsRequest *ListVersionsRequest) ListVersions() (versions []appmgrObjectstore.Object, apiErr *common.Error) {
+	lr := (*appmgrObjectstore.ListVersionsRequest)(listVersionsRequest)
+	versions, apiErr = lr.ListVersions()
+	return
+}
+
+// RestoreRequest is used to restore an object version or soft deleted object from worker end
+type RestoreRequest appmgrObjectstore.RestoreRequest
+
+// Restore is an alias of app manager restore object
+func (restoreRequest *RestoreRequest) Restore() (object appmgrObjectstore.Object, apiErr *common.Error) {
+	rr := (*appmgrObjectstore.RestoreRequest)(restoreRequest)
+	object, apiErr = rr.Restore()
+	return
+}
+
+// PurgeRequest is used to permanently remove soft deleted objects from worker end
+type PurgeRequest appmgrObjectstore.PurgeRequest
+
+// Purge is an alias of app manager purge objects
+func (purgeRequest *PurgeRequest) Purge() (purged int, apiErr *common.Error) {
+	pr := (*appmgrObjectstore.PurgeRequest)(purgeRequest)
+	purged, apiErr = pr.Purge()
+	return
+}
+
 // DeleteRequest is used to delete an existing object from worker end
 type DeleteRequest appmgrObjectstore.DeleteRequest
 
@@ -96,3 +148,23 @@ func (deleteRequest *DeleteRequest) Delete() (success bool, apiErr *common.Error
 	success, apiErr = dr.Delete()
 	return
 }
+
+// UploadRequest is used to upload an object from an io.Reader from worker end
+type UploadRequest appmgrObjectstore.UploadRequest
+
+// Upload is an alias of app manager multipart upload
+func (uploadRequest *UploadRequest) Upload(ctx context.Context, reader io.Reader) (objectID string, err error) {
+	ur := (*appmgrObjectstore.UploadRequest)(uploadRequest)
+	objectID, err = ur.Upload(ctx, reader)
+	return
+}
+
+// DownloadRequest is used to download an object to an io.Writer from worker end
+type DownloadRequest appmgrObjectstore.DownloadRequest
+
+// Download is an alias of app manager download
+func (downloadRequest *DownloadRequest) Download(ctx context.Context, writer io.Writer) (written int64, err error) {
+	dr := (*appmgrObjectstore.DownloadRequest)(downloadRequest)
+	written, err = dr.Download(ctx, writer)
+	return
+}
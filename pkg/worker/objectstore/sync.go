@@ -0,0 +1,542 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	appmgrObjectstore "go.semut.io/sdk/go-sdk/pkg/appmanager/objectstore"
+)
+
+// SyncDirection is the direction in which a directory is synchronised
+type SyncDirection string
+
+const (
+	// SyncToObjectStore mirrors the local directory into the object store
+	SyncToObjectStore SyncDirection = "ToObjectStore"
+	// SyncFromObjectStore mirrors the object store into the local directory
+	SyncFromObjectStore SyncDirection = "FromObjectStore"
+)
+
+// SyncActionType is the kind of change made to bring the destination in line with the source
+type SyncActionType string
+
+const (
+	// SyncUpload uploads a new or changed local file
+	SyncUpload SyncActionType = "Upload"
+	// SyncDownload downloads a new or changed object
+	SyncDownload SyncActionType = "Download"
+	// SyncDeleteObject deletes an object that no longer exists locally
+	SyncDeleteObject SyncActionType = "DeleteObject"
+	// SyncDeleteFile deletes a local file that no longer exists in the object store
+	SyncDeleteFile SyncActionType = "DeleteFile"
+)
+
+// DefaultSyncConcurrency is the number of parallel transfers used when SyncRequest leaves it unset
+const DefaultSyncConcurrency = 4
+
+// Err* is used to throw errors while synchronising directories
+var (
+	ErrInvalidSyncDirection = errors.New("invalid sync direction")
+	ErrSyncFailed           = errors.New("one or more sync actions failed")
+	ErrUnsafeObjectName     = errors.New("object name resolves outside the local directory")
+)
+
+// SyncAction is a single planned or performed change
+type SyncAction struct {
+	// Type of the change
+	Type SyncActionType
+	// RelativePath of the file, slash separated and relative to LocalDir and Prefix
+	RelativePath string
+	// FullName of the object
+	FullName string
+	// ObjectID of the object, empty for uploads of new files
+	ObjectID string
+	// Size of the file or object being transferred
+	Size int64
+	// Err is the error encountered while performing the action, nil on success or in dry runs
+	Err error
+}
+
+// SyncReport is the outcome of a sync
+type SyncReport struct {
+	// Actions planned, or performed if the sync was not a dry run
+	Actions []SyncAction
+	// Unchanged is the number of files found identical in source and destination
+	Unchanged int
+	// Rejected are the full names of objects skipped because their path relative to Prefix is
+	// absolute or contains a `..` segment, and so would resolve outside LocalDir
+	Rejected []string
+}
+
+// SyncRequest is used to mirror a directory on the worker with objects under a prefix
+type SyncRequest struct {
+	// ID of the deployment owning the objects
+	DeploymentID string
+	// LocalDir is the directory on the worker
+	LocalDir string
+	// Prefix is prepended to relative paths to form object full names
+	Prefix string
+	// Direction of the sync
+	Direction SyncDirection
+	// Include are glob patterns of relative paths to sync, `**` matches any number of
+	// directories. Omit to include all files
+	Include []string
+	// Exclude are glob patterns of relative paths to skip, applied after Include
+	Exclude []string
+	// Delete removes files from the destination that do not exist in the source
+	Delete bool
+	// Encrypted whether uploaded objects will be stored with inbuilt encryption or not
+	Encrypted bool
+	// Concurrency is the number of parallel transfers, defaults to DefaultSyncConcurrency
+	Concurrency int
+	// DryRun plans the actions without performing them
+	DryRun bool
+	// Progress is called after each action is performed
+	Progress func(SyncAction)
+}
+
+// localFile is a file found in the local directory
+type localFile struct {
+	path string
+	size int64
+}
+
+// Sync plans and, unless DryRun is set, performs the actions needed to mirror the source into
+// the destination. Files are compared by SHA-256 content hash. Failed actions and rejected
+// objects are reported in the returned report and cause ErrSyncFailed to be returned once all
+// other actions complete.
+func (syncRequest *SyncRequest) Sync(ctx context.Context) (report SyncReport, err error) {
+
+	if syncRequest.Direction != SyncToObjectStore && syncRequest.Direction != SyncFromObjectStore {
+		return SyncReport{}, ErrInvalidSyncDirection
+	}
+
+	report, err = syncRequest.plan()
+	if err != nil || syncRequest.DryRun {
+		return report, err
+	}
+
+	concurrency := syncRequest.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSyncConcurrency
+	}
+
+	indexes := make(chan int)
+	mutex := sync.Mutex{}
+	failed := false
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				action := &report.Actions[index]
+				action.Err = syncRequest.perform(ctx, *action)
+
+				mutex.Lock()
+				if action.Err != nil {
+					failed = true
+				}
+				if syncRequest.Progress != nil {
+					syncRequest.Progress(*action)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for index := range report.Actions {
+		if ctx.Err() != nil {
+			report.Actions[index].Err = ctx.Err()
+			mutex.Lock()
+			failed = true
+			mutex.Unlock()
+			continue
+		}
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	if failed || len(report.Rejected) != 0 {
+		return report, ErrSyncFailed
+	}
+
+	return report, nil
+}
+
+// plan compares the local directory with the object store and lists the required actions
+func (syncRequest *SyncRequest) plan() (report SyncReport, err error) {
+
+	localFiles, err := syncRequest.listLocal()
+	if err != nil {
+		return SyncReport{}, err
+	}
+
+	objects, rejected, err := syncRequest.listObjects()
+	if err != nil {
+		return SyncReport{}, err
+	}
+	report.Rejected = rejected
+
+	relativePaths := []string{}
+	for relativePath := range localFiles {
+		relativePaths = append(relativePaths, relativePath)
+	}
+	for relativePath := range objects {
+		if _, ok := localFiles[relativePath]; !ok {
+			relativePaths = append(relativePaths, relativePath)
+		}
+	}
+	sort.Strings(relativePaths)
+
+	for _, relativePath := range relativePaths {
+		file, hasFile := localFiles[relativePath]
+		object, hasObject := objects[relativePath]
+
+		action := SyncAction{
+			RelativePath: relativePath,
+			FullName:     syncRequest.fullName(relativePath),
+			ObjectID:     object.ObjectID,
+		}
+
+		if hasFile && hasObject {
+			same, err := sameContent(file, object)
+			if err != nil {
+				return SyncReport{}, err
+			}
+			if same {
+				report.Unchanged++
+				continue
+			}
+		}
+
+		switch {
+		case syncRequest.Direction == SyncToObjectStore && hasFile:
+			action.Type = SyncUpload
+			action.Size = file.size
+		case syncRequest.Direction == SyncToObjectStore && syncRequest.Delete:
+			action.Type = SyncDeleteObject
+			action.Size = int64(object.Size)
+		case syncRequest.Direction == SyncFromObjectStore && hasObject:
+			action.Type = SyncDownload
+			action.Size = int64(object.Size)
+		case syncRequest.Direction == SyncFromObjectStore && syncRequest.Delete:
+			action.Type = SyncDeleteFile
+			action.Size = file.size
+		default:
+			continue
+		}
+
+		report.Actions = append(report.Actions, action)
+	}
+
+	return report, nil
+}
+
+// perform carries out a single action
+func (syncRequest *SyncRequest) perform(ctx context.Context, action SyncAction) error {
+
+	localPath, err := syncRequest.localPath(action.RelativePath)
+	if err != nil {
+		return err
+	}
+
+	switch action.Type {
+	case SyncUpload:
+		file, err := os.Open(localPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		uploadRequest := appmgrObjectstore.UploadRequest{
+			DeploymentID: syncRequest.DeploymentID,
+			FullName:     action.FullName,
+			Encrypted:    syncRequest.Encrypted,
+		}
+		_, err = uploadRequest.Upload(ctx, file)
+		if err != nil && uploadRequest.UploadID != "" {
+			abortRequest := appmgrObjectstore.AbortMultipartRequest{UploadID: uploadRequest.UploadID}
+			abortRequest.Abort()
+		}
+		return err
+
+	case SyncDownload:
+		// encrypted objects are decrypted so that the file compares equal to the content hash
+		downloadRequest := DownloadFileRequest{
+			DeploymentID:    syncRequest.DeploymentID,
+			ObjectID:        action.ObjectID,
+			DestinationPath: localPath,
+		}
+		_, err := downloadRequest.DownloadFile(ctx)
+		return err
+
+	case SyncDeleteObject:
+		deleteRequest := appmgrObjectstore.DeleteRequest{ObjectID: action.ObjectID}
+		_, apiErr := deleteRequest.Delete()
+		if apiErr != nil {
+			return apiErr
+		}
+		return nil
+
+	case SyncDeleteFile:
+		return os.Remove(localPath)
+	}
+
+	return nil
+}
+
+// listLocal walks the local directory and returns matching regular files by relative path
+func (syncRequest *SyncRequest) listLocal() (map[string]localFile, error) {
+
+	files := map[string]localFile{}
+
+	err := filepath.Walk(syncRequest.LocalDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == syncRequest.LocalDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(syncRequest.LocalDir, filePath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		if syncRequest.selected(relativePath) {
+			files[relativePath] = localFile{path: filePath, size: info.Size()}
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// listObjects lists matching objects under the prefix by relative path, along with the sorted
+// full names of objects whose relative path is unsafe
+func (syncRequest *SyncRequest) listObjects() (map[string]appmgrObjectstore.Object, []string, error) {
+
+	listRequest := appmgrObjectstore.ListRequest{
+		DeploymentID: syncRequest.DeploymentID,
+		Pattern:      syncRequest.Prefix,
+	}
+	objectList, apiErr := listRequest.List()
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	objects := map[string]appmgrObjectstore.Object{}
+	rejected := []string{}
+	for _, object := range objectList {
+		if !hasPathPrefix(object.FullName, syncRequest.Prefix) {
+			continue
+		}
+		relativePath := strings.TrimLeft(strings.TrimPrefix(object.FullName, syncRequest.Prefix), "/")
+		if relativePath == "" || !syncRequest.selected(relativePath) {
+			continue
+		}
+		if !safeRelativePath(relativePath) {
+			rejected = append(rejected, object.FullName)
+			continue
+		}
+		objects[relativePath] = object
+	}
+	sort.Strings(rejected)
+
+	return objects, rejected, nil
+}
+
+// safeRelativePath reports whether a slash separated relative path stays below the directory
+// it is joined to, ie. it is not absolute and has no `..` segment
+func safeRelativePath(relativePath string) bool {
+
+	localPath := filepath.FromSlash(relativePath)
+	if path.IsAbs(relativePath) || filepath.IsAbs(localPath) || filepath.VolumeName(localPath) != "" {
+		return false
+	}
+	for _, segment := range strings.Split(localPath, string(filepath.Separator)) {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// localPath is the path of a relative path under LocalDir, failing with ErrUnsafeObjectName if
+// it resolves outside LocalDir
+func (syncRequest *SyncRequest) localPath(relativePath string) (string, error) {
+
+	localPath := filepath.Join(syncRequest.LocalDir, filepath.FromSlash(relativePath))
+	relative, err := filepath.Rel(syncRequest.LocalDir, localPath)
+	if !safeRelativePath(relativePath) || err != nil || relative == ".." ||
+		strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", ErrUnsafeObjectName
+	}
+	return localPath, nil
+}
+
+// hasPathPrefix reports whether a full name is the prefix or lies under it, so that prefix
+// `models` matches `models/a` but not `models-old/a`
+func hasPathPrefix(fullName, prefix string) bool {
+	directory := strings.TrimSuffix(prefix, "/")
+	return directory == "" || fullName == directory || strings.HasPrefix(fullName, directory+"/")
+}
+
+// fullName is the object full name of a relative path
+func (syncRequest *SyncRequest) fullName(relativePath string) string {
+	if syncRequest.Prefix == "" || strings.HasSuffix(syncRequest.Prefix, "/") {
+		return syncRequest.Prefix + relativePath
+	}
+	return syncRequest.Prefix + "/" + relativePath
+}
+
+// selected reports whether a relative path passes the include and exclude patterns
+func (syncRequest *SyncRequest) selected(relativePath string) bool {
+
+	included := len(syncRequest.Include) == 0
+	for _, pattern := range syncRequest.Include {
+		if matchGlob(pattern, relativePath) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, pattern := range syncRequest.Exclude {
+		if matchGlob(pattern, relativePath) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchGlob matches a slash separated path against a glob pattern where `**` matches any
+// number of path segments. Patterns without a slash are matched against the base name.
+func matchGlob(pattern, relativePath string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(relativePath))
+		return matched
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relativePath, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(patterns, segments []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(patterns[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		matched, _ := path.Match(patterns[0], segments[0])
+		if !matched {
+			return false
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// sameContent compares a local file with an object by size and SHA-256 content hash. The size
+// of encrypted objects may be that of the ciphertext, so only their hash is compared. Objects
+// without a content hash are treated as changed.
+func sameContent(file localFile, object appmgrObjectstore.Object) (bool, error) {
+
+	if object.ContentHash == "" || (!object.Encrypted && uint64(file.size) != object.Size) {
+		return false, nil
+	}
+
+	hash, err := hashFile(file.path)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(hash, object.ContentHash), nil
+}
+
+// hashFile is the hex encoded SHA-256 of the file content
+func hashFile(filePath string) (string, error) {
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeFileAtomic writes a file through write into a temporary file in the same directory,
+// which is renamed over filePath only once write succeeds
+func writeFileAtomic(filePath string, write func(file *os.File) error) (err error) {
+
+	dir := filepath.Dir(filePath)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(dir, "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	err = write(temp)
+	if err != nil {
+		return err
+	}
+
+	err = temp.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(temp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), filePath)
+}
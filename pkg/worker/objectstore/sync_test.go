@@ -0,0 +1,312 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	appmgrObjectstore "go.semut.io/sdk/go-sdk/pkg/appmanager/objectstore"
+	"go.semut.io/sdk/go-sdk/pkg/internal/platformtest"
+	"go.semut.io/sdk/go-sdk/pkg/worker/encryption"
+)
+
+// encryptedPrefix marks the content of objects stored with inbuilt encryption by fakeStore
+const encryptedPrefix = "encrypted:"
+
+// fakeStore is an object store on a platform stand-in. Encrypted objects are stored as their
+// content prefixed with encryptedPrefix, which the stand-in decrypt endpoint removes.
+type fakeStore struct {
+	mutex   sync.Mutex
+	objects map[string]appmgrObjectstore.Object
+	data    map[string][]byte
+	server  *platformtest.Server
+}
+
+func startFakeStore(t *testing.T) *fakeStore {
+
+	store := &fakeStore{
+		objects: map[string]appmgrObjectstore.Object{},
+		data:    map[string][]byte{},
+		server:  platformtest.Start(t),
+	}
+
+	store.server.Handle("ObjectStoreList", func(body json.RawMessage) interface{} {
+		request := appmgrObjectstore.ListRequest{}
+		json.Unmarshal(body, &request)
+
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+		response := appmgrObjectstore.ListResponse{APIResponse: platformtest.OK()}
+		for _, object := range store.objects {
+			// like the platform, the pattern is a plain string prefix
+			if strings.HasPrefix(object.FullName, request.Pattern) {
+				response.Objects = append(response.Objects, object)
+			}
+		}
+		return response
+	})
+
+	store.server.Handle("ObjectStoreDescribe", func(body json.RawMessage) interface{} {
+		request := appmgrObjectstore.DescribeRequest{}
+		json.Unmarshal(body, &request)
+
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+		object, ok := store.objects[request.ObjectID]
+		if !ok {
+			return platformtest.Fail("404", "object not found")
+		}
+		return appmgrObjectstore.DescribeResponse{APIResponse: platformtest.OK(), Object: object}
+	})
+
+	store.server.Handle("ObjectStoreReadRange", func(body json.RawMessage) interface{} {
+		request := appmgrObjectstore.ReadRangeRequest{}
+		json.Unmarshal(body, &request)
+
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+		data, ok := store.data[request.ObjectID]
+		if !ok {
+			return platformtest.Fail("404", "object not found")
+		}
+		end := request.Offset + request.Length
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		return appmgrObjectstore.ReadRangeResponse{
+			APIResponse: platformtest.OK(), Data: data[request.Offset:end], Size: uint64(len(data)),
+		}
+	})
+
+	store.server.Handle("ObjectStoreDelete", func(body json.RawMessage) interface{} {
+		request := appmgrObjectstore.DeleteRequest{}
+		json.Unmarshal(body, &request)
+
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+		delete(store.objects, request.ObjectID)
+		delete(store.data, request.ObjectID)
+		return appmgrObjectstore.DeleteResponse{APIResponse: platformtest.OK()}
+	})
+
+	store.server.Handle("EncryptionDecryptFile", func(body json.RawMessage) interface{} {
+		request := encryption.DecryptFileRequest{}
+		json.Unmarshal(body, &request)
+
+		content, err := ioutil.ReadFile(request.EncryptedFile)
+		if err != nil || !bytes.HasPrefix(content, []byte(encryptedPrefix)) {
+			return platformtest.Fail("400", "file is not encrypted")
+		}
+		err = ioutil.WriteFile(request.EncryptedFile, content[len(encryptedPrefix):], 0644)
+		if err != nil {
+			return platformtest.Fail("500", err.Error())
+		}
+		return encryption.DecryptFileResponse{APIResponse: platformtest.OK()}
+	})
+
+	return store
+}
+
+// put stores an object with the given plain content
+func (store *fakeStore) put(fullName, content string, encrypted bool) {
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	sum := sha256.Sum256([]byte(content))
+	data := []byte(content)
+	if encrypted {
+		data = append([]byte(encryptedPrefix), data...)
+	}
+
+	objectID := "id-" + fullName
+	store.objects[objectID] = appmgrObjectstore.Object{
+		ObjectID:                objectID,
+		FullName:                fullName,
+		Size:                    uint64(len(data)),
+		Encrypted:               encrypted,
+		EncryptionKeyIdentifier: "key",
+		ContentHash:             hex.EncodeToString(sum[:]),
+	}
+	store.data[objectID] = data
+}
+
+// fullNames returns the sorted full names of the stored objects
+func (store *fakeStore) fullNames() []string {
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	names := []string{}
+	for _, object := range store.objects {
+		names = append(names, object.FullName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// localFiles returns the sorted relative paths of the files in dir
+func localFiles(t *testing.T, dir string) []string {
+
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relativePath, _ := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(relativePath))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestSyncIgnoresSiblingPrefix(t *testing.T) {
+
+	store := startFakeStore(t)
+	store.put("models/a.bin", "a", false)
+	store.put("models/nested/b.bin", "b", false)
+	store.put("models-old/x.bin", "x", false)
+	store.put("models.bak", "bak", false)
+
+	for _, prefix := range []string{"models", "models/"} {
+		t.Run(prefix, func(t *testing.T) {
+			dir := t.TempDir()
+			syncRequest := SyncRequest{LocalDir: dir, Prefix: prefix, Direction: SyncFromObjectStore}
+
+			_, err := syncRequest.Sync(context.Background())
+			if err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+
+			got := strings.Join(localFiles(t, dir), ",")
+			if got != "a.bin,nested/b.bin" {
+				t.Fatalf("downloaded %s, want a.bin,nested/b.bin", got)
+			}
+		})
+	}
+
+	syncRequest := SyncRequest{LocalDir: t.TempDir(), Prefix: "models", Direction: SyncToObjectStore, Delete: true}
+	report, err := syncRequest.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(report.Actions) != 2 {
+		t.Fatalf("planned %v, want deletion of the 2 objects under models/", report.Actions)
+	}
+
+	got := strings.Join(store.fullNames(), ",")
+	if got != "models-old/x.bin,models.bak" {
+		t.Fatalf("remaining objects %s, want models-old/x.bin,models.bak", got)
+	}
+}
+
+func TestSyncDecryptsEncryptedObjects(t *testing.T) {
+
+	store := startFakeStore(t)
+	store.put("models/secret.bin", "top secret", true)
+	store.put("models/plain.bin", "plain", false)
+
+	dir := t.TempDir()
+	syncRequest := SyncRequest{LocalDir: dir, Prefix: "models", Direction: SyncFromObjectStore}
+
+	report, err := syncRequest.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(report.Actions) != 2 {
+		t.Fatalf("first sync performed %v, want 2 downloads", report.Actions)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "secret.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "top secret" {
+		t.Fatalf("secret.bin = %q, want decrypted content", content)
+	}
+
+	report, err = syncRequest.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(report.Actions) != 0 || report.Unchanged != 2 {
+		t.Fatalf("second sync performed %v with %d unchanged, want nothing to do", report.Actions, report.Unchanged)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.bin", "a.bin", true},
+		{"*.bin", "nested/a.bin", true},
+		{"nested/*.bin", "nested/a.bin", true},
+		{"nested/*.bin", "nested/deeper/a.bin", false},
+		{"nested/**/*.bin", "nested/a.bin", true},
+		{"nested/**/*.bin", "nested/deeper/a.bin", true},
+		{"**/tmp/*", "a/b/tmp/c", true},
+		{"**/tmp/*", "a/b/c", false},
+	}
+
+	for _, test := range tests {
+		if got := matchGlob(test.pattern, test.path); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestSyncRejectsTraversingObjectNames(t *testing.T) {
+
+	store := startFakeStore(t)
+	store.put("models/a.bin", "a", false)
+	store.put("models/../../escaped.bin", "escaped", false)
+	store.put("models/nested/../../../escaped.bin", "escaped", false)
+	store.put("models//abs/escaped.bin", "absolute", false)
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b")
+	syncRequest := SyncRequest{LocalDir: dir, Prefix: "models", Direction: SyncFromObjectStore}
+
+	report, err := syncRequest.Sync(context.Background())
+	if !errors.Is(err, ErrSyncFailed) {
+		t.Fatalf("Sync error = %v, want ErrSyncFailed for the rejected objects", err)
+	}
+	want := "models/../../escaped.bin,models/nested/../../../escaped.bin"
+	if got := strings.Join(report.Rejected, ","); got != want {
+		t.Fatalf("rejected %s, want %s", got, want)
+	}
+
+	if got := strings.Join(localFiles(t, root), ","); got != "a/b/a.bin,a/b/abs/escaped.bin" {
+		t.Fatalf("files below the temporary directory %s, want only files under the local directory", got)
+	}
+
+	escapedPath := filepath.Join(root, "escaped.bin")
+	err = ioutil.WriteFile(escapedPath, []byte("keep"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteRequest := SyncRequest{LocalDir: dir, Direction: SyncFromObjectStore, Delete: true}
+	err = deleteRequest.perform(context.Background(), SyncAction{Type: SyncDeleteFile, RelativePath: "../../escaped.bin"})
+	if !errors.Is(err, ErrUnsafeObjectName) {
+		t.Fatalf("perform error = %v, want ErrUnsafeObjectName", err)
+	}
+	if _, err := os.Stat(escapedPath); err != nil {
+		t.Fatalf("file outside the local directory removed: %v", err)
+	}
+}
@@ -57,3 +57,67 @@ func (generateStoreRequest *GenerateStoreCredentialsRequest) GenerateStoreCreden
 	success, apiErr = gsr.GenerateStoreCredentials()
 	return
 }
+
+// StoreSecretVersion is an alias of app manager store secret version
+func (storeSecretRequest *StoreSecretRequest) StoreSecretVersion() (version int64, apiErr *common.Error) {
+	ssr := (*appmgrSecrets.StoreSecretRequest)(storeSecretRequest)
+	version, apiErr = ssr.StoreSecretVersion()
+	return
+}
+
+// RetrieveSecretVersion is an alias of app manager retrieve secret version
+func (retrieveSecretRequest *RetrieveSecretRequest) RetrieveSecretVersion() (secret string, version int64, apiErr *common.Error) {
+	rsr := (*appmgrSecrets.RetrieveSecretRequest)(retrieveSecretRequest)
+	secret, version, apiErr = rsr.RetrieveSecretVersion()
+	return
+}
+
+// ListSecretVersionsRequest is used to request the versions of a secret from worker end
+type ListSecretVersionsRequest appmgrSecrets.ListSecretVersionsRequest
+
+// DisableSecretVersionRequest is used to request disabling a secret version from worker end
+type DisableSecretVersionRequest appmgrSecrets.DisableSecretVersionRequest
+
+// EnableSecretVersionRequest is used to request enabling a secret version from worker end
+type EnableSecretVersionRequest appmgrSecrets.EnableSecretVersionRequest
+
+// DestroySecretVersionRequest is used to request destroying a secret version from worker end
+type DestroySecretVersionRequest appmgrSecrets.DestroySecretVersionRequest
+
+// List is an alias of app manager list secret versions
+func (listRequest *ListSecretVersionsRequest) List() (versions []appmgrSecrets.SecretVersion, apiErr *common.Error) {
+	lr := (*appmgrSecrets.ListSecretVersionsRequest)(listRequest)
+	versions, apiErr = lr.List()
+	return
+}
+
+// DisableVersion is an alias of app manager disable secret version
+func (disableRequest *DisableSecretVersionRequest) DisableVersion() (success bool, apiErr *common.Error) {
+	dr := (*appmgrSecrets.DisableSecretVersionRequest)(disableRequest)
+	success, apiErr = dr.DisableVersion()
+	return
+}
+
+// EnableVersion is an alias of app manager enable secret version
+func (enableRequest *EnableSecretVersionRequest) EnableVersion() (success bool, apiErr *common.Error) {
+	er := (*appmgrSecrets.EnableSecretVersionRequest)(enableRequest)
+	success, apiErr = er.EnableVersion()
+	return
+}
+
+// DestroyVersion is an alias of app manager destroy secret version
+func (destroyRequest *DestroySecretVersionRequest) DestroyVersion() (success bool, apiErr *common.Error) {
+	dr := (*appmgrSecrets.DestroySecretVersionRequest)(destroyRequest)
+	success, apiErr = dr.DestroyVersion()
+	return
+}
+
+// ListSecretsRequest is used to request the metadata of stored secrets from worker end
+type ListSecretsRequest appmgrSecrets.ListSecretsRequest
+
+// List is an alias of app manager list secrets
+func (listRequest *ListSecretsRequest) List() (secrets []appmgrSecrets.SecretMetadata, apiErr *common.Error) {
+	lr := (*appmgrSecrets.ListSecretsRequest)(listRequest)
+	secrets, apiErr = lr.List()
+	return
+}
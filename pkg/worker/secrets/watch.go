@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often WatchSecretFile checks a projected secret file for changes
+const DefaultWatchInterval = 10 * time.Second
+
+// ReadSecretFile reads a secret projected as a file, without the trailing newline
+func ReadSecretFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// WatchSecretFile calls onChange with the current value of a secret projected as a file, then
+// again each time the platform rewrites it after a new version becomes latest. It is used with
+// the Reload refresh policy and returns the context error once ctx is cancelled. Errors reading
+// the file are passed to onError, if not nil, and the file is checked again at the next interval.
+func WatchSecretFile(ctx context.Context, path string, interval time.Duration,
+	onChange func(value string), onError func(err error)) error {
+
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last []byte
+	var lastModified time.Time
+	for {
+		info, err := os.Stat(path)
+		if err == nil && (last == nil || !info.ModTime().Equal(lastModified)) {
+			var content []byte
+			content, err = ioutil.ReadFile(path)
+			if err == nil {
+				lastModified = info.ModTime()
+				if last == nil || !bytes.Equal(content, last) {
+					last = content
+					onChange(strings.TrimRight(string(content), "\r\n"))
+				}
+			}
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadSecretFile(t *testing.T) {
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"plain", "s3cret", "s3cret"},
+		{"trailing newline", "s3cret\n", "s3cret"},
+		{"trailing CRLF", "s3cret\r\n\r\n", "s3cret"},
+		{"inner newlines", "line 1\nline 2\n", "line 1\nline 2"},
+		{"leading space kept", "  s3cret ", "  s3cret "},
+		{"empty", "", ""},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "secret")
+		if err := ioutil.WriteFile(path, []byte(test.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadSecretFile(path)
+		if err != nil || got != test.want {
+			t.Errorf("%s: ReadSecretFile = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+
+	_, err := ReadSecretFile(filepath.Join(t.TempDir(), "missing"))
+	if !os.IsNotExist(err) {
+		t.Fatalf("ReadSecretFile error = %v, want a not exist error", err)
+	}
+}
+
+// waitFor receives from values or fails the test after a timeout
+func waitFor(t *testing.T, values <-chan string) string {
+	t.Helper()
+	select {
+	case value := <-values:
+		return value
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the watcher")
+		return ""
+	}
+}
+
+func TestWatchSecretFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "secret")
+	write := func(content string, modified time.Time) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("first\n", start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan string, 10)
+	errs := make(chan string, 10)
+	done := make(chan error)
+	go func() {
+		done <- WatchSecretFile(ctx, path, time.Millisecond,
+			func(value string) { changes <- value },
+			func(err error) {
+				// the missing file is reported at every interval
+				select {
+				case errs <- err.Error():
+				default:
+				}
+			})
+	}()
+
+	if value := waitFor(t, changes); value != "first" {
+		t.Fatalf("first value %q, want the current content", value)
+	}
+
+	// touching the file without changing its content is not a change
+	write("first\n", start.Add(time.Minute))
+	write("second\n", start.Add(2*time.Minute))
+	if value := waitFor(t, changes); value != "second" {
+		t.Fatalf("value %q, want the rewritten content", value)
+	}
+
+	// errors are reported and the watch resumes once the file is back
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, errs)
+	write("third\n", start.Add(3*time.Minute))
+	if value := waitFor(t, changes); value != "third" {
+		t.Fatalf("value %q, want the restored content", value)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("WatchSecretFile returned %v, want context.Canceled", err)
+	}
+	select {
+	case value := <-changes:
+		t.Fatalf("unexpected change %q", value)
+	default:
+	}
+}